@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	c "github.com/gookit/color" // nolint:misspell
+	"github.com/katbyte/gogo-repo-stats/lib/chttp"
+	"github.com/katbyte/gogo-repo-stats/lib/gh"
+)
+
+// ghAuth is how every client authenticates: a personal token, a pool of them that requests are spread over, or a
+// github app whose installation tokens are looked up per org/user and shared by every client for that owner.
+type ghAuth struct {
+	Token string
+	Pool  *gh.TokenPool
+	App   *gh.App
+
+	// Cassette records or replays everything the clients do, when replaying no credentials are needed
+	Cassette *chttp.Cassette
+}
+
+// reserve is how many requests each token leaves unused before it is considered spent
+func newGHAuth(f FlagData, reserve int) (*ghAuth, error) {
+	cassette, err := newCassette(f)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := newGHAuthCredentials(f, reserve)
+	if err != nil {
+		return nil, err
+	}
+
+	auth.Cassette = cassette
+	if auth.App != nil {
+		auth.App.Cassette = cassette
+	}
+
+	return auth, nil
+}
+
+func newCassette(f FlagData) (*chttp.Cassette, error) {
+	switch {
+	case f.Record != "" && f.Replay != "":
+		return nil, fmt.Errorf("only one of record and replay can be used")
+	case f.Record != "":
+		c.Printf("Recording github api exchanges to <white>%s</>\n", f.Record)
+		return chttp.NewCassette(f.Record, chttp.CassetteRecord)
+	case f.Replay != "":
+		c.Printf("Replaying github api exchanges from <white>%s</>\n", f.Replay)
+		return chttp.NewCassette(f.Replay, chttp.CassetteReplay)
+	}
+
+	return nil, nil
+}
+
+func newGHAuthCredentials(f FlagData, reserve int) (*ghAuth, error) {
+	if f.AppID == "" {
+		tokens, err := f.Tokens()
+		if err != nil {
+			return nil, err
+		}
+
+		switch len(tokens) {
+		case 0:
+			return &ghAuth{}, nil
+		case 1:
+			return &ghAuth{Token: tokens[0]}, nil
+		}
+
+		c.Printf("Spreading requests over <white>%d</> tokens\n", len(tokens))
+		return &ghAuth{Pool: gh.NewTokenPool(tokens, reserve)}, nil
+	}
+
+	id, err := strconv.ParseInt(f.AppID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid app id %q: %w", f.AppID, err)
+	}
+	if f.AppPrivateKey == "" {
+		return nil, fmt.Errorf("app-private-key is required with app-id")
+	}
+
+	app, err := gh.NewApp(id, f.AppPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	app.Host = f.Host()
+
+	if f.InstallationID != "" {
+		if app.Installation, err = strconv.ParseInt(f.InstallationID, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid installation id %q: %w", f.InstallationID, err)
+		}
+	}
+
+	c.Printf("Authenticating as github app <white>%d</>\n", id)
+	return &ghAuth{App: app}, nil
+}
+
+// apply sets up a repo or owner's token to authenticate for owner.
+func (a *ghAuth) apply(t *gh.Token, owner string) {
+	t.Cassette = a.Cassette
+
+	if a.App != nil {
+		t.Token = nil
+		t.Source = a.App.TokenSource(owner)
+		return
+	}
+
+	if a.Pool != nil {
+		t.Token = nil
+		t.Pool = a.Pool
+		return
+	}
+
+	if a.Token != "" {
+		t.Token = &a.Token
+	}
+}
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/katbyte/gogo-repo-stats/version"
 	_ "github.com/mattn/go-sqlite3"
@@ -12,8 +13,16 @@ import (
 func ValidateParams(params []string) func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
 		for _, p := range params {
-			if viper.GetString(p) == "" {
-				return fmt.Errorf(p + " parameter can't be empty")
+			// a|b means either will do
+			set := false
+			for _, alt := range strings.Split(p, "|") {
+				if viper.GetString(alt) != "" {
+					set = true
+				}
+			}
+
+			if !set {
+				return fmt.Errorf(strings.ReplaceAll(p, "|", " or ") + " parameter can't be empty")
 			}
 		}
 
@@ -29,7 +38,7 @@ func Make(cmdName string) (*cobra.Command, error) {
 		Short:         cmdName + "is a small utility to TODO",
 		Long:          `TODO`,
 		SilenceErrors: true,
-		PreRunE:       ValidateParams([]string{"token", "repos", "cache"}),
+		PreRunE:       ValidateParams([]string{"token|token-file|app-id|replay|gitlab-token", "repos|org", "cache"}),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// f := GetFlags()
 			// r := gh.NewRepo(f.Owner, f.Repos, f.Token)
@@ -50,10 +59,28 @@ func Make(cmdName string) (*cobra.Command, error) {
 		Use:           "fetch",
 		Args:          cobra.NoArgs,
 		SilenceErrors: true,
-		PreRunE:       ValidateParams([]string{"token", "repos", "cache"}),
+		PreRunE:       ValidateParams([]string{"token|token-file|app-id|replay|gitlab-token", "repos|org", "cache"}),
 		RunE:          CmdFetch,
 	})
 
+	root.AddCommand(&cobra.Command{
+		Use:           "webhook",
+		Short:         cmdName + " listens for github webhooks and keeps the cache up to date as prs & issues change",
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		PreRunE:       ValidateParams([]string{"webhook-secret", "cache"}),
+		RunE:          CmdWebhook,
+	})
+
+	root.AddCommand(&cobra.Command{
+		Use:           "ingest [owner/repo#number=]FILE...",
+		Short:         cmdName + " fills the cache from gh api --paginate exports and GH Archive files instead of the api",
+		Args:          cobra.MinimumNArgs(1),
+		SilenceErrors: true,
+		PreRunE:       ValidateParams([]string{"cache"}),
+		RunE:          CmdIngest,
+	})
+
 	root.AddCommand(&cobra.Command{
 		Use:           "report [YYYY-MM] [YYYY-MM]",
 		Short:         cmdName + " calculates a report for a given month range. defaults to last month till now. single date is then to now. 2 dates is range",
@@ -71,6 +98,23 @@ func Make(cmdName string) (*cobra.Command, error) {
 		RunE:          CmdGraphs,
 	})
 
+	cache := &cobra.Command{
+		Use:   "cache",
+		Short: cmdName + " manages the cache db",
+	}
+	migrate := &cobra.Command{
+		Use:           "migrate",
+		Short:         "brings the cache's schema up to date, with --dry-run only lists the migrations that would be applied",
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		PreRunE:       ValidateParams([]string{"cache"}),
+		RunE:          CmdCacheMigrate,
+	}
+	// only migrate has anything to dry run so it is not a persistent flag
+	migrate.Flags().Bool("dry-run", false, "show the migrations that would be applied without changing the cache")
+	cache.AddCommand(migrate)
+	root.AddCommand(cache)
+
 	// todo emoji stats/counter
 
 	root.AddCommand(&cobra.Command{
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	c "github.com/gookit/color" // nolint:misspell
+	cachepkg "github.com/katbyte/gogo-repo-stats/lib/cache"
+	"github.com/spf13/cobra"
+)
+
+// every command migrates the cache when it opens it, migrate is for doing so up front or seeing what would change
+func CmdCacheMigrate(cmd *cobra.Command, _ []string) error {
+	f := GetFlags()
+
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return err
+	}
+
+	cache, err := cachepkg.OpenUnmigrated(f.CachePath)
+	if err != nil {
+		return err
+	}
+	defer cache.DB.Close()
+
+	version, err := cache.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	c.Printf("Schema version <white>%d</> of <white>%d</>\n", version, cachepkg.LatestSchemaVersion())
+
+	if dryRun {
+		pending, err := cache.PendingMigrations()
+		if err != nil {
+			return err
+		}
+
+		if len(pending) == 0 {
+			c.Printf("  <green>up to date</>\n")
+			return nil
+		}
+		for _, m := range pending {
+			c.Printf("  would migrate to <white>%d</>: %s\n", m.Version, m.Description)
+		}
+
+		return nil
+	}
+
+	applied, err := cache.Migrate()
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		c.Printf("  <green>up to date</>\n")
+		return nil
+	}
+	c.Printf("Migrated to schema version <white>%d</>, <yellow>%d</> migrations applied\n", applied[len(applied)-1].Version, len(applied))
+
+	return nil
+}
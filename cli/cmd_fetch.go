@@ -1,214 +1,711 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/v45/github"
 	c "github.com/gookit/color" // nolint: misspell
-	"github.com/katbyte/gogo-repo-stats/lib/cache"
+	cachepkg "github.com/katbyte/gogo-repo-stats/lib/cache"
 	"github.com/katbyte/gogo-repo-stats/lib/gh"
+	"github.com/katbyte/gogo-repo-stats/lib/provider"
 	"github.com/spf13/cobra"
 )
 
+// fetch is split into 3 stages:
+//   - listing: walks each repo's prs & issues and works out what needs to be (re)fetched
+//   - workers: a pool that gets the details and timeline of each item from github, across all repos
+//   - writer: a single goroutine that writes everything to the cache so sqlite only ever sees one writer
+
+// httpCacheDays is how long an http cache entry is kept after it was last stored
+const httpCacheDays = 90
+
 func CmdFetch(_ *cobra.Command, _ []string) error {
 	f := GetFlags()
 
 	// open cache
-	cache, err := cache.Open(f.CachePath)
+	cache, err := cachepkg.Open(f.CachePath)
 	if err != nil {
 		return fmt.Errorf("opening cache %s: %w", f.CachePath, err)
 	}
 	defer cache.DB.Close()
 
-	full := false // todo full get everything mode
+	// full mode ignores the watermark & cache and re-downloads everything, reconciling what github no longer returns
+	full := f.FullFetch
+
+	listPRs, listIssues := listRepoPRs, listRepoIssues
+	switch f.Backend {
+	case "", "rest":
+	case "graphql":
+		listPRs, listIssues = listRepoPRsGraphQL, listRepoIssuesGraphQL
+	case "provider":
+	default:
+		return fmt.Errorf("unknown backend %q, expected rest, graphql or provider", f.Backend)
+	}
 
-	for _, repo := range f.Repos {
-		r, err := gh.NewRepo(repo, f.Token)
-		if err != nil {
-			return fmt.Errorf("creating repo %s: %w", repo, err)
+	host := f.Host()
+	if err := host.Validate(); err != nil {
+		return fmt.Errorf("invalid github host options: %w", err)
+	}
+	if host.Enterprise() {
+		c.Printf("Using github enterprise server <white>%s</> (%s)\n", host.Name(), host.API())
+	}
+
+	concurrency := f.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// every client shares a single budget so the workers pause together as the rate limit runs low, with several
+	// tokens each has its own and requests go to whichever has the most left
+	budget := gh.NewRateLimitBudget(concurrency * 2)
+	throttle := gh.NewThrottle()
+
+	auth, err := newGHAuth(f, concurrency*2)
+	if err != nil {
+		return err
+	}
+
+	// summarise the rate limits even when the fetch fails, they are often why
+	defer func() {
+		c.Printf("Throttling: <darkGray>%s</>\n", throttle.String())
+		if auth.Pool != nil {
+			for _, t := range auth.Pool.Tokens {
+				c.Printf("Rate limit <white>%s</>: <darkGray>%s</>\n", t.Name, t.Budget.String())
+			}
+		} else {
+			c.Printf("Rate limit: <darkGray>%s</>\n", budget.String())
 		}
+	}()
 
-		// for each PR, check if cached, if not insert && update
-		count := 0
-		c.Printf("Retrieving all prs for <white>%s</>/<cyan>%s</>...\n", r.Owner, r.Name)
-		err = r.ListAllPullRequests("all", func(prs []*github.PullRequest, resp *github.Response) error {
-			for i, p := range prs {
-				count++
+	names, err := resolveRepos(f, cache, auth)
+	if err != nil {
+		return err
+	}
 
-				if p == nil {
-					c.Printf(" <red>prs[%d] was nil</>, skipping", i)
-					continue
-				}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-				n := p.GetNumber()
-				if n == 0 {
-					c.Printf(" <red>prs[%d].Number was nil</>, skipping", i)
-					continue
-				}
+	pool := newFetchPool(ctx, cancel, cache, concurrency)
 
-				// check cache
-				cpr, err := cache.GetPR(repo, n)
-				if err == nil {
-					// if cached && closed (in cache) we have all relevant data
-					if cpr != nil && cpr.State != "open" {
-						// but check events, if zero we likely should get all events again
-						cevents, err := cache.GetEventsFor(repo, n)
-						if err != nil {
-							return fmt.Errorf("failed to get events from cache %s/%s/%d: %w", r.Owner, r.Name, n, err)
-						}
-
-						c.Printf(" pr <cyan>#%d</> <darkGray>(%d @ %s)</>: %s\n", n, count, p.GetCreatedAt().Format("2006-01-02"), p.GetTitle())
-						c.Printf("   CACHED! with <green>%d</> events\n", len(cevents))
-
-						if len(cevents) != 0 && !full {
-							continue
-						}
-					}
-				}
+	var repos []*repoFetch
+	var others []providerFetch
+	for _, repo := range names {
+		if kind, _ := provider.Split(repo); kind == provider.GitLab {
+			others = append(others, newGitLabFetch(f, repo))
+			continue
+		}
 
-				if p.GetState() == "open" {
-					c.Printf(" pr <yellow>#%d</> <darkGray>(%d @ %s)</>: %s\n", n, count, p.GetCreatedAt().Format("2006-01-02"), p.GetTitle())
-				} else {
-					c.Printf(" pr <green>#%d</> <darkGray>(%d @ %s)</>: %s\n", n, count, p.GetCreatedAt().Format("2006-01-02"), p.GetTitle())
-				}
+		if parts := strings.Split(repo, "/"); len(parts) == 3 && parts[0] != host.Name() {
+			pool.Fail(fmt.Errorf("repo %s is not on %s, set --web-url or --api-url to fetch from %s", repo, host.Name(), parts[0]))
+			break
+		}
 
-				gh, ctx := r.NewClient()
-				pr, _, err := gh.PullRequests.Get(ctx, r.Owner, r.Name, n)
-				if err != nil {
+		r, err := gh.NewRepo(repo, "")
+		if err != nil {
+			pool.Fail(fmt.Errorf("creating repo %s: %w", repo, err))
+			break
+		}
+		auth.apply(&r.Token, r.Owner)
+		r.Budget = budget
+		r.Throttle = throttle
+		r.Host = host
+		r.Conditional = conditionalStore{cache} // revalidate with etags, 304s are free
+
+		if f.Backend == "provider" {
+			others = append(others, providerFetch{Name: repo, Provider: *r})
+			continue
+		}
 
-					return fmt.Errorf("failed to get pr from GH %s/%s/%d: %w", r.Owner, r.Name, n, err)
+		rf := newRepoFetch(repo, r)
+		rf.Resume = f.Resume
+		repos = append(repos, rf)
 
-				}
+		if err := listPRs(ctx, cache, rf, full, pool); err != nil {
+			pool.Fail(fmt.Errorf("failed to get all prs for %s/%s: %w", r.Owner, r.Name, err))
+			break
+		}
 
-				err = cache.UpsertRepoPRFromGH(repo, pr)
-				if err != nil {
-					return fmt.Errorf("cache upsert failed: %w", err)
-				}
+		if err := listIssues(ctx, cache, rf, full, pool); err != nil {
+			pool.Fail(fmt.Errorf("failed to get all issues for %s/%s: %w", r.Owner, r.Name, err))
+			break
+		}
+	}
 
-				// get and store events
-				events, err := r.GetAllIssueEvents(*pr.Number)
-				if err != nil {
-					return fmt.Errorf("failed to get events from GH %s/%s/%d: %w", r.Owner, r.Name, n, err)
-				}
+	// wait for the workers and writer to drain before touching watermarks
+	if err := pool.Wait(); err != nil {
+		return err
+	}
 
-				c.Printf("   <darkGray>events:</> ")
-				for _, t := range *events {
-					c.Printf("%s, ", t.GetEvent())
+	for _, rf := range repos {
+		// only move the watermark once every changed item has made it into the cache
+		if err = cache.UpsertSyncWatermark(rf.Name, cachepkg.SyncKindPRs, rf.NewestPR); err != nil {
+			return fmt.Errorf("updating pr sync watermark for %s: %w", rf.Name, err)
+		}
+		if err = cache.UpsertSyncWatermark(rf.Name, cachepkg.SyncKindIssues, rf.NewestIssue); err != nil {
+			return fmt.Errorf("updating issue sync watermark for %s: %w", rf.Name, err)
+		}
 
-					err = cache.UpsertEvent(repo, n, &t)
-					if err != nil {
-						return fmt.Errorf("cache upsert failed: %w", err)
-					}
-				}
-				c.Printf("\n")
+		// the run for this repo is complete so the next one starts from the beginning
+		if err = cache.ClearFetchCheckpoints(rf.Name); err != nil {
+			return fmt.Errorf("clearing fetch checkpoints for %s: %w", rf.Name, err)
+		}
 
-				// now that we have PR and events in the cache, we can calculate stats:
-				daysOpen, daysWaiting, daysToFirst, err := cache.ComputeAndUpdatePRStats(repo, pr.GetNumber())
-				if err != nil {
-					return fmt.Errorf("falied to compute and update stats: %w", err)
-				}
-				c.Printf("   <darkGray>days</> open: <green>%.2f</> waiting: <green>%.2f</> first: <green>%.2f</> \n", *daysOpen, *daysWaiting, *daysToFirst)
+		// only a full fetch has seen every item github still has, so only then can we tell what has gone
+		if full && rf.Resumed {
+			c.Printf("  <yellow>skipping reconciliation</>, a resumed fetch has not seen the pages listed before it was interrupted\n")
+		} else if full {
+			rf.PRs.Removed, err = reconcileRemoved(cache, rf.Name, cachepkg.SyncKindPRs, rf.SeenPRs)
+			if err != nil {
+				return fmt.Errorf("reconciling prs for %s: %w", rf.Name, err)
+			}
+
+			rf.Issues.Removed, err = reconcileRemoved(cache, rf.Name, cachepkg.SyncKindIssues, rf.SeenIssues)
+			if err != nil {
+				return fmt.Errorf("reconciling issues for %s: %w", rf.Name, err)
 			}
+		}
+
+		if err = fetchReleases(cache, rf); err != nil {
+			return fmt.Errorf("fetching releases for %s: %w", rf.Name, err)
+		}
 
-			return nil
-		})
+		audit, err := auditEvents(cache, rf.Name)
 		if err != nil {
-			return fmt.Errorf("failed to get all prs for %s/%s: %w", r.Owner, r.Name, err)
+			return err
 		}
 
-		err = r.ListAllIssues("all", func(issues []*github.Issue, resp *github.Response) error {
-			for i, p := range issues {
-				count++
+		c.Printf("Fetched <white>%s</>/<cyan>%s</>:\n", rf.Repo.Owner, rf.Repo.Name)
+		c.Printf("  prs:    <green>%d</> added, <yellow>%d</> changed, <red>%d</> removed\n", rf.PRs.Added, rf.PRs.Changed, rf.PRs.Removed)
+		c.Printf("  issues: <green>%d</> added, <yellow>%d</> changed, <red>%d</> removed\n", rf.Issues.Added, rf.Issues.Changed, rf.Issues.Removed)
+		c.Printf("  releases: <white>%d</>, <green>%d</> prs newly released\n", rf.Releases, rf.Released)
+		c.Printf("  events: <yellow>%d</> duplicates removed, <white>%d</> timestamps shared by several events\n", audit.Duplicates, audit.Collisions)
+	}
 
-				if p == nil {
-					c.Printf(" <red>issues[%d] was nil</>, skipping", i)
-					continue
-				}
+	for _, pf := range others {
+		if err = fetchProviderRepo(cache, pf, full); err != nil {
+			return err
+		}
+	}
 
-				n := p.GetNumber()
-				if n == 0 {
-					c.Printf(" <red>issues[%d].Number was nil</>, skipping", i)
-					continue
-				}
+	if f.Project != 0 {
+		p := gh.NewProject(projectOwner(f), f.Project, "")
+		auth.apply(&p.Token, p.Owner)
+		p.Budget = budget
+		p.Throttle = throttle
+		p.Host = host
 
-				if p.IsPullRequest() {
-					continue
-				}
+		if err = fetchProject(cache, f, p); err != nil {
+			return fmt.Errorf("fetching project %d: %w", f.Project, err)
+		}
+	}
 
-				// check cache
-				cissue, err := cache.GetIssue(repo, n)
-				if err != nil {
-					c.Printf(" <red>issues[%d] unable to look up in cache</>, skipping: %s\n\n", err)
-				}
+	// the http cache only saves quota, so entries that have not been stored for a while are dropped to bound it
+	pruned, err := cache.PruneConditionalResponses(time.Now().AddDate(0, 0, -httpCacheDays))
+	if err != nil {
+		return err
+	}
+	if pruned > 0 {
+		c.Printf("Pruned <white>%d</> stale http cache entries\n", pruned)
+	}
+
+	return nil
+}
+
+// repoFetch is the state of a single repo's fetch, the listing stage owns the seen/newest fields and the writer the summaries.
+type repoFetch struct {
+	Name string
+	Repo *gh.Repo
+
+	// Resume continues listing from the last checkpoint, Resumed is set if there was one to continue from
+	Resume  bool
+	Resumed bool
+
+	PRs    fetchSummary
+	Issues fetchSummary
+
+	// releases found & prs newly mapped to the release they shipped in
+	Releases int
+	Released int
+
+	SeenPRs     map[int]bool
+	SeenIssues  map[int]bool
+	NewestPR    time.Time
+	NewestIssue time.Time
+}
 
+func newRepoFetch(name string, r *gh.Repo) *repoFetch {
+	return &repoFetch{
+		Name:       name,
+		Repo:       r,
+		SeenPRs:    map[int]bool{},
+		SeenIssues: map[int]bool{},
+	}
+}
+
+type fetchSummary struct {
+	Added   int
+	Changed int
+	Removed int
+}
+
+// listStart works out where listing a kind starts: the watermark to list changes since and,
+// when resuming, the checkpoint of the last page that was completely written.
+func listStart(cache *cachepkg.Cache, rf *repoFetch, kind string, full bool) (time.Time, *cachepkg.FetchCheckpoint, error) {
+	// only list what has changed since the last sync, with no watermark this is everything
+	since, err := cache.GetSyncWatermark(rf.Name, kind)
+	if err != nil {
+		return since, nil, fmt.Errorf("getting %s sync watermark for %s: %w", kind, rf.Name, err)
+	}
+	if full {
+		since = time.Time{}
+	}
+
+	if !rf.Resume {
+		return since, nil, nil
+	}
+
+	cp, err := cache.GetFetchCheckpoint(rf.Name, kind)
+	if err != nil {
+		return since, nil, fmt.Errorf("getting %s fetch checkpoint for %s: %w", kind, rf.Name, err)
+	}
+	// anything updated while we were stopped moves to the first pages and is missed, but as it is newer than
+	// the checkpoint's watermark the next incremental fetch will pick it up
+	if cp != nil {
+		rf.Resumed = true
+		c.Printf("Resuming %s for <white>%s</>/<cyan>%s</> after page <white>%d</> (#%d)...\n", kind, rf.Repo.Owner, rf.Repo.Name, cp.Page, cp.Last)
+	}
+
+	return since, cp, nil
+}
+
+func listRepoPRs(ctx context.Context, cache *cachepkg.Cache, rf *repoFetch, full bool, pool *fetchPool) error {
+	r := rf.Repo
+
+	since, cp, err := listStart(cache, rf, cachepkg.SyncKindPRs, full)
+	if err != nil {
+		return err
+	}
+	rf.NewestPR = since
+
+	start := 1
+	if cp != nil {
+		start = cp.Page + 1
+		if cp.Newest.After(rf.NewestPR) {
+			rf.NewestPR = cp.Newest
+		}
+	}
+	page := start - 1
+
+	// for each PR, check if cached, if not queue it to be fetched
+	count := 0
+	if since.IsZero() {
+		c.Printf("Retrieving all prs for <white>%s</>/<cyan>%s</>...\n", r.Owner, r.Name)
+	} else {
+		c.Printf("Retrieving prs updated since <white>%s</> for <white>%s</>/<cyan>%s</>...\n", since.Format("2006-01-02 15:04:05"), r.Owner, r.Name)
+	}
+
+	return r.ListPullRequestsUpdatedSince(since, start, func(prs []*github.PullRequest, resp *github.Response) error {
+		page++
+		end := fetchPageEnd{}
+
+		for i, p := range prs {
+			count++
+
+			if p == nil {
+				c.Printf(" <red>prs[%d] was nil</>, skipping", i)
+				continue
+			}
+
+			n := p.GetNumber()
+			if n == 0 {
+				c.Printf(" <red>prs[%d].Number was nil</>, skipping", i)
+				continue
+			}
+
+			if p.GetUpdatedAt().After(rf.NewestPR) {
+				rf.NewestPR = p.GetUpdatedAt()
+			}
+			rf.SeenPRs[n] = true
+			end.Last = n
+
+			// check cache, with a watermark everything listed has changed since the last sync so is always refetched
+			cpr, err := cache.GetPR(rf.Name, n)
+			if err == nil && since.IsZero() && !full {
 				// if cached && closed (in cache) we have all relevant data
-				if cissue != nil && cissue.State != "open" {
+				if cpr != nil && cpr.State != "open" {
 					// but check events, if zero we likely should get all events again
-					cevents, err := cache.GetEventsFor(repo, n)
+					cevents, err := cache.GetEventsFor(rf.Name, n)
 					if err != nil {
 						return fmt.Errorf("failed to get events from cache %s/%s/%d: %w", r.Owner, r.Name, n, err)
 					}
 
-					c.Printf(" issue <cyan>#%d</> <darkGray>(%d @ %s)</>: %s\n", n, count, p.GetCreatedAt().Format("2006-01-02"), p.GetTitle())
+					c.Printf(" pr <cyan>#%d</> <darkGray>(%d @ %s)</>: %s\n", n, count, p.GetCreatedAt().Format("2006-01-02"), p.GetTitle())
 					c.Printf("   CACHED! with <green>%d</> events\n", len(cevents))
 
-					if len(cevents) != 0 && !full {
+					if len(cevents) != 0 {
 						continue
 					}
 				}
+			}
 
-				if p.GetState() == "open" {
-					c.Printf(" issue <yellow>#%d</> <darkGray>(%d @ %s)</>: %s\n", n, count, p.GetCreatedAt().Format("2006-01-02"), p.GetTitle())
-				} else {
-					c.Printf(" issue <green>#%d</> <darkGray>(%d @ %s)</>: %s\n", n, count, p.GetCreatedAt().Format("2006-01-02"), p.GetTitle())
-				}
+			// commits whose checks have all completed are not fetched again
+			cchecks, err := cache.GetChecksFor(rf.Name, n)
+			if err != nil {
+				return fmt.Errorf("failed to get checks from cache %s/%s/%d: %w", r.Owner, r.Name, n, err)
+			}
 
-				gh, ctx := r.NewClient()
-				issue, _, err := gh.Issues.Get(ctx, r.Owner, r.Name, n)
-				if err != nil {
+			err = pool.Queue(fetchJob{
+				Fetch:        rf,
+				Kind:         cachepkg.SyncKindPRs,
+				Number:       n,
+				Count:        count,
+				Full:         full,
+				CachedPR:     cpr,
+				CachedChecks: cchecks,
+				Page:         page,
+				FirstPage:    start,
+			})
+			if err != nil {
+				return err
+			}
+			end.Items++
+		}
 
-					return fmt.Errorf("failed to get issue from GH %s/%s/%d: %w", r.Owner, r.Name, n, err)
+		end.Newest = rf.NewestPR
+		if err := pool.EndPage(rf, cachepkg.SyncKindPRs, page, start, end); err != nil {
+			return err
+		}
 
-				}
+		return ctx.Err()
+	})
+}
 
-				err = cache.UpsertRepoIssueFromGH(repo, issue)
-				if err != nil {
-					return fmt.Errorf("cache upsert failed: %w", err)
-				}
+func listRepoIssues(ctx context.Context, cache *cachepkg.Cache, rf *repoFetch, full bool, pool *fetchPool) error {
+	r := rf.Repo
+
+	since, cp, err := listStart(cache, rf, cachepkg.SyncKindIssues, full)
+	if err != nil {
+		return err
+	}
+	rf.NewestIssue = since
+
+	start := 1
+	if cp != nil {
+		start = cp.Page + 1
+		if cp.Newest.After(rf.NewestIssue) {
+			rf.NewestIssue = cp.Newest
+		}
+	}
+	page := start - 1
+
+	count := 0
+	if since.IsZero() {
+		c.Printf("Retrieving all issues for <white>%s</>/<cyan>%s</>...\n", r.Owner, r.Name)
+	} else {
+		c.Printf("Retrieving issues updated since <white>%s</> for <white>%s</>/<cyan>%s</>...\n", since.Format("2006-01-02 15:04:05"), r.Owner, r.Name)
+	}
+
+	return r.ListIssuesUpdatedSince(since, start, func(issues []*github.Issue, resp *github.Response) error {
+		page++
+		end := fetchPageEnd{}
+
+		for i, p := range issues {
+			count++
+
+			if p == nil {
+				c.Printf(" <red>issues[%d] was nil</>, skipping", i)
+				continue
+			}
+
+			n := p.GetNumber()
+			if n == 0 {
+				c.Printf(" <red>issues[%d].Number was nil</>, skipping", i)
+				continue
+			}
+
+			if p.GetUpdatedAt().After(rf.NewestIssue) {
+				rf.NewestIssue = p.GetUpdatedAt()
+			}
+			end.Last = n
 
-				// get and store events
-				events, err := r.GetAllIssueEvents(*issue.Number)
+			if p.IsPullRequest() {
+				continue
+			}
+			rf.SeenIssues[n] = true
+
+			// check cache
+			cissue, err := cache.GetIssue(rf.Name, n)
+			if err != nil {
+				c.Printf(" <red>issues[%d] unable to look up in cache</>, skipping: %s\n\n", i, err)
+			}
+
+			// if cached && closed (in cache) we have all relevant data, unless it has changed since the last sync
+			if cissue != nil && cissue.State != "open" && since.IsZero() && !full {
+				// but check events, if zero we likely should get all events again
+				cevents, err := cache.GetIssueEventsFor(rf.Name, n)
 				if err != nil {
-					return fmt.Errorf("failed to get events from GH %s/%s/%d: %w", r.Owner, r.Name, n, err)
+					return fmt.Errorf("failed to get events from cache %s/%s/%d: %w", r.Owner, r.Name, n, err)
 				}
 
-				c.Printf("   <darkGray>events:</> ")
-				for _, t := range *events {
-					c.Printf("%s, ", t.GetEvent())
+				c.Printf(" issue <cyan>#%d</> <darkGray>(%d @ %s)</>: %s\n", n, count, p.GetCreatedAt().Format("2006-01-02"), p.GetTitle())
+				c.Printf("   CACHED! with <green>%d</> events\n", len(cevents))
 
-					err = cache.UpsertEvent(repo, n, &t)
-					if err != nil {
-						return fmt.Errorf("cache upsert failed: %w", err)
-					}
+				if len(cevents) != 0 {
+					continue
 				}
-				c.Printf("\n")
+			}
 
-				// TODO compute stats for issues
-				// now that we have PR and events in the cache, we can calculate stats:
-				/*daysOpen, daysWaiting, daysToFirst, err := cache.ComputeAndUpdatePRStats(repo, issue.GetNumber())
-				if err != nil {
-					return fmt.Errorf("falied to compute and update stats: %w", err)
-				}
-				c.Printf("   <darkGray>days</> open: <green>%.2f</> waiting: <green>%.2f</> first: <green>%.2f</> \n", *daysOpen, *daysWaiting, *daysToFirst)
+			err = pool.Queue(fetchJob{
+				Fetch:       rf,
+				Kind:        cachepkg.SyncKindIssues,
+				Number:      n,
+				Count:       count,
+				Full:        full,
+				CachedIssue: cissue,
+				Page:        page,
+				FirstPage:   start,
+			})
+			if err != nil {
+				return err
+			}
+			end.Items++
+		}
+
+		end.Newest = rf.NewestIssue
+		if err := pool.EndPage(rf, cachepkg.SyncKindIssues, page, start, end); err != nil {
+			return err
+		}
+
+		return ctx.Err()
+	})
+}
+
+// listRepoPRsGraphQL lists prs with their timelines in bulk, so every changed pr is queued already fetched.
+func listRepoPRsGraphQL(ctx context.Context, cache *cachepkg.Cache, rf *repoFetch, full bool, pool *fetchPool) error {
+	r := rf.Repo
+
+	since, cp, err := listStart(cache, rf, cachepkg.SyncKindPRs, full)
+	if err != nil {
+		return err
+	}
+	rf.NewestPR = since
+
+	start := 1
+	var after *string
+	if cp != nil {
+		start = cp.Page + 1
+		if cp.Cursor.Valid {
+			after = &cp.Cursor.String
+		}
+		if cp.Newest.After(rf.NewestPR) {
+			rf.NewestPR = cp.Newest
+		}
+	}
+	page := start - 1
+
+	count := 0
+	c.Printf("Retrieving prs (graphql) updated since <white>%s</> for <white>%s</>/<cyan>%s</>...\n", since.Format("2006-01-02 15:04:05"), r.Owner, r.Name)
+
+	return r.ListPullRequestsWithTimelineGraphQL(since, after, func(prs []gh.PullRequestWithTimeline, cursor *string) error {
+		page++
+		end := fetchPageEnd{Cursor: cursor}
+
+		for _, p := range prs {
+			count++
+			n := p.PullRequest.GetNumber()
+			end.Last = n
+
+			if p.PullRequest.GetUpdatedAt().After(rf.NewestPR) {
+				rf.NewestPR = p.PullRequest.GetUpdatedAt()
+			}
+			rf.SeenPRs[n] = true
+
+			// only used to tell added from changed
+			cpr, err := cache.GetPR(rf.Name, n)
+			if err != nil {
+				return err
+			}
+
+			// commits whose checks have all completed are not fetched again
+			cchecks, err := cache.GetChecksFor(rf.Name, n)
+			if err != nil {
+				return fmt.Errorf("failed to get checks from cache %s/%s/%d: %w", r.Owner, r.Name, n, err)
+			}
+
+			events, reviews, files := p.Events, p.Reviews, p.Files
+			comments, reviewComments := p.Comments, p.ReviewComments
+			err = pool.Queue(fetchJob{
+				Fetch:        rf,
+				Kind:         cachepkg.SyncKindPRs,
+				Number:       n,
+				Count:        count,
+				Full:         full,
+				CachedPR:     cpr,
+				CachedChecks: cchecks,
+				Prefetched:   &fetchResult{PR: p.PullRequest, Events: &events, Reviews: &reviews, Files: &files, Comments: &comments, ReviewComments: &reviewComments, Commits: p.Commits},
+				Page:         page,
+				FirstPage:    start,
+			})
+			if err != nil {
+				return err
+			}
+			end.Items++
+		}
+
+		end.Newest = rf.NewestPR
+		if err := pool.EndPage(rf, cachepkg.SyncKindPRs, page, start, end); err != nil {
+			return err
+		}
+
+		return ctx.Err()
+	})
+}
+
+// listRepoIssuesGraphQL lists issues with their timelines in bulk, so every changed issue is queued already fetched.
+func listRepoIssuesGraphQL(ctx context.Context, cache *cachepkg.Cache, rf *repoFetch, full bool, pool *fetchPool) error {
+	r := rf.Repo
+
+	since, cp, err := listStart(cache, rf, cachepkg.SyncKindIssues, full)
+	if err != nil {
+		return err
+	}
+	rf.NewestIssue = since
+
+	start := 1
+	var after *string
+	if cp != nil {
+		start = cp.Page + 1
+		if cp.Cursor.Valid {
+			after = &cp.Cursor.String
+		}
+		if cp.Newest.After(rf.NewestIssue) {
+			rf.NewestIssue = cp.Newest
+		}
+	}
+	page := start - 1
+
+	count := 0
+	c.Printf("Retrieving issues (graphql) updated since <white>%s</> for <white>%s</>/<cyan>%s</>...\n", since.Format("2006-01-02 15:04:05"), r.Owner, r.Name)
+
+	return r.ListIssuesWithTimelineGraphQL(since, after, func(issues []gh.IssueWithTimeline, cursor *string) error {
+		page++
+		end := fetchPageEnd{Cursor: cursor}
 
-				*/
+		for _, i := range issues {
+			count++
+			n := i.Issue.GetNumber()
+			end.Last = n
+
+			if i.Issue.GetUpdatedAt().After(rf.NewestIssue) {
+				rf.NewestIssue = i.Issue.GetUpdatedAt()
+			}
+			rf.SeenIssues[n] = true
+
+			// only used to tell added from changed
+			cissue, err := cache.GetIssue(rf.Name, n)
+			if err != nil {
+				return err
 			}
 
-			return nil
-		})
+			events, comments := i.Events, i.Comments
+			err = pool.Queue(fetchJob{
+				Fetch:       rf,
+				Kind:        cachepkg.SyncKindIssues,
+				Number:      n,
+				Count:       count,
+				Full:        full,
+				CachedIssue: cissue,
+				Prefetched:  &fetchResult{Issue: i.Issue, Events: &events, Comments: &comments},
+				Page:        page,
+				FirstPage:   start,
+			})
+			if err != nil {
+				return err
+			}
+			end.Items++
+		}
+
+		end.Newest = rf.NewestIssue
+		if err := pool.EndPage(rf, cachepkg.SyncKindIssues, page, start, end); err != nil {
+			return err
+		}
+
+		return ctx.Err()
+	})
+}
+
+// auditEvents removes the repo's events duplicated under a local id and recomputes the stats of the prs they were on.
+func auditEvents(cache *cachepkg.Cache, repo string) (*cachepkg.EventAudit, error) {
+	audit, err := cache.AuditEvents(repo)
+	if err != nil {
+		return nil, fmt.Errorf("auditing events of %s: %w", repo, err)
+	}
+
+	for _, n := range audit.Numbers {
+		// issues have no stats
+		pr, err := cache.GetPR(repo, n)
 		if err != nil {
-			return fmt.Errorf("failed to get all issues for %s/%s: %w", r.Owner, r.Name, err)
+			return nil, fmt.Errorf("getting pr %s#%d: %w", repo, n, err)
+		}
+		if pr == nil {
+			continue
+		}
+
+		if _, _, _, err := cache.ComputeAndUpdatePRStats(repo, n); err != nil {
+			return nil, fmt.Errorf("falied to compute and update stats: %w", err)
 		}
 	}
-	return nil
+
+	return audit, nil
+}
+
+// reconcileRemoved marks every cached item of kind that was not seen during a full fetch as removed.
+func reconcileRemoved(cache *cachepkg.Cache, repo, kind string, seen map[int]bool) (int, error) {
+	cached, err := cache.GetCachedNumbers(repo, kind)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for n := range cached {
+		if seen[n] {
+			continue
+		}
+
+		// items marked by an earlier full fetch are only counted the first time
+		marked, err := cache.MarkRemoved(repo, kind, n)
+		if err != nil {
+			return removed, err
+		}
+		if marked {
+			c.Printf(" %s <red>#%d</> is no longer returned by github, marked as removed\n", kind, n)
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+func prChanged(a, b cachepkg.PR) bool {
+	return a.Title != b.Title ||
+		a.State != b.State ||
+		a.Milestone != b.Milestone ||
+		a.Merged != b.Merged ||
+		a.Merger != b.Merger ||
+		!a.Closed.Equal(b.Closed) ||
+		a.Additions != b.Additions ||
+		a.Deletions != b.Deletions ||
+		a.ChangedFiles != b.ChangedFiles ||
+		a.Commits != b.Commits
+}
+
+func issueChanged(a, b cachepkg.Issue) bool {
+	return a.Title != b.Title ||
+		a.State != b.State ||
+		a.Milestone != b.Milestone ||
+		a.Labels != b.Labels ||
+		!a.Closed.Equal(b.Closed)
 }
@@ -0,0 +1,549 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	c "github.com/gookit/color" // nolint: misspell
+	cachepkg "github.com/katbyte/gogo-repo-stats/lib/cache"
+	"github.com/katbyte/gogo-repo-stats/lib/gh"
+)
+
+type fetchJob struct {
+	Fetch  *repoFetch
+	Kind   string
+	Number int
+	Count  int
+	Full   bool
+
+	// what was in the cache before this fetch, nil if it is new
+	CachedPR     *cachepkg.PR
+	CachedIssue  *cachepkg.Issue
+	CachedChecks []cachepkg.Check // prs only
+
+	// set when the lister already has everything (ie the graphql backend) and the workers just pass it on
+	Prefetched *fetchResult
+
+	// the listing page this item came from and the first page of this run, used to checkpoint progress
+	Page      int
+	FirstPage int
+
+	// set on the marker queued after the last item of a page, there is nothing to fetch
+	PageEnd *fetchPageEnd
+}
+
+// fetchPageEnd describes a listing page once all of its items have been queued.
+type fetchPageEnd struct {
+	Items  int       // number of jobs queued for the page
+	Last   int       // number of the last item listed on the page
+	Cursor *string   // graphql cursor to continue after the page
+	Newest time.Time // newest updated_at listed up to and including the page
+}
+
+// pageTracker follows which pages of a repo & kind have been completely written, out of order as the
+// workers finish, so the checkpoint only ever moves past pages that are safely in the cache.
+type pageTracker struct {
+	next    int
+	written map[int]int
+	ends    map[int]fetchPageEnd
+}
+
+type fetchResult struct {
+	fetchJob
+
+	PR      *github.PullRequest
+	Issue   *github.Issue
+	Events  *[]gh.TimelineEvent
+	Reviews *[]github.PullRequestReview // prs only
+	Files   *[]github.CommitFile        // prs only
+
+	Comments       *[]github.IssueComment
+	ReviewComments *[]github.PullRequestComment // prs only
+	Checks         []cachepkg.Check             // prs only
+	Commits        *[]gh.PullRequestCommit      // prs only, set by the graphql backend
+	Err            error
+}
+
+// fetchPool fans jobs out to a bounded number of workers that talk to github, and funnels their results
+// into a single writer goroutine that owns all cache writes.
+type fetchPool struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	cache  *cachepkg.Cache
+
+	jobs       chan fetchJob
+	results    chan fetchResult
+	writerDone chan struct{}
+
+	errOnce sync.Once
+	err     error
+
+	// only touched by the writer goroutine
+	pages map[string]*pageTracker
+}
+
+func newFetchPool(ctx context.Context, cancel context.CancelFunc, cache *cachepkg.Cache, workers int) *fetchPool {
+	p := &fetchPool{
+		ctx:        ctx,
+		cancel:     cancel,
+		cache:      cache,
+		jobs:       make(chan fetchJob, workers),
+		results:    make(chan fetchResult, workers),
+		writerDone: make(chan struct{}),
+		pages:      map[string]*pageTracker{},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range p.jobs {
+				// keep draining after a failure so the lister never blocks
+				if p.ctx.Err() != nil {
+					continue
+				}
+				p.results <- fetchItem(j)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(p.results)
+	}()
+
+	go func() {
+		defer close(p.writerDone)
+		for res := range p.results {
+			if res.Err != nil {
+				p.Fail(res.Err)
+				continue
+			}
+			if p.ctx.Err() != nil {
+				continue
+			}
+
+			if res.PageEnd == nil {
+				if err := p.write(res); err != nil {
+					p.Fail(err)
+					continue
+				}
+			}
+
+			if err := p.checkpoint(res.fetchJob); err != nil {
+				p.Fail(err)
+			}
+		}
+	}()
+
+	return p
+}
+
+// Queue hands a job to the workers, blocking while they are all busy.
+func (p *fetchPool) Queue(j fetchJob) error {
+	select {
+	case p.jobs <- j:
+		return nil
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	}
+}
+
+// EndPage queues a marker after the last item of a listing page so the writer knows when the page is done.
+func (p *fetchPool) EndPage(rf *repoFetch, kind string, page, first int, end fetchPageEnd) error {
+	return p.Queue(fetchJob{
+		Fetch:     rf,
+		Kind:      kind,
+		Page:      page,
+		FirstPage: first,
+		PageEnd:   &end,
+	})
+}
+
+// Fail records the first error and stops all outstanding work.
+func (p *fetchPool) Fail(err error) {
+	p.errOnce.Do(func() {
+		p.err = err
+		p.cancel()
+	})
+}
+
+// Wait stops accepting jobs and returns once everything queued has been written, or the first error.
+func (p *fetchPool) Wait() error {
+	close(p.jobs)
+	<-p.writerDone
+
+	return p.err
+}
+
+// fetchItem gets an item and its timeline from github, it runs on the workers so must not touch the cache. the one
+// exception is the client's conditional transport storing validators, see gh.NewConditionalTransport.
+func fetchItem(j fetchJob) fetchResult {
+	if j.PageEnd != nil {
+		return fetchResult{fetchJob: j}
+	}
+
+	if j.Prefetched != nil {
+		res := *j.Prefetched
+		res.fetchJob = j
+		if j.Kind == cachepkg.SyncKindPRs {
+			res.Err = fetchChecks(&res)
+		}
+		return res
+	}
+
+	res := fetchResult{fetchJob: j}
+	r := j.Fetch.Repo
+	client, ctx := r.NewClient()
+
+	switch j.Kind {
+	case cachepkg.SyncKindPRs:
+		pr, _, err := client.PullRequests.Get(ctx, r.Owner, r.Name, j.Number)
+		if err != nil {
+			res.Err = fmt.Errorf("failed to get pr from GH %s/%s/%d: %w", r.Owner, r.Name, j.Number, err)
+			return res
+		}
+		res.PR = pr
+	case cachepkg.SyncKindIssues:
+		issue, _, err := client.Issues.Get(ctx, r.Owner, r.Name, j.Number)
+		if err != nil {
+			res.Err = fmt.Errorf("failed to get issue from GH %s/%s/%d: %w", r.Owner, r.Name, j.Number, err)
+			return res
+		}
+		res.Issue = issue
+	default:
+		res.Err = fmt.Errorf("unknown fetch kind %q", j.Kind)
+		return res
+	}
+
+	events, err := r.GetAllIssueEvents(j.Number)
+	if err != nil {
+		res.Err = fmt.Errorf("failed to get events from GH %s/%s/%d: %w", r.Owner, r.Name, j.Number, err)
+		return res
+	}
+	res.Events = events
+
+	comments, err := r.GetAllIssueComments(j.Number)
+	if err != nil {
+		res.Err = fmt.Errorf("failed to get comments from GH %s/%s/%d: %w", r.Owner, r.Name, j.Number, err)
+		return res
+	}
+	res.Comments = comments
+
+	if j.Kind == cachepkg.SyncKindPRs {
+		reviewComments, err := r.GetAllPullRequestComments(j.Number)
+		if err != nil {
+			res.Err = fmt.Errorf("failed to get review comments from GH %s/%s/%d: %w", r.Owner, r.Name, j.Number, err)
+			return res
+		}
+		res.ReviewComments = reviewComments
+
+		reviews, err := r.GetAllPullRequestReviews(j.Number)
+		if err != nil {
+			res.Err = fmt.Errorf("failed to get reviews from GH %s/%s/%d: %w", r.Owner, r.Name, j.Number, err)
+			return res
+		}
+		res.Reviews = reviews
+
+		files, err := r.GetAllPullRequestFiles(j.Number)
+		if err != nil {
+			res.Err = fmt.Errorf("failed to get files from GH %s/%s/%d: %w", r.Owner, r.Name, j.Number, err)
+			return res
+		}
+		res.Files = files
+
+		if err := fetchChecks(&res); err != nil {
+			res.Err = err
+			return res
+		}
+	}
+
+	return res
+}
+
+// fetchChecks gets the check runs & statuses for every commit the PR has had: its current commits plus any
+// that were force pushed away. commits whose checks had all completed when they were cached are kept as they are
+// unless this is a full fetch, it runs on the workers so must not touch the cache.
+func fetchChecks(res *fetchResult) error {
+	r := res.Fetch.Repo
+	n := res.Number
+
+	// the graphql backend listed the commits along with the state of their checks
+	var shas []string
+	rollups := map[string]string{}
+	if res.Commits != nil {
+		for _, cm := range *res.Commits {
+			shas = append(shas, cm.SHA)
+			rollups[cm.SHA] = cm.Checks
+		}
+	} else {
+		var err error
+		shas, err = r.GetAllPullRequestCommitSHAs(n)
+		if err != nil {
+			return fmt.Errorf("failed to get commits from GH %s/%s/%d: %w", r.Owner, r.Name, n, err)
+		}
+	}
+	if head := res.PR.GetHead().GetSHA(); head != "" {
+		shas = append(shas, head)
+	}
+	if res.Events != nil {
+		for _, e := range *res.Events {
+			if e.GetEvent() == "head_ref_force_pushed" {
+				shas = append(shas, e.GetCommitID(), e.GetSHA())
+			}
+		}
+	}
+
+	completed := map[string]bool{}
+	if !res.Full {
+		completed = cachepkg.CompletedSHAs(res.CachedChecks)
+	}
+
+	seen := map[string]bool{}
+	for _, sha := range shas {
+		if sha == "" || seen[sha] {
+			continue
+		}
+		seen[sha] = true
+
+		if completed[sha] {
+			for _, ch := range res.CachedChecks {
+				if ch.SHA == sha {
+					res.Checks = append(res.Checks, ch)
+				}
+			}
+			continue
+		}
+
+		// listed without a state, nothing has run on it
+		if state, ok := rollups[sha]; ok && state == "" {
+			continue
+		}
+
+		var runs *[]github.CheckRun
+		statuses := &[]github.RepoStatus{}
+		if res.Prefetched != nil {
+			var hasStatuses bool
+			var err error
+			runs, hasStatuses, err = r.GetAllCheckRunsForRefGraphQL(sha)
+			if err != nil {
+				return fmt.Errorf("failed to get check runs from GH %s/%s/%d@%s: %w", r.Owner, r.Name, n, sha, err)
+			}
+
+			if hasStatuses {
+				if statuses, err = r.GetAllStatusesForRef(sha); err != nil {
+					return fmt.Errorf("failed to get statuses from GH %s/%s/%d@%s: %w", r.Owner, r.Name, n, sha, err)
+				}
+			}
+		} else {
+			var err error
+			runs, err = r.GetAllCheckRunsForRef(sha)
+			if err != nil {
+				return fmt.Errorf("failed to get check runs from GH %s/%s/%d@%s: %w", r.Owner, r.Name, n, sha, err)
+			}
+			statuses, err = r.GetAllStatusesForRef(sha)
+			if err != nil {
+				return fmt.Errorf("failed to get statuses from GH %s/%s/%d@%s: %w", r.Owner, r.Name, n, sha, err)
+			}
+		}
+
+		res.Checks = append(res.Checks, cachepkg.ChecksFromGH(res.Fetch.Name, n, sha, *runs, *statuses)...)
+	}
+
+	return nil
+}
+
+// write stores a fetched item in the cache, it is only ever called from the writer goroutine.
+func (p *fetchPool) write(res fetchResult) error {
+	cache := p.cache
+	rf := res.Fetch
+	repo := rf.Name
+	n := res.Number
+
+	switch res.Kind {
+	case cachepkg.SyncKindPRs:
+		pr := res.PR
+		if pr.GetState() == "open" {
+			c.Printf(" pr <yellow>#%d</> <darkGray>(%d @ %s)</>: %s\n", n, res.Count, pr.GetCreatedAt().Format("2006-01-02"), pr.GetTitle())
+		} else {
+			c.Printf(" pr <green>#%d</> <darkGray>(%d @ %s)</>: %s\n", n, res.Count, pr.GetCreatedAt().Format("2006-01-02"), pr.GetTitle())
+		}
+
+		if err := cache.UpsertRepoPRFromGH(repo, pr); err != nil {
+			return fmt.Errorf("cache upsert failed: %w", err)
+		}
+
+		if pr.Additions != nil && pr.Deletions != nil {
+			c.Printf("   <darkGray>size:</> %s <darkGray>(+%d/-%d in %d files, %d commits)</>\n", cachepkg.PRSizeFor(int64(pr.GetAdditions()+pr.GetDeletions())), pr.GetAdditions(), pr.GetDeletions(), pr.GetChangedFiles(), pr.GetCommits())
+		}
+
+		if res.Files != nil {
+			if err := cache.ReplacePRFilesFromGH(repo, n, *res.Files); err != nil {
+				return fmt.Errorf("cache upsert failed: %w", err)
+			}
+		}
+
+		if res.CachedPR == nil {
+			rf.PRs.Added++
+		} else if npr, err := cache.GetPR(repo, n); err == nil && npr != nil && prChanged(*res.CachedPR, *npr) {
+			rf.PRs.Changed++
+		}
+	case cachepkg.SyncKindIssues:
+		issue := res.Issue
+		if issue.GetState() == "open" {
+			c.Printf(" issue <yellow>#%d</> <darkGray>(%d @ %s)</>: %s\n", n, res.Count, issue.GetCreatedAt().Format("2006-01-02"), issue.GetTitle())
+		} else {
+			c.Printf(" issue <green>#%d</> <darkGray>(%d @ %s)</>: %s\n", n, res.Count, issue.GetCreatedAt().Format("2006-01-02"), issue.GetTitle())
+		}
+
+		if err := cache.UpsertRepoIssueFromGH(repo, issue); err != nil {
+			return fmt.Errorf("cache upsert failed: %w", err)
+		}
+
+		if res.CachedIssue == nil {
+			rf.Issues.Added++
+		} else if nissue, err := cache.GetIssue(repo, n); err == nil && nissue != nil && issueChanged(*res.CachedIssue, *nissue) {
+			rf.Issues.Changed++
+		}
+	}
+
+	if res.Full {
+		if err := cache.ClearRemoved(repo, res.Kind, n); err != nil {
+			return err
+		}
+	}
+
+	// store comments
+	if res.Comments != nil || res.ReviewComments != nil {
+		count := 0
+		if res.Comments != nil {
+			for _, cm := range *res.Comments {
+				cm := cm
+				if err := cache.UpsertIssueCommentFromGH(repo, n, &cm); err != nil {
+					return fmt.Errorf("cache upsert failed: %w", err)
+				}
+			}
+			count += len(*res.Comments)
+		}
+		if res.ReviewComments != nil {
+			for _, cm := range *res.ReviewComments {
+				cm := cm
+				if err := cache.UpsertReviewCommentFromGH(repo, n, &cm); err != nil {
+					return fmt.Errorf("cache upsert failed: %w", err)
+				}
+			}
+			count += len(*res.ReviewComments)
+		}
+		c.Printf("   <darkGray>comments:</> %d\n", count)
+	}
+
+	// store events
+	c.Printf("   <darkGray>events:</> ")
+	for _, t := range *res.Events {
+		c.Printf("%s, ", t.GetEvent())
+
+		t := t
+		if err := cache.UpsertEvent(repo, n, &t); err != nil {
+			return fmt.Errorf("cache upsert failed: %w", err)
+		}
+	}
+	c.Printf("\n")
+
+	// TODO compute stats for issues
+	if res.Kind != cachepkg.SyncKindPRs {
+		return nil
+	}
+
+	// store reviews
+	if res.Reviews != nil {
+		c.Printf("   <darkGray>reviews:</> ")
+		for _, rv := range *res.Reviews {
+			c.Printf("%s (%s), ", rv.GetUser().GetLogin(), strings.ToLower(rv.GetState()))
+
+			rv := rv
+			if err := cache.UpsertReviewFromGH(repo, n, &rv); err != nil {
+				return fmt.Errorf("cache upsert failed: %w", err)
+			}
+		}
+		c.Printf("\n")
+	}
+
+	// now that we have PR and events in the cache, we can calculate stats:
+	daysOpen, daysWaiting, daysToFirst, err := cache.ComputeAndUpdatePRStats(repo, n)
+	if err != nil {
+		return fmt.Errorf("falied to compute and update stats: %w", err)
+	}
+	c.Printf("   <darkGray>days</> open: <green>%.2f</> waiting: <green>%.2f</> first: <green>%.2f</> \n", *daysOpen, *daysWaiting, *daysToFirst)
+
+	// store checks
+	if err := cache.ReplacePRChecks(repo, n, res.Checks); err != nil {
+		return fmt.Errorf("cache upsert failed: %w", err)
+	}
+	daysToGreen, mergedFailing, flaky, err := cache.ComputeAndUpdatePRCIStats(repo, n, res.PR.GetHead().GetSHA())
+	if err != nil {
+		return fmt.Errorf("falied to compute and update ci stats: %w", err)
+	}
+	if len(res.Checks) > 0 {
+		c.Printf("   <darkGray>checks:</> %d", len(res.Checks))
+		if daysToGreen != nil {
+			c.Printf(" green: <green>%.2f</>", *daysToGreen)
+		}
+		if mergedFailing {
+			c.Printf(" <red>merged failing</>")
+		}
+		if flaky > 0 {
+			c.Printf(" flaky: <yellow>%d</>", flaky)
+		}
+		c.Printf("\n")
+	}
+
+	return nil
+}
+
+// checkpoint records a written item or page marker and persists a checkpoint for every page that is now
+// completely written, it is only ever called from the writer goroutine.
+func (p *fetchPool) checkpoint(j fetchJob) error {
+	key := j.Fetch.Name + "/" + j.Kind
+	t, ok := p.pages[key]
+	if !ok {
+		t = &pageTracker{next: j.FirstPage, written: map[int]int{}, ends: map[int]fetchPageEnd{}}
+		p.pages[key] = t
+	}
+
+	if j.PageEnd != nil {
+		t.ends[j.Page] = *j.PageEnd
+	} else {
+		t.written[j.Page]++
+	}
+
+	for {
+		end, ok := t.ends[t.next]
+		if !ok || t.written[t.next] < end.Items {
+			return nil
+		}
+
+		cp := cachepkg.FetchCheckpoint{
+			Repo:   j.Fetch.Name,
+			Kind:   j.Kind,
+			Page:   t.next,
+			Last:   end.Last,
+			Newest: end.Newest,
+		}
+		if end.Cursor != nil {
+			cp.Cursor.String = *end.Cursor
+			cp.Cursor.Valid = true
+		}
+		if err := p.cache.UpsertFetchCheckpoint(cp); err != nil {
+			return err
+		}
+
+		delete(t.ends, t.next)
+		delete(t.written, t.next)
+		t.next++
+	}
+}
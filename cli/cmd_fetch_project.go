@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	c "github.com/gookit/color" // nolint: misspell
+	cachepkg "github.com/katbyte/gogo-repo-stats/lib/cache"
+	"github.com/katbyte/gogo-repo-stats/lib/gh"
+	"github.com/katbyte/gogo-repo-stats/lib/provider"
+)
+
+// a projects (v2) board is fetched whole each time, there is no way to list only the items that changed. every field
+// value that differs from the last one seen is added to the cache's history so how long items sit in each column and
+// the board's flow can be worked out later
+
+// projectOwner is who --project belongs to: --project-owner, --org or the owner of the first github repo
+func projectOwner(f FlagData) string {
+	if f.ProjectOwner != "" {
+		return f.ProjectOwner
+	}
+	if f.Org != "" {
+		return f.Org
+	}
+
+	for _, repo := range f.Repos {
+		if kind, _ := provider.Split(repo); kind != provider.GitHub {
+			continue
+		}
+		if r, err := gh.NewRepo(repo, ""); err == nil {
+			return r.Owner
+		}
+	}
+
+	return ""
+}
+
+// projectKey is how the project is keyed in the cache, like repos it is owner/number with the host on enterprise
+func projectKey(f FlagData) (string, error) {
+	owner := projectOwner(f)
+	if owner == "" {
+		return "", fmt.Errorf("unable to work out who project %d belongs to, set --project-owner", f.Project)
+	}
+
+	return f.Host().RepoKey(owner + "/" + strconv.Itoa(f.Project)), nil
+}
+
+func fetchProject(cache *cachepkg.Cache, f FlagData, p gh.Project) error {
+	key, err := projectKey(f)
+	if err != nil {
+		return err
+	}
+
+	c.Printf("Retrieving items of project <white>%s</>/<cyan>%d</>...\n", p.Owner, p.Number)
+
+	now := time.Now()
+	seen := map[string]bool{}
+	var items, changes int
+	err = p.ListItems(func(page []gh.ProjectItem) error {
+		for _, i := range page {
+			seen[i.ID] = true
+			items++
+
+			item := cachepkg.ProjectItem{
+				ID:       i.ID,
+				Type:     i.Type,
+				Number:   i.Number,
+				Title:    i.Title,
+				Archived: i.Archived,
+				Updated:  i.Updated,
+				Fields:   i.Fields,
+			}
+			if i.Repo != "" {
+				item.Repo = p.Host.RepoKey(i.Repo)
+			}
+
+			if err := cache.UpsertProjectItem(key, item); err != nil {
+				return fmt.Errorf("cache upsert failed: %w", err)
+			}
+
+			n, err := cache.RecordProjectFieldValues(key, item)
+			if err != nil {
+				return fmt.Errorf("cache upsert failed: %w", err)
+			}
+			changes += n
+
+			if n > 0 {
+				c.Printf(" %s <cyan>%s#%d</> <darkGray>(%d changed)</>: %s\n", item.Type, item.Repo, item.Number, n, item.Title)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get all items of project %s: %w", key, err)
+	}
+
+	// the whole board was listed so anything not on it has been deleted
+	removed, err := cache.MarkProjectItemsRemoved(key, seen, now)
+	if err != nil {
+		return err
+	}
+
+	c.Printf("Fetched project <white>%s</>/<cyan>%d</>:\n", p.Owner, p.Number)
+	c.Printf("  items: <white>%d</>, <yellow>%d</> field changes, <red>%d</> removed\n", items, changes, removed)
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	c "github.com/gookit/color" // nolint: misspell
+	cachepkg "github.com/katbyte/gogo-repo-stats/lib/cache"
+	"github.com/katbyte/gogo-repo-stats/lib/gitlab"
+	"github.com/katbyte/gogo-repo-stats/lib/provider"
+)
+
+// repos not on github (and github ones with --backend provider) are fetched through the provider interface: a page
+// of items at a time and then each item's events, written straight to the cache as nothing else is writing by then.
+// there are no checks, reviews, comments or releases, just what every provider has
+
+type providerFetch struct {
+	Name     string
+	Provider provider.Provider
+}
+
+// newGitLabFetch returns the fetch of a gitlab:group/project repo
+func newGitLabFetch(f FlagData, repo string) providerFetch {
+	_, path := provider.Split(repo)
+	return providerFetch{
+		Name:     repo,
+		Provider: gitlab.NewProject(f.GitLabURL, path, f.GitLabToken),
+	}
+}
+
+func fetchProviderRepo(cache *cachepkg.Cache, pf providerFetch, full bool) error {
+	var prs, issues fetchSummary
+	name := pf.Name
+
+	// prs
+	since, err := cache.GetSyncWatermark(name, cachepkg.SyncKindPRs)
+	if err != nil {
+		return fmt.Errorf("getting pr sync watermark for %s: %w", name, err)
+	}
+	if full {
+		since = time.Time{}
+	}
+
+	c.Printf("Listing prs for <cyan>%s</> updated since <white>%s</>...\n", name, since.Format("2006-01-02"))
+	newest, seen := since, map[int]bool{}
+	err = pf.Provider.ListPRs(since, func(page []provider.PR) error {
+		for _, pr := range page {
+			seen[pr.Number] = true
+			if pr.Updated.After(newest) {
+				newest = pr.Updated
+			}
+
+			if pr.State == "open" {
+				c.Printf(" pr <yellow>#%d</> <darkGray>(@ %s)</>: %s\n", pr.Number, pr.Created.Format("2006-01-02"), pr.Title)
+			} else {
+				c.Printf(" pr <green>#%d</> <darkGray>(@ %s)</>: %s\n", pr.Number, pr.Created.Format("2006-01-02"), pr.Title)
+			}
+
+			cached, err := cache.GetPR(name, pr.Number)
+			if err != nil {
+				return err
+			}
+			if err := cache.UpsertRepoPR(name, pr); err != nil {
+				return fmt.Errorf("cache upsert failed: %w", err)
+			}
+
+			if cached == nil {
+				prs.Added++
+			} else if npr, err := cache.GetPR(name, pr.Number); err == nil && npr != nil && prChanged(*cached, *npr) {
+				prs.Changed++
+			}
+
+			if full {
+				if err := cache.ClearRemoved(name, cachepkg.SyncKindPRs, pr.Number); err != nil {
+					return err
+				}
+			}
+
+			events, err := pf.Provider.PREvents(pr.Number)
+			if err != nil {
+				return err
+			}
+			if err := upsertProviderEvents(cache.UpsertRepoEvent, name, pr.Number, events); err != nil {
+				return err
+			}
+
+			daysOpen, daysWaiting, daysToFirst, err := cache.ComputeAndUpdatePRStats(name, pr.Number)
+			if err != nil {
+				return fmt.Errorf("falied to compute and update stats: %w", err)
+			}
+			c.Printf("   <darkGray>days</> open: <green>%.2f</> waiting: <green>%.2f</> first: <green>%.2f</> \n", *daysOpen, *daysWaiting, *daysToFirst)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get all prs for %s: %w", name, err)
+	}
+
+	// only move the watermark once every changed item has made it into the cache
+	if err = cache.UpsertSyncWatermark(name, cachepkg.SyncKindPRs, newest); err != nil {
+		return fmt.Errorf("updating pr sync watermark for %s: %w", name, err)
+	}
+	if full {
+		if prs.Removed, err = reconcileRemoved(cache, name, cachepkg.SyncKindPRs, seen); err != nil {
+			return fmt.Errorf("reconciling prs for %s: %w", name, err)
+		}
+	}
+
+	// issues
+	since, err = cache.GetSyncWatermark(name, cachepkg.SyncKindIssues)
+	if err != nil {
+		return fmt.Errorf("getting issue sync watermark for %s: %w", name, err)
+	}
+	if full {
+		since = time.Time{}
+	}
+
+	c.Printf("Listing issues for <cyan>%s</> updated since <white>%s</>...\n", name, since.Format("2006-01-02"))
+	newest, seen = since, map[int]bool{}
+	err = pf.Provider.ListIssues(since, func(page []provider.Issue) error {
+		for _, issue := range page {
+			seen[issue.Number] = true
+			if issue.Updated.After(newest) {
+				newest = issue.Updated
+			}
+
+			if issue.State == "open" {
+				c.Printf(" issue <yellow>#%d</> <darkGray>(@ %s)</>: %s\n", issue.Number, issue.Created.Format("2006-01-02"), issue.Title)
+			} else {
+				c.Printf(" issue <green>#%d</> <darkGray>(@ %s)</>: %s\n", issue.Number, issue.Created.Format("2006-01-02"), issue.Title)
+			}
+
+			cached, err := cache.GetIssue(name, issue.Number)
+			if err != nil {
+				return err
+			}
+			if err := cache.UpsertRepoIssue(name, issue); err != nil {
+				return fmt.Errorf("cache upsert failed: %w", err)
+			}
+
+			if cached == nil {
+				issues.Added++
+			} else if nissue, err := cache.GetIssue(name, issue.Number); err == nil && nissue != nil && issueChanged(*cached, *nissue) {
+				issues.Changed++
+			}
+
+			if full {
+				if err := cache.ClearRemoved(name, cachepkg.SyncKindIssues, issue.Number); err != nil {
+					return err
+				}
+			}
+
+			events, err := pf.Provider.IssueEvents(issue.Number)
+			if err != nil {
+				return err
+			}
+			if err := upsertProviderEvents(cache.UpsertRepoIssueEvent, name, issue.Number, events); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get all issues for %s: %w", name, err)
+	}
+
+	if err = cache.UpsertSyncWatermark(name, cachepkg.SyncKindIssues, newest); err != nil {
+		return fmt.Errorf("updating issue sync watermark for %s: %w", name, err)
+	}
+	if full {
+		if issues.Removed, err = reconcileRemoved(cache, name, cachepkg.SyncKindIssues, seen); err != nil {
+			return fmt.Errorf("reconciling issues for %s: %w", name, err)
+		}
+	}
+
+	audit, err := auditEvents(cache, name)
+	if err != nil {
+		return err
+	}
+
+	c.Printf("Fetched <cyan>%s</>:\n", name)
+	c.Printf("  prs:    <green>%d</> added, <yellow>%d</> changed, <red>%d</> removed\n", prs.Added, prs.Changed, prs.Removed)
+	c.Printf("  issues: <green>%d</> added, <yellow>%d</> changed, <red>%d</> removed\n", issues.Added, issues.Changed, issues.Removed)
+	c.Printf("  events: <yellow>%d</> duplicates removed, <white>%d</> timestamps shared by several events\n", audit.Duplicates, audit.Collisions)
+
+	return nil
+}
+
+// upsertProviderEvents stores events with upsert, the cache's UpsertRepoEvent for prs and UpsertRepoIssueEvent for
+// issues as providers may number them apart
+func upsertProviderEvents(upsert func(string, int, provider.Event) error, repo string, number int, events []provider.Event) error {
+	c.Printf("   <darkGray>events:</> ")
+	for _, e := range events {
+		c.Printf("%s, ", e.Event)
+
+		if err := upsert(repo, number, e); err != nil {
+			return fmt.Errorf("cache upsert failed: %w", err)
+		}
+	}
+	c.Printf("\n")
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"fmt"
+
+	c "github.com/gookit/color" // nolint: misspell
+	cachepkg "github.com/katbyte/gogo-repo-stats/lib/cache"
+)
+
+// fetchReleases stores a repo's releases and maps every merged pr not yet released to the first release it shipped
+// in. it runs once the pool has drained so it can write to the cache directly.
+//
+// a pr referenced in the notes of a release is taken as shipped in it, otherwise the compare api is used to find the
+// first release whose tag contains the merge commit. prereleases are ignored as nothing has shipped yet. the releases
+// compared against a pr are remembered so a pr merged into a branch that is never tagged costs a compare per new
+// release rather than one per release on every fetch. repos that only push tags have their tags used as releases, see
+// gh.GetAllTagReleases.
+func fetchReleases(cache *cachepkg.Cache, rf *repoFetch) error {
+	r := rf.Repo
+
+	c.Printf("Retrieving releases for <white>%s</>/<cyan>%s</>...\n", r.Owner, r.Name)
+	fetched, err := r.GetAllReleases()
+	if err != nil {
+		return err
+	}
+	if len(*fetched) == 0 {
+		c.Printf("  <darkGray>no releases, using tags...</>\n")
+		if fetched, err = r.GetAllTagReleases(); err != nil {
+			return err
+		}
+	}
+	if err = cache.ReplaceReleasesFromGH(rf.Name, *fetched); err != nil {
+		return err
+	}
+
+	all, err := cache.GetReleases(rf.Name)
+	if err != nil {
+		return err
+	}
+
+	var releases []cachepkg.Release
+	for _, rl := range all {
+		if !rl.Prerelease {
+			releases = append(releases, rl)
+		}
+	}
+	rf.Releases = len(releases)
+	if len(releases) == 0 {
+		return nil
+	}
+
+	// the first release whose notes mention each pr
+	noted := map[int][]cachepkg.Release{}
+	for _, rl := range releases {
+		for _, n := range rl.ReferencedPRs() {
+			noted[n] = append(noted[n], rl)
+		}
+	}
+
+	prs, err := cache.GetUnreleasedMergedPRs(rf.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, pr := range prs {
+		var shipped *cachepkg.Release
+
+		for _, rl := range noted[pr.Number] {
+			if !rl.Published.Before(pr.Merged) {
+				rl := rl
+				shipped = &rl
+				break
+			}
+		}
+
+		if shipped == nil && pr.MergeSHA != "" {
+			checked := pr.Checked
+			for _, rl := range releases {
+				if rl.Published.Before(pr.Merged) || !rl.Published.After(pr.Checked) {
+					continue
+				}
+
+				contains, err := r.TagContainsCommit(rl.Tag, pr.MergeSHA)
+				if err != nil {
+					return err
+				}
+				if contains {
+					rl := rl
+					shipped = &rl
+					break
+				}
+				checked = rl.Published
+			}
+
+			if shipped == nil && checked.After(pr.Checked) {
+				if err = cache.UpdatePRReleaseChecked(rf.Name, pr.Number, checked); err != nil {
+					return fmt.Errorf("cache update failed: %w", err)
+				}
+			}
+		}
+
+		// not released yet
+		if shipped == nil {
+			continue
+		}
+
+		c.Printf(" pr <green>#%d</> released in <cyan>%s</> <darkGray>(%s)</>\n", pr.Number, shipped.Tag, shipped.Published.Format("2006-01-02"))
+		if err = cache.UpdatePRRelease(rf.Name, pr, *shipped); err != nil {
+			return fmt.Errorf("cache update failed: %w", err)
+		}
+		rf.Released++
+	}
+
+	return nil
+}
@@ -54,11 +54,14 @@ func CmdGraphs(_ *cobra.Command, args []string) error {
 	}
 	defer cache.DB.Close()
 
+	f.Repos, err = resolveRepos(f, cache, nil)
+	if err != nil {
+		return err
+	}
+
 	c.Printf("Generating graphs for PRs from <white>%s</> to <white>%s</>...\n", from.Format("2006-01-02"), to.Format("2006-01-02"))
 	c.Printf("  for repos: <cyan>%s</>\n", strings.Join(f.Repos, "</>, <cyan>"))
-	if len(f.Authors) > 0 {
-		c.Printf("  for authors: <green>%s</>\n", strings.Join(f.Authors, "</>, <green>"))
-	}
+	printFilters(f)
 
 	for _, repo := range f.Repos {
 		repoPath := outPath + "/" + gh.RepoShortName(repo)
@@ -96,6 +99,12 @@ func CmdGraphs(_ *cobra.Command, args []string) error {
 		if err = GraphRepoOpenPRsDailyByType(cache, repoPath, from, to, []string{repo}); err != nil {
 			return fmt.Errorf("failed to generate daily open issues by type graphs path: %w", err)
 		}
+		if err = GraphRepoPRsBySize(cache, repoPath, from, to, []string{repo}); err != nil {
+			return fmt.Errorf("failed to generate pr size graphs path: %w", err)
+		}
+		if err = GraphRepoReleaseLeadTime(cache, repoPath, from, to, []string{repo}); err != nil {
+			return fmt.Errorf("failed to generate release lead time graphs path: %w", err)
+		}
 	}
 
 	c.Printf("  <magenta>MultiRepo graphs</>...\n")
@@ -106,6 +115,18 @@ func CmdGraphs(_ *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to generate daily pr graphs path: %w", err)
 	}
 
+	if f.Project != 0 {
+		project, err := projectKey(f)
+		if err != nil {
+			return err
+		}
+
+		c.Printf("  <magenta>Project</> <cyan>%s</>...\n", project)
+		if err = GraphProjectCumulativeFlow(cache, outPath, from, to, project, f.ProjectField); err != nil {
+			return fmt.Errorf("failed to generate project cumulative flow graph: %w", err)
+		}
+	}
+
 	/*
 		if err = GraphRepoDailyTotalPRs(cache, outPath, from, to, nil); err != nil {
 			return fmt.Errorf("failed to generate daily total pr graphs path: %w", err)
@@ -35,7 +35,7 @@ type DayStatsIssuesByType struct {
 }
 
 func GraphRepoOpenPRsDailyByType(cache *cache.Cache, outPath string, from, to time.Time, repos []string) error {
-	// f := GetFlags() // todo out path ends up in flags
+	f := GetFlags() // todo out path ends up in flags
 
 	c.Printf("    Issues open daily..\n")
 
@@ -49,7 +49,7 @@ func GraphRepoOpenPRsDailyByType(cache *cache.Cache, outPath string, from, to ti
 	}
 
 	// get all issues for range
-	issues, err := cache.GetRepoIssuesOpenForDateRange(repos, from, to)
+	issues, err := cache.GetRepoIssuesOpenForDateRange(f.Filter(repos), from, to)
 	if err != nil {
 		return fmt.Errorf("getting PRs: %w", err)
 	}
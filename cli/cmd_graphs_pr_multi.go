@@ -51,7 +51,7 @@ func GraphMultiRepoTotalPRsDaily(cache *cache.Cache, outPath string, from, to ti
 
 		// for each repo get stats for the day and add to totals
 		for _, repo := range repos {
-			stats, err := cache.CalculateRepoPRStatsForDateRange(dayStart, dayEnd, []string{repo}, f.Authors)
+			stats, err := cache.CalculateRepoPRStatsForDateRange(dayStart, dayEnd, f.Filter([]string{repo}))
 			if err != nil {
 				return fmt.Errorf("failed to query stats: %w", err)
 			}
@@ -140,7 +140,7 @@ func GraphMultiRepoTotalPRsDaily(cache *cache.Cache, outPath string, from, to ti
 }
 
 func GraphMultiRepoOpenPRsDaily(c *cache.Cache, outPath string, from, to time.Time, repos []string) error {
-	// f := GetFlags() // todo out path ends up in flags
+	f := GetFlags() // todo out path ends up in flags
 
 	var shortRepos []string
 	for _, repo := range repos {
@@ -158,7 +158,7 @@ func GraphMultiRepoOpenPRsDaily(c *cache.Cache, outPath string, from, to time.Ti
 	}
 
 	// get all prs for range
-	prs, err := c.GetRepoPRsOpenForDateRange(repos, from, to)
+	prs, err := c.GetRepoPRsOpenForDateRange(f.Filter(repos), from, to)
 	if err != nil {
 		return fmt.Errorf("getting PRs: %w", err)
 	}
@@ -180,6 +180,11 @@ func GraphMultiRepoOpenPRsDaily(c *cache.Cache, outPath string, from, to time.Ti
 			return fmt.Errorf("getting events for PR %d: %w", pr.Number, err)
 		}
 
+		reviews, err := c.GetReviewsFor(pr.Repo, pr.Number)
+		if err != nil {
+			return fmt.Errorf("getting reviews for PR %d: %w", pr.Number, err)
+		}
+
 		eventMap := map[time.Time]cache.Event{}
 		var eventDates []time.Time
 		for _, e := range events {
@@ -193,6 +198,7 @@ func GraphMultiRepoOpenPRsDaily(c *cache.Cache, outPath string, from, to time.Ti
 		state := "open"
 		daysWaiting := 0
 		eventIndex := 0
+		reviewIndex := 0
 		for day := opened; ; day = day.AddDate(0, 0, 1) {
 			if day.Before(from) {
 				continue
@@ -223,6 +229,13 @@ func GraphMultiRepoOpenPRsDaily(c *cache.Cache, outPath string, from, to time.Ti
 				}
 			}
 
+			for ; reviewIndex < len(reviews) && reviews[reviewIndex].Submitted.Before(day.AddDate(0, 0, 1)); reviewIndex++ {
+				state = reviewedState(state, pr.User, reviews[reviewIndex])
+				if state != "waiting" {
+					daysWaiting = 0
+				}
+			}
+
 			switch state {
 			case "waiting":
 				daysWaiting++
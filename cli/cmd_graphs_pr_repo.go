@@ -33,7 +33,7 @@ func GraphsRepoOpenedPRsDaily(cache *cache.Cache, outPath string, from, to time.
 			dayEnd = to
 		}
 
-		stats, err := cache.CalculateRepoPRStatsForDateRange(dayStart, dayEnd, repos, f.Authors)
+		stats, err := cache.CalculateRepoPRStatsForDateRange(dayStart, dayEnd, f.Filter(repos))
 		if err != nil {
 			return fmt.Errorf("failed to query stats: %w", err)
 		}
@@ -138,7 +138,7 @@ func GraphRepoTotalPRsDaily(cache *cache.Cache, outPath string, from, to time.Ti
 
 	// previous totals todo ???? these would be the PRs from before the start date
 	/*
-		stats, err := cache.CalculateRepoPRStatsForDateRange(from.AddDate(-47, 0, 0), from.Add(-time.Nanosecond), f.Authors)
+		stats, err := cache.CalculateRepoPRStatsForDateRange(from.AddDate(-47, 0, 0), from.Add(-time.Nanosecond), f.Filter(repos))
 		if err != nil {
 			return fmt.Errorf("failed to query stats: %w", err)
 		}
@@ -163,7 +163,7 @@ func GraphRepoTotalPRsDaily(cache *cache.Cache, outPath string, from, to time.Ti
 			dayEnd = to
 		}
 
-		stats, err := cache.CalculateRepoPRStatsForDateRange(dayStart, dayEnd, repos, f.Authors)
+		stats, err := cache.CalculateRepoPRStatsForDateRange(dayStart, dayEnd, f.Filter(repos))
 		if err != nil {
 			return fmt.Errorf("failed to query stats: %w", err)
 		}
@@ -278,8 +278,29 @@ type DayStatsPRs struct {
 	TrendSevenDay int
 }
 
+// reviewedState moves a PR between open/waiting and approved as reviews come in, a PR stays approved until a
+// change is requested or a label/milestone event moves it on. authors can't approve their own PRs.
+func reviewedState(state, author string, r cache.Review) string {
+	if r.User == author {
+		return state
+	}
+
+	switch r.State {
+	case cache.ReviewStateApproved:
+		if state == "open" || state == "waiting" {
+			return "approved"
+		}
+	case cache.ReviewStateChangesRequested:
+		if state == "approved" {
+			return "open"
+		}
+	}
+
+	return state
+}
+
 func GraphRepoOpenPRsDaily(theCache *cache.Cache, outPath string, from, to time.Time, repos []string) error {
-	// f := GetFlags() // todo out path ends up in flags
+	f := GetFlags() // todo out path ends up in flags
 
 	c.Printf("    PRs open daily..\n")
 
@@ -293,7 +314,7 @@ func GraphRepoOpenPRsDaily(theCache *cache.Cache, outPath string, from, to time.
 	}
 
 	// get all prs for range
-	prs, err := theCache.GetRepoPRsOpenForDateRange(repos, from, to)
+	prs, err := theCache.GetRepoPRsOpenForDateRange(f.Filter(repos), from, to)
 	if err != nil {
 		return fmt.Errorf("getting PRs: %w", err)
 	}
@@ -315,6 +336,11 @@ func GraphRepoOpenPRsDaily(theCache *cache.Cache, outPath string, from, to time.
 			return fmt.Errorf("getting events for PR %d: %w", pr.Number, err)
 		}
 
+		reviews, err := theCache.GetReviewsFor(pr.Repo, pr.Number)
+		if err != nil {
+			return fmt.Errorf("getting reviews for PR %d: %w", pr.Number, err)
+		}
+
 		eventMap := map[time.Time]cache.Event{}
 		var eventDates []time.Time
 		for _, e := range events {
@@ -328,6 +354,7 @@ func GraphRepoOpenPRsDaily(theCache *cache.Cache, outPath string, from, to time.
 		state := "open"
 		daysWaiting := 0
 		eventIndex := 0
+		reviewIndex := 0
 		for day := opened; ; day = day.AddDate(0, 0, 1) {
 			if day.Before(from.AddDate(0, 0, -1)) {
 				continue
@@ -358,6 +385,13 @@ func GraphRepoOpenPRsDaily(theCache *cache.Cache, outPath string, from, to time.
 				}
 			}
 
+			for ; reviewIndex < len(reviews) && reviews[reviewIndex].Submitted.Before(day.AddDate(0, 0, 1)); reviewIndex++ {
+				state = reviewedState(state, pr.User, reviews[reviewIndex])
+				if state != "waiting" {
+					daysWaiting = 0
+				}
+			}
+
 			switch state {
 			case "waiting":
 				daysWaiting++
@@ -399,7 +433,7 @@ func GraphRepoOpenPRsDaily(theCache *cache.Cache, outPath string, from, to time.
 		trendTotal := 0
 		for dayT := day.AddDate(0, 0, -(i - 1)); !dayT.After(day); dayT = dayT.AddDate(0, 0, 1) {
 			d := dates[dayT.Format("2006-01-02")]
-			trendTotal += d.Open + d.Blocked + d.Waiting + d.WaitingOver
+			trendTotal += d.Open + d.Blocked + d.Waiting + d.WaitingOver + d.Approved
 		}
 
 		d := dates[k]
@@ -408,7 +442,7 @@ func GraphRepoOpenPRsDaily(theCache *cache.Cache, outPath string, from, to time.
 	}
 
 	var xAxis []string
-	var lineOpen, lineBlocked, lineWaiting, lineWaitingOver, lineTrendLine []opts.LineData
+	var lineOpen, lineBlocked, lineWaiting, lineWaitingOver, lineApproved, lineTrendLine []opts.LineData
 
 	data := [][]string{{"date", "total", "open", "blocked", "waiting", "waiting-over", "approved", "7 day trend"}}
 
@@ -436,6 +470,7 @@ func GraphRepoOpenPRsDaily(theCache *cache.Cache, outPath string, from, to time.
 		lineBlocked = append(lineBlocked, opts.LineData{Value: day.Blocked})
 		lineWaiting = append(lineWaiting, opts.LineData{Value: day.Waiting})
 		lineWaitingOver = append(lineWaitingOver, opts.LineData{Value: day.WaitingOver})
+		lineApproved = append(lineApproved, opts.LineData{Value: day.Approved})
 		lineTrendLine = append(lineTrendLine, opts.LineData{Value: day.TrendSevenDay})
 	}
 
@@ -483,7 +518,7 @@ func GraphRepoOpenPRsDaily(theCache *cache.Cache, outPath string, from, to time.
 			Width:  "1500px",
 			Height: "750px",
 		}),
-		charts.WithColorsOpts(opts.Colors{"#C13530", "#2E4555", "#62A0A8", "#5470c6", "#000000"}),
+		charts.WithColorsOpts(opts.Colors{"#C13530", "#2E4555", "#62A0A8", "#91CC75", "#5470c6", "#000000"}),
 		charts.WithToolboxOpts(opts.Toolbox{Show: true}),
 		charts.WithTooltipOpts(opts.Tooltip{
 			Show:      true,
@@ -509,6 +544,7 @@ func GraphRepoOpenPRsDaily(theCache *cache.Cache, outPath string, from, to time.
 	graph.AddSeries("Blocked", lineBlocked)
 	graph.AddSeries("Waiting Over 14", lineWaitingOver)
 	graph.AddSeries("Waiting", lineWaiting)
+	graph.AddSeries("Approved", lineApproved)
 	graph.AddSeries("Open", lineOpen).SetSeriesOptions(prStackOps...)
 
 	graph.AddSeries("Total - 7 day avg", lineTrendLine)
@@ -533,7 +569,7 @@ type DayStatsPRsByAuthor struct {
 }
 
 func GraphRepoOpenPRsByAuthorsDaily(theCache *cache.Cache, outPath string, from, to time.Time, repos []string) error {
-	// f := GetFlags()
+	f := GetFlags()
 
 	// todo make args
 	authorGroupings := map[string][]string{
@@ -568,7 +604,7 @@ func GraphRepoOpenPRsByAuthorsDaily(theCache *cache.Cache, outPath string, from,
 	}
 
 	// get all prs for range
-	prs, err := theCache.GetRepoPRsOpenForDateRange(repos, from, to)
+	prs, err := theCache.GetRepoPRsOpenForDateRange(f.Filter(repos), from, to)
 	if err != nil {
 		return fmt.Errorf("getting PRs: %w", err)
 	}
@@ -737,6 +773,211 @@ func GraphRepoOpenPRsByAuthorsDaily(theCache *cache.Cache, outPath string, from,
 	return nil
 }
 
+func GraphRepoPRsBySize(cache *cache.Cache, outPath string, from, to time.Time, repos []string) error {
+	f := GetFlags() // todo out path ends up in flags
+
+	c.Printf("    PRs by size..\n")
+
+	stats, err := cache.CalculateRepoPRSizeStatsForDateRange(from, to, f.Filter(repos))
+	if err != nil {
+		return fmt.Errorf("failed to query size stats: %w", err)
+	}
+
+	var xAxis []string
+	var daysOpen, daysToFirst []opts.BarData
+
+	data := [][]string{{"size", "total", "open", "lines", "files", "days open", "days to first"}}
+	for _, s := range stats {
+		xAxis = append(xAxis, fmt.Sprintf("%s (%d)", s.Size, s.Total))
+		daysOpen = append(daysOpen, opts.BarData{Value: strconv.FormatFloat(s.DaysOpenAverage.Float64, 'f', 2, 64)})
+		daysToFirst = append(daysToFirst, opts.BarData{Value: strconv.FormatFloat(s.DaysToFirstAverage.Float64, 'f', 2, 64)})
+
+		data = append(data,
+			[]string{
+				s.Size,
+				strconv.Itoa(s.Total),
+				strconv.Itoa(s.Open),
+				strconv.FormatFloat(s.LinesAverage.Float64, 'f', 2, 64),
+				strconv.FormatFloat(s.FilesAverage.Float64, 'f', 2, 64),
+				strconv.FormatFloat(s.DaysOpenAverage.Float64, 'f', 2, 64),
+				strconv.FormatFloat(s.DaysToFirstAverage.Float64, 'f', 2, 64),
+			})
+	}
+
+	// write raw data
+	file, err := os.Create(outPath + "/prs-by-size.csv")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	csv := csv.NewWriter(file)
+	defer csv.Flush()
+
+	for _, r := range data {
+		err := csv.Write(r)
+		if err != nil {
+			return fmt.Errorf("writing to csv vile file: %w", err)
+		}
+	}
+
+	var repoShortNames []string
+	for _, r := range repos {
+		repoShortNames = append(repoShortNames, gh.RepoShortName(r))
+	}
+
+	// render graph
+	graph := charts.NewBar()
+	graph.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title:    strings.Join(repoShortNames, ",") + " PRs by Size",
+			Subtitle: "Average days open and to first response by lines changed: XS < 10, S < 50, M < 250, L < 1000, XL",
+			Left:     "center", // nolint:misspell
+		}),
+
+		charts.WithXAxisOpts(opts.XAxis{
+			Name: "Size (PRs)",
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Name: "Days",
+		}),
+		charts.WithInitializationOpts(opts.Initialization{
+			Width:  "1500px",
+			Height: "750px",
+		}),
+		charts.WithColorsOpts(opts.Colors{"#2E4555", "#62A0A8"}),
+		charts.WithToolboxOpts(opts.Toolbox{Show: true}),
+		charts.WithTooltipOpts(opts.Tooltip{
+			Show:      true,
+			Trigger:   "axis",
+			TriggerOn: "mousemove",
+		}),
+		charts.WithLegendOpts(opts.Legend{
+			Show: true,
+			Top:  "bottom",
+			Left: "center", // nolint:misspell
+		}),
+	)
+
+	graph.SetXAxis(xAxis).
+		AddSeries("Days Open", daysOpen).
+		AddSeries("Days To First", daysToFirst)
+
+	file, err = os.Create(outPath + "/prs-by-size.html")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	err = graph.Render(file)
+	if err != nil {
+		return fmt.Errorf("failed to render graph chart: %w", err)
+	}
+
+	return nil
+}
+
+func GraphRepoReleaseLeadTime(cache *cache.Cache, outPath string, from, to time.Time, repos []string) error {
+	f := GetFlags() // todo out path ends up in flags
+
+	c.Printf("    release lead time..\n")
+
+	stats, err := cache.CalculateReleaseStatsForDateRange(from, to, f.Filter(repos))
+	if err != nil {
+		return fmt.Errorf("failed to query release stats: %w", err)
+	}
+
+	var xAxis []string
+	var daysToRelease, mergedToRelease []opts.BarData
+
+	data := [][]string{{"repo", "release", "published", "prs", "days to release", "merged to release"}}
+	for _, s := range stats {
+		xAxis = append(xAxis, fmt.Sprintf("%s (%d)", s.Tag, s.PRs))
+		daysToRelease = append(daysToRelease, opts.BarData{Value: strconv.FormatFloat(s.DaysToReleaseAverage.Float64, 'f', 2, 64)})
+		mergedToRelease = append(mergedToRelease, opts.BarData{Value: strconv.FormatFloat(s.MergedToReleaseAverage.Float64, 'f', 2, 64)})
+
+		data = append(data,
+			[]string{
+				s.Repo,
+				s.Tag,
+				s.Published.Format("2006-01-02"),
+				strconv.Itoa(s.PRs),
+				strconv.FormatFloat(s.DaysToReleaseAverage.Float64, 'f', 2, 64),
+				strconv.FormatFloat(s.MergedToReleaseAverage.Float64, 'f', 2, 64),
+			})
+	}
+
+	// write raw data
+	file, err := os.Create(outPath + "/releases-lead-time.csv")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	csv := csv.NewWriter(file)
+	defer csv.Flush()
+
+	for _, r := range data {
+		err := csv.Write(r)
+		if err != nil {
+			return fmt.Errorf("writing to csv vile file: %w", err)
+		}
+	}
+
+	var repoShortNames []string
+	for _, r := range repos {
+		repoShortNames = append(repoShortNames, gh.RepoShortName(r))
+	}
+
+	// render graph
+	graph := charts.NewBar()
+	graph.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title:    strings.Join(repoShortNames, ",") + " Release Lead Time",
+			Subtitle: "Average days from opened and from merged until the prs shipped in each release",
+			Left:     "center", // nolint:misspell
+		}),
+
+		charts.WithXAxisOpts(opts.XAxis{
+			Name: "Release (PRs)",
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Name: "Days",
+		}),
+		charts.WithInitializationOpts(opts.Initialization{
+			Width:  "1500px",
+			Height: "750px",
+		}),
+		charts.WithColorsOpts(opts.Colors{"#2E4555", "#91CC75"}),
+		charts.WithToolboxOpts(opts.Toolbox{Show: true}),
+		charts.WithTooltipOpts(opts.Tooltip{
+			Show:      true,
+			Trigger:   "axis",
+			TriggerOn: "mousemove",
+		}),
+		charts.WithLegendOpts(opts.Legend{
+			Show: true,
+			Top:  "bottom",
+			Left: "center", // nolint:misspell
+		}),
+	)
+
+	graph.SetXAxis(xAxis).
+		AddSeries("Days To Release", daysToRelease).
+		AddSeries("Merged To Release", mergedToRelease)
+
+	file, err = os.Create(outPath + "/releases-lead-time.html")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	err = graph.Render(file)
+	if err != nil {
+		return fmt.Errorf("failed to render graph chart: %w", err)
+	}
+
+	return nil
+}
+
 type WeekStatsPRs struct {
 	Total           int
 	OpenDays        float64
@@ -763,7 +1004,7 @@ func GraphRepoWeeklyPrStats(repo string, c *cache.Cache, outPath string, from, t
 	for day := from; day.Before(to); day = day.AddDate(0, 0, 7) {
 		year, week := day.ISOWeek()
 
-		stats, err := c.CalculateRepoPRStatsForDateRange(repo, day, day.AddDate(0, 0, 7).Add(-time.Nanosecond), []string{})
+		stats, err := c.CalculateRepoPRStatsForDateRange(day, day.AddDate(0, 0, 7).Add(-time.Nanosecond), cache.Filter{Repos: []string{repo}})
 		if err != nil {
 			return fmt.Errorf("failed to query stats: %w", err)
 		}
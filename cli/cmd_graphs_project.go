@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+	c "github.com/gookit/color"
+	"github.com/katbyte/gogo-repo-stats/lib/cache"
+)
+
+// GraphProjectCumulativeFlow graphs how many of the board's items had each value of field at the end of every day
+func GraphProjectCumulativeFlow(cache *cache.Cache, outPath string, from, to time.Time, project, field string) error {
+	c.Printf("    Project cumulative flow..\n")
+
+	spans, err := cache.GetProjectFieldHistory(project, field)
+	if err != nil {
+		return fmt.Errorf("getting project history: %w", err)
+	}
+	c.Printf("      %d %s values found\n", len(spans), field)
+
+	var values []string
+	seen := map[string]bool{}
+	for _, s := range spans {
+		if !seen[s.Value] {
+			seen[s.Value] = true
+			values = append(values, s.Value)
+		}
+	}
+	sort.Strings(values)
+
+	var xAxis []string
+	lineData := map[string][]opts.LineData{}
+	data := [][]string{append([]string{"date"}, values...)}
+
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		end := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location()).AddDate(0, 0, 1)
+
+		// an item is counted in the column it was in as the day ended
+		counts := map[string]int{}
+		for _, s := range spans {
+			if s.Since.Before(end) && (s.Until.IsZero() || !s.Until.Before(end)) {
+				counts[s.Value]++
+			}
+		}
+
+		date := day.Format("2006-01-02")
+		xAxis = append(xAxis, date)
+
+		row := []string{date}
+		for _, v := range values {
+			row = append(row, strconv.Itoa(counts[v]))
+			lineData[v] = append(lineData[v], opts.LineData{Value: counts[v]})
+		}
+		data = append(data, row)
+	}
+
+	// write raw data
+	file, err := os.Create(outPath + "/project-cumulative-flow.csv")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	csv := csv.NewWriter(file)
+	defer csv.Flush()
+
+	for _, r := range data {
+		if err := csv.Write(r); err != nil {
+			return fmt.Errorf("failed to write csv: %w", err)
+		}
+	}
+
+	// render graph
+	graph := charts.NewLine()
+	graph.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title:    project + " Cumulative Flow (daily)",
+			Subtitle: "Items by " + field,
+			Left:     "center", // nolint:misspell
+		}),
+
+		charts.WithXAxisOpts(opts.XAxis{
+			Name: "Date",
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Name: "Items",
+		}),
+		charts.WithInitializationOpts(opts.Initialization{
+			Width:  "1500px",
+			Height: "750px",
+		}),
+		charts.WithToolboxOpts(opts.Toolbox{Show: true}),
+		charts.WithTooltipOpts(opts.Tooltip{
+			Show:      true,
+			Trigger:   "axis",
+			TriggerOn: "mousemove",
+		}),
+		charts.WithLegendOpts(opts.Legend{
+			Show: true,
+			Top:  "bottom",
+			Left: "center", // nolint:misspell
+		}),
+	)
+
+	graph.SetXAxis(xAxis)
+
+	for _, v := range values {
+		graph.AddSeries(v, lineData[v])
+	}
+	graph.SetSeriesOptions(
+		charts.WithAreaStyleOpts(opts.AreaStyle{Opacity: 0.8}),
+		charts.WithLineChartOpts(opts.LineChart{Stack: "items"}),
+		charts.WithLineStyleOpts(opts.LineStyle{Width: 1, Opacity: 0.9}),
+	)
+
+	file, err = os.Create(outPath + "/project-cumulative-flow.html")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	err = graph.Render(file)
+	if err != nil {
+		return fmt.Errorf("failed to render graph graph: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,345 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+	c "github.com/gookit/color" // nolint:misspell
+	cachepkg "github.com/katbyte/gogo-repo-stats/lib/cache"
+	"github.com/katbyte/gogo-repo-stats/lib/gh"
+	"github.com/spf13/cobra"
+)
+
+// ingest fills the cache from exported json instead of the api, so repos can be backfilled or audited without a token
+// and the report & graphs work on archived data. two kinds of export are understood, either can be gzipped:
+//   - what `gh api --paginate` prints for pulls, issues & timelines: a json array per page or, with --jq '.[]', an
+//     object per line
+//   - GH Archive hourly files (https://www.gharchive.org) of activity events, applied just like webhook deliveries
+//
+// a timeline doesn't say which pr or issue its events are for, so a timeline export is given as owner/repo#number=FILE
+
+// itemFile matches the owner/repo#number= prefix of a timeline export
+var itemFile = regexp.MustCompile(`^([^=#]+/[^=#]+)#(\d+)=(.+)$`)
+
+type ingestItem struct {
+	Repo   string
+	Number int
+}
+
+type ingester struct {
+	*eventWriter
+
+	// the item timeline events without an issue reference of their own belong to
+	item *ingestItem
+
+	// prs whose stats need computing once everything is ingested
+	prs map[ingestItem]bool
+
+	PRs, Issues, Events, Archived, Skipped int
+}
+
+// ingestProbe is just enough of an object to tell what it is
+type ingestProbe struct {
+	Type    *string          `json:"type"`
+	Payload *json.RawMessage `json:"payload"`
+
+	Event *string `json:"event"`
+
+	Number      *int             `json:"number"`
+	Head        *json.RawMessage `json:"head"`
+	PullRequest *json.RawMessage `json:"pull_request"`
+
+	RepositoryURL  string `json:"repository_url"`
+	IssueURL       string `json:"issue_url"`
+	PullRequestURL string `json:"pull_request_url"`
+	Issue          *struct {
+		Number        int    `json:"number"`
+		RepositoryURL string `json:"repository_url"`
+	} `json:"issue"`
+}
+
+func CmdIngest(_ *cobra.Command, args []string) error {
+	f := GetFlags()
+
+	if err := f.Host().Validate(); err != nil {
+		return err
+	}
+
+	cache, err := cachepkg.Open(f.CachePath)
+	if err != nil {
+		return fmt.Errorf("opening cache %s: %w", f.CachePath, err)
+	}
+	defer cache.DB.Close()
+
+	writer, err := newEventWriter(f, cache)
+	if err != nil {
+		return err
+	}
+
+	in := ingester{
+		eventWriter: writer,
+		prs:         map[ingestItem]bool{},
+	}
+
+	for _, arg := range args {
+		path := arg
+		in.item = nil
+		if m := itemFile.FindStringSubmatch(arg); m != nil {
+			n, _ := strconv.Atoi(m[2])
+			in.item = &ingestItem{Repo: m[1], Number: n}
+			path = m[3]
+		}
+
+		c.Printf("Ingesting <white>%s</>...\n", path)
+		if err := in.file(path); err != nil {
+			return fmt.Errorf("ingesting %s: %w", path, err)
+		}
+	}
+
+	// timelines may have been ingested before their pr so stats are only computed once everything is in
+	items := make([]ingestItem, 0, len(in.prs))
+	for i := range in.prs {
+		items = append(items, i)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Repo != items[j].Repo {
+			return items[i].Repo < items[j].Repo
+		}
+		return items[i].Number < items[j].Number
+	})
+
+	for _, i := range items {
+		if err := in.computePRStats(i.Repo, i.Number); err != nil {
+			return err
+		}
+	}
+
+	c.Printf("Ingested <green>%d</> prs, <green>%d</> issues, <green>%d</> timeline events & <green>%d</> archive events, skipped <yellow>%d</>\n", in.PRs, in.Issues, in.Events, in.Archived, in.Skipped)
+	return nil
+}
+
+func (in *ingester) file(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var r io.Reader = bufio.NewReader(file)
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	// a stream of values copes with both a line per object and the back to back pages of --paginate
+	d := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		if err := d.Decode(&raw); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("decoding json: %w", err)
+		}
+
+		if !bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+			if err := in.object(raw); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var page []json.RawMessage
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return fmt.Errorf("decoding json: %w", err)
+		}
+		for _, o := range page {
+			if err := in.object(o); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (in *ingester) object(raw json.RawMessage) error {
+	var p ingestProbe
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return fmt.Errorf("decoding json: %w", err)
+	}
+
+	switch {
+	case p.Type != nil && p.Payload != nil:
+		return in.archived(raw)
+	case p.Event != nil:
+		return in.timeline(raw, p)
+	case p.Number != nil && p.Head != nil:
+		return in.pr(raw)
+	case p.Number != nil && p.PullRequest == nil:
+		return in.issue(raw, p)
+	}
+
+	// prs listed through the issues api are missing their merge details, the pulls export has them
+	in.Skipped++
+	return nil
+}
+
+func (in *ingester) pr(raw json.RawMessage) error {
+	var pr github.PullRequest
+	if err := json.Unmarshal(raw, &pr); err != nil {
+		return fmt.Errorf("decoding pr: %w", err)
+	}
+
+	repo, ok := in.accept(pr.GetBase().GetRepo().GetFullName())
+	if !ok {
+		in.Skipped++
+		return nil
+	}
+
+	c.Printf(" <cyan>%s</> pr <yellow>#%d</> <darkGray>(%s)</>: %s\n", repo, pr.GetNumber(), pr.GetCreatedAt().Format("2006-01-02"), pr.GetTitle())
+	if err := in.cache.UpsertRepoPRFromGH(repo, &pr); err != nil {
+		return fmt.Errorf("cache upsert failed: %w", err)
+	}
+
+	in.PRs++
+	in.prs[ingestItem{repo, pr.GetNumber()}] = true
+	return nil
+}
+
+func (in *ingester) issue(raw json.RawMessage, p ingestProbe) error {
+	var issue github.Issue
+	if err := json.Unmarshal(raw, &issue); err != nil {
+		return fmt.Errorf("decoding issue: %w", err)
+	}
+
+	ownerName, _ := apiURLItem(p.RepositoryURL)
+	repo, ok := in.accept(ownerName)
+	if ownerName == "" || !ok {
+		in.Skipped++
+		return nil
+	}
+
+	c.Printf(" <cyan>%s</> issue <yellow>#%d</> <darkGray>(%s)</>: %s\n", repo, issue.GetNumber(), issue.GetCreatedAt().Format("2006-01-02"), issue.GetTitle())
+	if err := in.cache.UpsertRepoIssueFromGH(repo, &issue); err != nil {
+		return fmt.Errorf("cache upsert failed: %w", err)
+	}
+
+	in.Issues++
+	return nil
+}
+
+func (in *ingester) timeline(raw json.RawMessage, p ingestProbe) error {
+	var t gh.TimelineEvent
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return fmt.Errorf("decoding timeline event: %w", err)
+	}
+
+	// the issue events api includes the issue, some timeline events link back to it, the rest need to be told
+	var ownerName string
+	var number int
+	switch {
+	case p.Issue != nil:
+		ownerName, _ = apiURLItem(p.Issue.RepositoryURL)
+		number = p.Issue.Number
+	case p.IssueURL != "":
+		ownerName, number = apiURLItem(p.IssueURL)
+	case p.PullRequestURL != "":
+		ownerName, number = apiURLItem(p.PullRequestURL)
+	case in.item != nil:
+		ownerName, number = in.item.Repo, in.item.Number
+	}
+
+	repo, ok := in.accept(ownerName)
+	if ownerName == "" || number == 0 || !ok {
+		in.Skipped++
+		return nil
+	}
+
+	if err := in.cache.UpsertEvent(repo, number, &t); err != nil {
+		return fmt.Errorf("cache upsert failed: %w", err)
+	}
+
+	in.Events++
+	in.prs[ingestItem{repo, number}] = true
+	return nil
+}
+
+// archived applies a GH Archive event, the payloads are those of the matching webhook without the repo & sender
+func (in *ingester) archived(raw json.RawMessage) error {
+	var e github.Event
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return fmt.Errorf("decoding archive event: %w", err)
+	}
+
+	if _, ok := in.accept(e.GetRepo().GetName()); !ok {
+		in.Skipped++
+		return nil
+	}
+
+	payload, err := e.ParsePayload()
+	if err != nil {
+		// an event type go-github doesn't know about
+		in.Skipped++
+		return nil
+	}
+
+	repo := &github.Repository{FullName: e.GetRepo().Name}
+	switch ev := payload.(type) {
+	case *github.PullRequestEvent:
+		ev.Repo, ev.Sender = repo, e.Actor
+	case *github.PullRequestReviewEvent:
+		ev.Repo, ev.Sender = repo, e.Actor
+	case *github.IssuesEvent:
+		ev.Repo, ev.Sender = repo, e.Actor
+	case *github.IssueCommentEvent:
+		ev.Repo, ev.Sender = repo, e.Actor
+	default:
+		in.Skipped++
+		return nil
+	}
+
+	var extra eventPayload
+	if err := json.Unmarshal(*e.RawPayload, &extra); err != nil {
+		return fmt.Errorf("decoding archive event: %w", err)
+	}
+
+	if err := in.handle(payload, extra); err != nil {
+		return fmt.Errorf("applying %s %s: %w", e.GetType(), e.GetID(), err)
+	}
+
+	in.Archived++
+	return nil
+}
+
+// apiURLItem returns the owner/name and number of an api url like https://api.github.com/repos/owner/name/issues/1,
+// without a number for the repo's own url
+func apiURLItem(u string) (string, int) {
+	parts := strings.Split(u, "/")
+	for i, p := range parts {
+		if p != "repos" || i+2 >= len(parts) {
+			continue
+		}
+
+		ownerName := parts[i+1] + "/" + parts[i+2]
+		if i+4 < len(parts) {
+			n, _ := strconv.Atoi(parts[i+4])
+			return ownerName, n
+		}
+		return ownerName, 0
+	}
+
+	return "", 0
+}
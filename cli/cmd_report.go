@@ -61,11 +61,14 @@ func CmdReport(_ *cobra.Command, args []string) error {
 	}
 	defer cache.DB.Close()
 
+	f.Repos, err = resolveRepos(f, cache, nil)
+	if err != nil {
+		return err
+	}
+
 	c.Printf("Generating reports forall PRs from <white>%s</> to <white>%s</>...\n", from.Format("2006-01-02"), to.Format("2006-01-02"))
 	c.Printf("  for repos: <cyan>%s</>\n", strings.Join(f.Repos, "</>, <cyan>"))
-	if len(f.Authors) > 0 {
-		c.Printf("  for authors: <green>%s</>\n", strings.Join(f.Authors, "</>, <green>"))
-	}
+	printFilters(f)
 
 	// for each month
 	for month := from; month.Before(to); month = month.AddDate(0, 1, 0) {
@@ -78,9 +81,9 @@ func CmdReport(_ *cobra.Command, args []string) error {
 
 		t := table.NewWriter()
 		t.SetOutputMirror(os.Stdout)
-		t.AppendHeader(table.Row{c.Sprintf("<yellow>%s</>", month.Format("2006-01")), "Opened", "Open", "Days Open", "Days Wait", "Days First", "First Over"})
+		t.AppendHeader(table.Row{c.Sprintf("<yellow>%s</>", month.Format("2006-01")), "Opened", "Open", "Days Open", "Days Wait", "Days First", "First Over", "Days Green", "Merged Red", "Flaky", "Days Release", "Merged Release"})
 
-		var totalOpened, totalOpen, totalFirstOver int
+		var totalOpened, totalOpen, totalFirstOver, totalFlaky int
 
 		for _, repo := range f.Repos {
 
@@ -98,7 +101,7 @@ func CmdReport(_ *cobra.Command, args []string) error {
 					weekEnd = monthEnd
 				}
 
-				stats, err := cache.CalculateRepoPRStatsForDateRange(weekStart, weekEnd, []string{repo}, f.Authors)
+				stats, err := cache.CalculateRepoPRStatsForDateRange(weekStart, weekEnd, f.Filter([]string{repo}))
 				if err != nil {
 					return fmt.Errorf("failed to query stats: %w", err)
 				}
@@ -111,10 +114,15 @@ func CmdReport(_ *cobra.Command, args []string) error {
 					strconv.FormatFloat(stats.DaysWaitingAverage.Float64, 'f', 2, 64),
 					strconv.FormatFloat(stats.DaysToFirstAverage.Float64, 'f', 2, 64),
 					strconv.Itoa(stats.DaysToFirstOver),
+					strconv.FormatFloat(stats.DaysToGreenAverage.Float64, 'f', 2, 64),
+					mergedRedPercent(stats),
+					strconv.Itoa(stats.Flaky),
+					strconv.FormatFloat(stats.DaysToReleaseAverage.Float64, 'f', 2, 64),
+					strconv.FormatFloat(stats.MergedToReleaseAverage.Float64, 'f', 2, 64),
 				}})
 			}
 
-			stats, err := cache.CalculateRepoPRStatsForDateRange(monthStart, monthEnd, []string{repo}, f.Authors)
+			stats, err := cache.CalculateRepoPRStatsForDateRange(monthStart, monthEnd, f.Filter([]string{repo}))
 			if err != nil {
 				return fmt.Errorf("failed to query stats: %w", err)
 			}
@@ -128,12 +136,18 @@ func CmdReport(_ *cobra.Command, args []string) error {
 				strconv.FormatFloat(stats.DaysWaitingAverage.Float64, 'f', 2, 64),
 				strconv.FormatFloat(stats.DaysToFirstAverage.Float64, 'f', 2, 64),
 				strconv.Itoa(stats.DaysToFirstOver),
+				strconv.FormatFloat(stats.DaysToGreenAverage.Float64, 'f', 2, 64),
+				mergedRedPercent(stats),
+				strconv.Itoa(stats.Flaky),
+				strconv.FormatFloat(stats.DaysToReleaseAverage.Float64, 'f', 2, 64),
+				strconv.FormatFloat(stats.MergedToReleaseAverage.Float64, 'f', 2, 64),
 			}})
 			t.AppendSeparator()
 
 			totalOpened += stats.Total
 			totalOpen += stats.Open
 			totalFirstOver += stats.DaysToFirstOver
+			totalFlaky += stats.Flaky
 		}
 
 		t.AppendFooter(table.Row{
@@ -144,6 +158,11 @@ func CmdReport(_ *cobra.Command, args []string) error {
 			"",
 			"",
 			strconv.Itoa(totalFirstOver),
+			"",
+			"",
+			strconv.Itoa(totalFlaky),
+			"",
+			"",
 		})
 		t.Render() // Send output
 		fmt.Println()
@@ -153,16 +172,16 @@ func CmdReport(_ *cobra.Command, args []string) error {
 
 	t := table.NewWriter()
 	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{c.Sprintf("<yellow>%s</><><yellow>%s</>", from.Format("2006-01-02"), to.Format("2006-01-02")), "Opened", "Open", "Days Open", "Days Wait", "Days First", "First Over"})
+	t.AppendHeader(table.Row{c.Sprintf("<yellow>%s</><><yellow>%s</>", from.Format("2006-01-02"), to.Format("2006-01-02")), "Opened", "Open", "Days Open", "Days Wait", "Days First", "First Over", "Days Green", "Merged Red", "Flaky", "Days Release", "Merged Release"})
 	t.AppendSeparator()
 
 	// calculate total stats for each repo
-	var totalOpened, totalOpen, totalFirstOver int
+	var totalOpened, totalOpen, totalFirstOver, totalFlaky int
 	for _, repo := range f.Repos {
 		// quick hack to shorten repo names
 		repoShort := gh.RepoShortName(repo)
 
-		stats, err := cache.CalculateRepoPRStatsForDateRange(from, to, []string{repo}, f.Authors)
+		stats, err := cache.CalculateRepoPRStatsForDateRange(from, to, f.Filter([]string{repo}))
 		if err != nil {
 			return fmt.Errorf("failed to query stats: %w", err)
 		}
@@ -175,11 +194,17 @@ func CmdReport(_ *cobra.Command, args []string) error {
 			strconv.FormatFloat(stats.DaysWaitingAverage.Float64, 'f', 2, 64),
 			strconv.FormatFloat(stats.DaysToFirstAverage.Float64, 'f', 2, 64),
 			strconv.Itoa(stats.DaysToFirstOver),
+			strconv.FormatFloat(stats.DaysToGreenAverage.Float64, 'f', 2, 64),
+			mergedRedPercent(stats),
+			strconv.Itoa(stats.Flaky),
+			strconv.FormatFloat(stats.DaysToReleaseAverage.Float64, 'f', 2, 64),
+			strconv.FormatFloat(stats.MergedToReleaseAverage.Float64, 'f', 2, 64),
 		}})
 
 		totalOpened += stats.Total
 		totalOpen += stats.Open
 		totalFirstOver += stats.DaysToFirstOver
+		totalFlaky += stats.Flaky
 	}
 	t.AppendSeparator()
 	t.AppendFooter(table.Row{
@@ -188,10 +213,104 @@ func CmdReport(_ *cobra.Command, args []string) error {
 		strconv.Itoa(totalOpen),
 		"",
 		"",
+		"",
 		strconv.Itoa(totalFirstOver),
+		"",
+		"",
+		strconv.Itoa(totalFlaky),
+		"",
+		"",
 	})
 	t.Render() // Send output
 	fmt.Println()
 	fmt.Println()
+
+	// how size relates to time open & to first response across all repos
+	sizes, err := cache.CalculateRepoPRSizeStatsForDateRange(from, to, f.Filter(f.Repos))
+	if err != nil {
+		return fmt.Errorf("failed to query size stats: %w", err)
+	}
+
+	t = table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{c.Sprintf("<yellow>Size</>"), "Opened", "Open", "Lines", "Files", "Days Open", "Days First"})
+	t.AppendSeparator()
+	for _, s := range sizes {
+		t.AppendRows([]table.Row{{
+			c.Sprintf("<cyan>%s</>", s.Size),
+			strconv.Itoa(s.Total),
+			strconv.Itoa(s.Open),
+			strconv.FormatFloat(s.LinesAverage.Float64, 'f', 0, 64),
+			strconv.FormatFloat(s.FilesAverage.Float64, 'f', 1, 64),
+			strconv.FormatFloat(s.DaysOpenAverage.Float64, 'f', 2, 64),
+			strconv.FormatFloat(s.DaysToFirstAverage.Float64, 'f', 2, 64),
+		}})
+	}
+	t.Render() // Send output
+	fmt.Println()
+	fmt.Println()
+
+	// how long items sit in each column of the board
+	if f.Project != 0 {
+		project, err := projectKey(f)
+		if err != nil {
+			return err
+		}
+
+		columns, err := cache.CalculateProjectColumnStats(project, f.ProjectField, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to query project column stats: %w", err)
+		}
+
+		t = table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{c.Sprintf("<yellow>%s</> <darkGray>(%s)</>", project, f.ProjectField), "Items", "Current", "Days", "Current Days"})
+		t.AppendSeparator()
+		for _, s := range columns {
+			t.AppendRows([]table.Row{{
+				c.Sprintf("<cyan>%s</>", s.Value),
+				strconv.Itoa(s.Items),
+				strconv.Itoa(s.Current),
+				strconv.FormatFloat(s.DaysAverage, 'f', 2, 64),
+				strconv.FormatFloat(s.CurrentDaysAverage, 'f', 2, 64),
+			}})
+		}
+		t.Render() // Send output
+		fmt.Println()
+		fmt.Println()
+	}
+
 	return nil
 }
+
+// mergedRedPercent is the share of merged prs that went in with a failing check on their head commit.
+func mergedRedPercent(stats *cache.PRsStats) string {
+	if stats.Merged == 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(float64(stats.MergedFailing)*100/float64(stats.Merged), 'f', 0, 64) + "%"
+}
+
+// printFilters shows what the report or graphs are narrowed to beyond the repos
+func printFilters(f FlagData) {
+	list := func(what string, values []string) {
+		if len(values) > 0 {
+			c.Printf("  %s: <green>%s</>\n", what, strings.Join(values, "</>, <green>"))
+		}
+	}
+
+	list("for authors", f.Authors)
+	list("without authors", f.ExcludeAuthors)
+	list("with labels", f.Labels)
+	list("in milestones", f.Milestones)
+	list("in states", f.States)
+	if f.NoDrafts {
+		c.Printf("  without <green>drafts</>\n")
+	}
+	if f.NoBots {
+		c.Printf("  without <green>bots</>\n")
+	}
+	if f.IncludeRemoved {
+		c.Printf("  including <green>removed</>\n")
+	}
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	c "github.com/gookit/color" // nolint:misspell
+	cachepkg "github.com/katbyte/gogo-repo-stats/lib/cache"
+	"github.com/spf13/cobra"
+)
+
+// rather than polling on a cron the webhook command has github tell us as things happen: each pull_request, issues,
+// pull_request_review, issue_comment & label delivery is verified against the shared secret and handed to an
+// eventWriter. nothing is fetched so no api quota is used
+
+// maxWebhookPayload is the most github sends in a delivery, bodies any bigger are cut off unread
+const maxWebhookPayload = 25 << 20
+
+type webhookServer struct {
+	*eventWriter
+	secret []byte
+
+	// deliveries are applied one at a time so an item's upserts and stats are never interleaved with another's
+	lock sync.Mutex
+}
+
+func CmdWebhook(_ *cobra.Command, _ []string) error {
+	f := GetFlags()
+
+	host := f.Host()
+	if err := host.Validate(); err != nil {
+		return err
+	}
+
+	cache, err := cachepkg.Open(f.CachePath)
+	if err != nil {
+		return fmt.Errorf("opening cache %s: %w", f.CachePath, err)
+	}
+	defer cache.DB.Close()
+
+	writer, err := newEventWriter(f, cache)
+	if err != nil {
+		return err
+	}
+
+	s := &webhookServer{
+		eventWriter: writer,
+		secret:      []byte(f.WebhookSecret),
+	}
+
+	c.Printf("Listening for github webhooks on <white>%s</>...\n", f.Listen)
+	server := &http.Server{
+		Addr:              f.Listen,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return server.ListenAndServe()
+}
+
+func (s *webhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "webhooks must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	delivery := github.DeliveryID(r)
+	kind := github.WebHookType(r)
+
+	payload, err := s.verify(w, r)
+	if err != nil {
+		c.Printf("<red>rejected</> %s delivery <darkGray>%s</>: %v\n", kind, delivery, err)
+
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	event, err := github.ParseWebHook(kind, payload)
+	if err != nil {
+		c.Printf("<darkGray>ignoring %s delivery %s: %v</>\n", kind, delivery, err)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	var extra eventPayload
+	if err := json.Unmarshal(payload, &extra); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.handle(event, extra); err != nil {
+		c.Printf("<red>failed</> %s delivery <darkGray>%s</>: %v\n", kind, delivery, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verify reads a delivery's body, no more than github ever sends, and returns its json payload if X-Hub-Signature-256
+// is the body signed with the secret. the sha1 X-Hub-Signature github also sends is never accepted
+func (s *webhookServer) verify(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	signature := r.Header.Get(github.SHA256SignatureHeader)
+	if !strings.HasPrefix(signature, "sha256=") {
+		return nil, fmt.Errorf("missing %s", github.SHA256SignatureHeader)
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookPayload))
+	if err != nil {
+		return nil, fmt.Errorf("reading body: %w", err)
+	}
+
+	if err = github.ValidateSignature(signature, body, s.secret); err != nil {
+		return nil, err
+	}
+
+	// the payload is the body, or its payload field when the webhook is set to send a form
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing content type: %w", err)
+	}
+
+	switch contentType {
+	case "application/json":
+		return body, nil
+	case "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("parsing form: %w", err)
+		}
+		return []byte(form.Get("payload")), nil
+	default:
+		return nil, fmt.Errorf("unsupported content type %q", contentType)
+	}
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" // nolint:gosec
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const testWebhookSecret = "its-a-secret"
+
+func signWebhook(h func() hash.Hash, body string) string {
+	mac := hmac.New(h, []byte(testWebhookSecret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookSignature(t *testing.T) {
+	ping := `{"zen":"Keep it logically awesome.","hook_id":1}`
+	form := url.Values{"payload": {ping}}.Encode()
+
+	cases := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		headers     map[string]string
+		status      int
+	}{
+		{
+			name:        "signed json",
+			contentType: "application/json",
+			body:        ping,
+			headers:     map[string]string{"X-Hub-Signature-256": "sha256=" + signWebhook(sha256.New, ping)},
+			status:      http.StatusNoContent,
+		},
+		{
+			name:        "signed form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        form,
+			headers:     map[string]string{"X-Hub-Signature-256": "sha256=" + signWebhook(sha256.New, form)},
+			status:      http.StatusNoContent,
+		},
+		{
+			name:        "unsigned",
+			contentType: "application/json",
+			body:        ping,
+			status:      http.StatusUnauthorized,
+		},
+		{
+			name:        "only signed with sha1",
+			contentType: "application/json",
+			body:        ping,
+			headers:     map[string]string{"X-Hub-Signature": "sha1=" + signWebhook(sha1.New, ping)},
+			status:      http.StatusUnauthorized,
+		},
+		{
+			name:        "sha1 signature in the sha256 header",
+			contentType: "application/json",
+			body:        ping,
+			headers:     map[string]string{"X-Hub-Signature-256": "sha1=" + signWebhook(sha1.New, ping)},
+			status:      http.StatusUnauthorized,
+		},
+		{
+			name:        "signed with another secret",
+			contentType: "application/json",
+			body:        ping,
+			headers:     map[string]string{"X-Hub-Signature-256": "sha256=" + signWebhook(sha256.New, ping+"tampered")},
+			status:      http.StatusUnauthorized,
+		},
+		{
+			name:        "too large",
+			contentType: "application/json",
+			body:        strings.Repeat(" ", maxWebhookPayload+1),
+			headers:     map[string]string{"X-Hub-Signature-256": "sha256=" + signWebhook(sha256.New, ping)},
+			status:      http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "not posted",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+		},
+	}
+
+	// pings are answered without touching the writer so it is not needed
+	s := &webhookServer{secret: []byte(testWebhookSecret)}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodPost
+			}
+
+			r := httptest.NewRequest(method, "/", strings.NewReader(tc.body))
+			r.Header.Set("Content-Type", tc.contentType)
+			r.Header.Set("X-GitHub-Event", "ping")
+			r.Header.Set("X-GitHub-Delivery", "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("expected status %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
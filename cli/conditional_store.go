@@ -0,0 +1,40 @@
+package cli
+
+import (
+	cachepkg "github.com/katbyte/gogo-repo-stats/lib/cache"
+	"github.com/katbyte/gogo-repo-stats/lib/gh"
+)
+
+// conditionalStore keeps the conditional transport's validators & payloads in the cache's http_cache table
+type conditionalStore struct {
+	cache *cachepkg.Cache
+}
+
+var _ gh.ConditionalStore = conditionalStore{}
+
+func (s conditionalStore) GetConditionalResponse(url string) (*gh.ConditionalResponse, error) {
+	r, err := s.cache.GetConditionalResponse(url)
+	if err != nil || r == nil {
+		return nil, err
+	}
+
+	return &gh.ConditionalResponse{
+		ETag:         r.ETag,
+		LastModified: r.LastModified,
+		Header:       r.Header,
+		Body:         r.Body,
+	}, nil
+}
+
+func (s conditionalStore) UpsertConditionalResponse(url string, r gh.ConditionalResponse) error {
+	return s.cache.UpsertConditionalResponse(url, cachepkg.ConditionalResponse{
+		ETag:         r.ETag,
+		LastModified: r.LastModified,
+		Header:       r.Header,
+		Body:         r.Body,
+	})
+}
+
+func (s conditionalStore) TouchConditionalResponse(url string) error {
+	return s.cache.TouchConditionalResponse(url)
+}
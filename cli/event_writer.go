@@ -0,0 +1,392 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	c "github.com/gookit/color" // nolint:misspell
+	cachepkg "github.com/katbyte/gogo-repo-stats/lib/cache"
+	"github.com/katbyte/gogo-repo-stats/lib/gh"
+)
+
+// an eventWriter applies github's activity events (webhook deliveries or the same payloads from an archive) to the
+// cache through the same functions fetch uses, recomputing a pr's stats after each. prs first seen through a review or
+// comment are left for the next fetch to pick up
+type eventWriter struct {
+	cache *cachepkg.Cache
+	host  gh.Host
+
+	// when repos or an org are given only their events are stored, otherwise every repo's are
+	repos map[string]bool
+}
+
+func newEventWriter(f FlagData, cache *cachepkg.Cache) (*eventWriter, error) {
+	w := &eventWriter{
+		cache: cache,
+		host:  f.Host(),
+	}
+
+	if len(f.Repos) > 0 || f.Org != "" {
+		repos, err := resolveRepos(f, cache, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		w.repos = map[string]bool{}
+		for _, r := range repos {
+			w.repos[r] = true
+		}
+		c.Printf("Accepting events for repos: <cyan>%s</>\n", strings.Join(repos, "</>, <cyan>"))
+	}
+
+	return w, nil
+}
+
+// eventPayload is what go-github's event types are missing: the milestone removed by a demilestoned action and the
+// old name of a renamed label
+type eventPayload struct {
+	Milestone *github.Milestone `json:"milestone,omitempty"`
+	Changes   struct {
+		Name struct {
+			From *string `json:"from,omitempty"`
+		} `json:"name"`
+	} `json:"changes"`
+}
+
+// timelineActions are the pull_request & issues actions that are also timeline events, mapped to the timeline name.
+// an edited title is handled on its own as a renamed event
+var timelineActions = map[string]string{
+	"closed":                 "closed",
+	"reopened":               "reopened",
+	"labeled":                "labeled",
+	"unlabeled":              "unlabeled",
+	"milestoned":             "milestoned",
+	"demilestoned":           "demilestoned",
+	"assigned":               "assigned",
+	"unassigned":             "unassigned",
+	"locked":                 "locked",
+	"unlocked":               "unlocked",
+	"review_requested":       "review_requested",
+	"review_request_removed": "review_request_removed",
+	"ready_for_review":       "ready_for_review",
+	"converted_to_draft":     "convert_to_draft",
+}
+
+func (w *eventWriter) handle(event interface{}, extra eventPayload) error {
+	switch e := event.(type) {
+	case *github.PingEvent:
+		c.Printf("<green>ping</> hook <white>%d</>: %s\n", e.GetHookID(), e.GetZen())
+		return nil
+	case *github.PullRequestEvent:
+		return w.pullRequest(e, extra)
+	case *github.PullRequestReviewEvent:
+		return w.pullRequestReview(e)
+	case *github.IssuesEvent:
+		return w.issues(e, extra)
+	case *github.IssueCommentEvent:
+		return w.issueComment(e)
+	case *github.LabelEvent:
+		return w.label(e, extra)
+	}
+
+	return nil
+}
+
+// repo returns the cache key for a delivery's repo and if its events should be stored
+func (w *eventWriter) repo(r *github.Repository) (string, bool) {
+	return w.accept(r.GetFullName())
+}
+
+// accept returns the cache key for an owner/name and if its events should be stored
+func (w *eventWriter) accept(ownerName string) (string, bool) {
+	key := w.host.RepoKey(ownerName)
+	if w.repos == nil {
+		return key, true
+	}
+
+	return key, w.repos[key]
+}
+
+func (w *eventWriter) pullRequest(e *github.PullRequestEvent, extra eventPayload) error {
+	repo, ok := w.repo(e.Repo)
+	if !ok {
+		return nil
+	}
+
+	pr := e.GetPullRequest()
+	n := pr.GetNumber()
+	c.Printf("<cyan>%s</> pr <yellow>#%d</> %s: %s\n", repo, n, e.GetAction(), pr.GetTitle())
+
+	if err := w.cache.UpsertRepoPRFromGH(repo, pr); err != nil {
+		return fmt.Errorf("cache upsert failed: %w", err)
+	}
+
+	// a merge is closed on the timeline too, after the merged event
+	if e.GetAction() == "closed" && pr.GetMerged() {
+		if err := w.event(repo, n, "merged", e.GetSender(), pr.GetMergedAt(), pr.GetHTMLURL(), eventDetails{Commit: pr.GetMergeCommitSHA()}); err != nil {
+			return err
+		}
+	}
+
+	name, ok := timelineActions[e.GetAction()]
+	if from := e.GetChanges().GetTitle().GetFrom(); e.GetAction() == "edited" && from != "" {
+		name, ok = "renamed", true
+	}
+	if ok {
+		at := pr.GetUpdatedAt()
+		if name == "closed" {
+			at = pr.GetClosedAt()
+		}
+
+		milestone := extra.Milestone
+		if milestone == nil {
+			milestone = pr.Milestone
+		}
+
+		d := eventDetails{
+			Label:      e.Label,
+			Milestone:  milestone,
+			Assignee:   e.Assignee,
+			Reviewer:   e.RequestedReviewer,
+			Team:       e.RequestedTeam,
+			RenameFrom: e.GetChanges().GetTitle().GetFrom(),
+			RenameTo:   pr.GetTitle(),
+			LockReason: pr.GetActiveLockReason(),
+		}
+		if err := w.event(repo, n, name, e.GetSender(), at, pr.GetHTMLURL(), d); err != nil {
+			return err
+		}
+	}
+
+	return w.computePRStats(repo, n)
+}
+
+func (w *eventWriter) pullRequestReview(e *github.PullRequestReviewEvent) error {
+	repo, ok := w.repo(e.Repo)
+	if !ok {
+		return nil
+	}
+
+	// the pull request of a review delivery is missing its merge & size details so it isn't upserted
+	review := e.GetReview()
+	n := e.GetPullRequest().GetNumber()
+	c.Printf("<cyan>%s</> pr <yellow>#%d</> review %s: %s (%s)\n", repo, n, e.GetAction(), review.GetUser().GetLogin(), strings.ToLower(review.GetState()))
+
+	if err := w.cache.UpsertReviewFromGH(repo, n, review); err != nil {
+		return fmt.Errorf("cache upsert failed: %w", err)
+	}
+
+	if e.GetAction() == "submitted" {
+		event, state, submitted, url := "reviewed", strings.ToLower(review.GetState()), review.GetSubmittedAt(), review.GetHTMLURL()
+		// the timeline's reviewed & commented events have the node id of the review or comment, other actions have
+		// none in a delivery so are stored under a made up one until a fetch finds them (see AuditEvents)
+		t := gh.TimelineEvent{Timeline: github.Timeline{
+			Event:       &event,
+			User:        review.User,
+			State:       &state,
+			Body:        review.Body,
+			SubmittedAt: &submitted,
+			URL:         &url,
+		}, NodeID: review.NodeID}
+		if err := w.cache.UpsertEvent(repo, n, &t); err != nil {
+			return fmt.Errorf("cache upsert failed: %w", err)
+		}
+	}
+
+	return w.computePRStats(repo, n)
+}
+
+func (w *eventWriter) issues(e *github.IssuesEvent, extra eventPayload) error {
+	repo, ok := w.repo(e.Repo)
+	if !ok {
+		return nil
+	}
+
+	issue := e.GetIssue()
+	n := issue.GetNumber()
+	c.Printf("<cyan>%s</> issue <yellow>#%d</> %s: %s\n", repo, n, e.GetAction(), issue.GetTitle())
+
+	switch e.GetAction() {
+	case "deleted", "transferred":
+		_, err := w.cache.MarkRemoved(repo, cachepkg.SyncKindIssues, n)
+		return err
+	}
+
+	if err := w.cache.UpsertRepoIssueFromGH(repo, issue); err != nil {
+		return fmt.Errorf("cache upsert failed: %w", err)
+	}
+
+	name, ok := timelineActions[e.GetAction()]
+	if from := e.GetChanges().GetTitle().GetFrom(); e.GetAction() == "edited" && from != "" {
+		name, ok = "renamed", true
+	}
+	if ok {
+		at := issue.GetUpdatedAt()
+		if name == "closed" {
+			at = issue.GetClosedAt()
+		}
+
+		milestone := extra.Milestone
+		if milestone == nil {
+			milestone = issue.Milestone
+		}
+
+		d := eventDetails{
+			Label:      e.Label,
+			Milestone:  milestone,
+			Assignee:   e.Assignee,
+			RenameFrom: e.GetChanges().GetTitle().GetFrom(),
+			RenameTo:   issue.GetTitle(),
+			LockReason: issue.GetActiveLockReason(),
+		}
+		return w.event(repo, n, name, e.GetSender(), at, issue.GetHTMLURL(), d)
+	}
+
+	// TODO compute stats for issues
+	return nil
+}
+
+func (w *eventWriter) issueComment(e *github.IssueCommentEvent) error {
+	repo, ok := w.repo(e.Repo)
+	if !ok {
+		return nil
+	}
+
+	issue, comment := e.GetIssue(), e.GetComment()
+	n := issue.GetNumber()
+	kind := "issue"
+	if issue.IsPullRequest() {
+		kind = "pr"
+	}
+	c.Printf("<cyan>%s</> %s <yellow>#%d</> comment %s: %s\n", repo, kind, n, e.GetAction(), comment.GetUser().GetLogin())
+
+	if e.GetAction() == "deleted" {
+		if err := w.cache.DeleteComment(repo, cachepkg.CommentKindIssue, comment.GetID()); err != nil {
+			return err
+		}
+	} else {
+		if err := w.cache.UpsertIssueCommentFromGH(repo, n, comment); err != nil {
+			return fmt.Errorf("cache upsert failed: %w", err)
+		}
+	}
+
+	if e.GetAction() == "created" {
+		event, created, url := "commented", comment.GetCreatedAt(), comment.GetHTMLURL()
+		t := gh.TimelineEvent{Timeline: github.Timeline{
+			Event:     &event,
+			User:      comment.User,
+			Body:      comment.Body,
+			CreatedAt: &created,
+			URL:       &url,
+		}, NodeID: comment.NodeID}
+		if err := w.cache.UpsertEvent(repo, n, &t); err != nil {
+			return fmt.Errorf("cache upsert failed: %w", err)
+		}
+	}
+
+	if !issue.IsPullRequest() {
+		if err := w.cache.UpsertRepoIssueFromGH(repo, issue); err != nil {
+			return fmt.Errorf("cache upsert failed: %w", err)
+		}
+		return nil
+	}
+
+	return w.computePRStats(repo, n)
+}
+
+// label only acts on renames, past events keep the label they had so stats looking for a label by name still find them
+func (w *eventWriter) label(e *github.LabelEvent, extra eventPayload) error {
+	repo, ok := w.repo(e.Repo)
+	if !ok {
+		return nil
+	}
+
+	from, to := extra.Changes.Name.From, e.GetLabel().GetName()
+	c.Printf("<cyan>%s</> label %s: %s\n", repo, e.GetAction(), to)
+	if e.GetAction() != "edited" || from == nil || *from == to {
+		return nil
+	}
+
+	numbers, err := w.cache.RenameLabel(repo, *from, to)
+	if err != nil {
+		return err
+	}
+	c.Printf("  renamed <white>%s</> -> <white>%s</> on %d prs & issues\n", *from, to, len(numbers))
+
+	for _, n := range numbers {
+		if err := w.computePRStats(repo, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// eventDetails is everything a delivery says about an action, only what the timeline event has is kept
+type eventDetails struct {
+	Label      *github.Label
+	Milestone  *github.Milestone
+	Assignee   *github.User
+	Reviewer   *github.User
+	Team       *github.Team
+	RenameFrom string
+	RenameTo   string
+	LockReason string
+	Commit     string
+}
+
+// event stores an action as the timeline event a fetch would have found for it
+func (w *eventWriter) event(repo string, number int, name string, actor *github.User, at time.Time, url string, d eventDetails) error {
+	t := gh.TimelineEvent{Timeline: github.Timeline{
+		Event:     &name,
+		Actor:     actor,
+		CreatedAt: &at,
+		URL:       &url,
+	}}
+
+	switch name {
+	case "labeled", "unlabeled":
+		t.Label = d.Label
+	case "milestoned", "demilestoned":
+		t.Milestone = d.Milestone
+	case "assigned", "unassigned":
+		t.Assignee = d.Assignee
+	case "review_requested", "review_request_removed":
+		t.Reviewer, t.RequestedTeam = d.Reviewer, d.Team
+	case "renamed":
+		t.Rename = &github.Rename{From: &d.RenameFrom, To: &d.RenameTo}
+	case "locked":
+		if d.LockReason != "" {
+			t.LockReason = &d.LockReason
+		}
+	case "merged":
+		if d.Commit != "" {
+			t.CommitID = &d.Commit
+		}
+	}
+
+	if err := w.cache.UpsertEvent(repo, number, &t); err != nil {
+		return fmt.Errorf("cache upsert failed: %w", err)
+	}
+
+	return nil
+}
+
+// computePRStats recomputes a pr's stats now its events have changed, issues (and prs not yet fetched) are skipped
+func (w *eventWriter) computePRStats(repo string, number int) error {
+	if pr, err := w.cache.GetPR(repo, number); err != nil {
+		return err
+	} else if pr == nil {
+		return nil
+	}
+
+	daysOpen, daysWaiting, daysToFirst, err := w.cache.ComputeAndUpdatePRStats(repo, number)
+	if err != nil {
+		return fmt.Errorf("falied to compute and update stats: %w", err)
+	}
+	c.Printf("   <darkGray>days</> open: <green>%.2f</> waiting: <green>%.2f</> first: <green>%.2f</> \n", *daysOpen, *daysWaiting, *daysToFirst)
+
+	return nil
+}
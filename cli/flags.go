@@ -2,36 +2,152 @@ package cli
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
+	"github.com/katbyte/gogo-repo-stats/lib/cache"
+	"github.com/katbyte/gogo-repo-stats/lib/gh"
+	"github.com/katbyte/gogo-repo-stats/lib/pointer"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 type FlagData struct {
-	Token     string
-	Repos     []string
-	Authors   []string
-	CachePath string
-	// FullFetch bool todo
+	Token       string
+	TokenFile   string
+	Repos       []string
+	Org         string
+	Include     []string
+	Exclude     []string
+	Topics      []string
+	Archived    bool
+	Forks       bool
+	Authors     []string
+	CachePath   string
+	FullFetch   bool
+	Resume      bool
+	Concurrency int
+	Backend     string
+	APIURL      string
+	WebURL      string
+	CABundle    string
+	Proxy       string
+
+	AppID          string
+	AppPrivateKey  string
+	InstallationID string
+
+	Record string
+	Replay string
+
+	Listen        string
+	WebhookSecret string
+
+	GitLabURL   string
+	GitLabToken string
+
+	Project      int
+	ProjectOwner string
+	ProjectField string
+
+	ExcludeAuthors []string
+	Labels         []string
+	Milestones     []string
+	States         []string
+	NoDrafts       bool
+	NoBots         bool
+	IncludeRemoved bool
 }
 
 func configureFlags(root *cobra.Command) error {
 	flags := FlagData{}
 	pflags := root.PersistentFlags()
 
-	pflags.StringVarP(&flags.Token, "token", "t", "", "github oauth token (GITHUB_TOKEN)")
+	pflags.StringVarP(&flags.Token, "token", "t", "", "github oauth token (GITHUB_TOKEN), several can be given comma separated and requests are spread over them")
+	pflags.StringVar(&flags.TokenFile, "token-file", "", "file of github tokens one per line to spread requests over, # starts a comment")
 	pflags.StringSliceVarP(&flags.Repos, "repos", "r", nil, "repos to fetch data for in the format owner/repo. ie 'katbyte/tctest,katbyte/terrafmt'")
+	pflags.StringVar(&flags.Org, "org", "", "discover and use every repo of this org or user, can be combined with --repos")
+	pflags.StringSliceVar(&flags.Include, "include", nil, "only use discovered repos matching these globs or 're:' prefixed regexes. ie 'terraform-provider-*,re:^go-'")
+	pflags.StringSliceVar(&flags.Exclude, "exclude", nil, "skip discovered repos matching these globs or 're:' prefixed regexes")
+	pflags.StringSliceVar(&flags.Topics, "topics", nil, "only use discovered repos with at least one of these topics")
+	pflags.BoolVar(&flags.Archived, "archived", false, "include archived repos when discovering")
+	pflags.BoolVar(&flags.Forks, "forks", false, "include forks when discovering")
 	pflags.StringSliceVarP(&flags.Authors, "authors", "a", nil, "only sync prs by these authors. ie 'katbyte,author2,author3'")
 	pflags.StringVarP(&flags.CachePath, "cache", "c", "", "path to sqllite3 db to use as cache")
-	// pflags.BoolVarP(&flags.FullFetch, "full", "f", false, "do a full fetch and not abort")
+	pflags.IntVarP(&flags.Concurrency, "concurrency", "j", 4, "number of prs/issues to fetch from github at once")
+	pflags.StringVar(&flags.Backend, "backend", "rest", "github api to fetch with: rest (a request per item), graphql (a page of items and their timelines per request) or provider (an item at a time like gitlab repos, without checks, reviews or releases)")
+	pflags.BoolVarP(&flags.FullFetch, "full", "f", false, "re-download every pr, issue and timeline ignoring the cache and reconcile items github no longer returns")
+	pflags.BoolVar(&flags.Resume, "resume", false, "continue an interrupted fetch after the last page that was completely written to the cache")
+	pflags.StringVar(&flags.APIURL, "api-url", "", "github enterprise server api url. ie 'https://ghes.example.com/api/v3/', defaults to api.github.com or worked out from --web-url")
+	pflags.StringVar(&flags.WebURL, "web-url", "", "github enterprise server url used for links. ie 'https://ghes.example.com', defaults to github.com or worked out from --api-url")
+	pflags.StringVar(&flags.CABundle, "ca-bundle", "", "path to a pem file of extra certificate authorities to trust when talking to github")
+	pflags.StringVar(&flags.Proxy, "proxy", "", "http proxy url to talk to github through, defaults to HTTPS_PROXY/HTTP_PROXY")
+	pflags.StringVar(&flags.AppID, "app-id", "", "authenticate as this github app instead of with a token")
+	pflags.StringVar(&flags.AppPrivateKey, "app-private-key", "", "the github app's private key, either the PEM or a path to it")
+	pflags.StringVar(&flags.Record, "record", "", "record every github api exchange to this cassette directory, with tokens redacted")
+	pflags.StringVar(&flags.Replay, "replay", "", "serve github api exchanges from this cassette directory instead of the network")
+	pflags.StringVar(&flags.Listen, "listen", ":8080", "address the webhook command listens on")
+	pflags.StringVar(&flags.WebhookSecret, "webhook-secret", "", "shared secret github signs webhook deliveries with")
+	pflags.StringVar(&flags.GitLabURL, "gitlab-url", "https://gitlab.com", "gitlab instance to fetch gitlab:group/project repos from")
+	pflags.StringVar(&flags.GitLabToken, "gitlab-token", "", "gitlab access token with read_api to fetch gitlab:group/project repos with")
+	pflags.IntVar(&flags.Project, "project", 0, "number of a projects (v2) board to fetch the items of and report on")
+	pflags.StringVar(&flags.ProjectOwner, "project-owner", "", "org or user the project belongs to, defaults to --org or the owner of the first repo")
+	pflags.StringVar(&flags.ProjectField, "project-field", "Status", "single select field of the project whose values are the board's columns")
+	pflags.StringSliceVar(&flags.ExcludeAuthors, "exclude-authors", nil, "leave prs & issues by these authors out of reports & graphs")
+	pflags.StringSliceVar(&flags.Labels, "labels", nil, "only report on prs & issues with at least one of these labels")
+	pflags.StringSliceVar(&flags.Milestones, "milestones", nil, "only report on prs & issues in one of these milestones")
+	pflags.StringSliceVar(&flags.States, "states", nil, "only report on prs & issues in one of these states, open or closed")
+	pflags.BoolVar(&flags.NoDrafts, "no-drafts", false, "leave draft prs out of reports & graphs")
+	pflags.BoolVar(&flags.NoBots, "no-bots", false, "leave prs & issues opened by bots out of reports & graphs")
+	pflags.BoolVar(&flags.IncludeRemoved, "include-removed", false, "report on prs & issues a full fetch found github no longer has (deleted, transferred or converted to discussions)")
+	pflags.StringVar(&flags.InstallationID, "installation-id", "", "github app installation to use for every repo, defaults to looking up the app's installation on each org/user")
 
 	// binding map for viper/pflag -> env
 	m := map[string]string{
-		"token":   "GITHUB_TOKEN",
-		"repos":   "GITHUB_REPOS",
-		"authors": "GITHUB_AUTHORS",
-		"cache":   "CACHE_DB_FILE",
+		"token":       "GITHUB_TOKEN",
+		"token-file":  "GITHUB_TOKEN_FILE",
+		"repos":       "GITHUB_REPOS",
+		"org":         "GITHUB_ORG",
+		"include":     "GITHUB_REPOS_INCLUDE",
+		"exclude":     "GITHUB_REPOS_EXCLUDE",
+		"topics":      "GITHUB_REPOS_TOPICS",
+		"archived":    "",
+		"forks":       "",
+		"authors":     "GITHUB_AUTHORS",
+		"cache":       "CACHE_DB_FILE",
+		"full":        "",
+		"resume":      "",
+		"concurrency": "FETCH_CONCURRENCY",
+		"backend":     "FETCH_BACKEND",
+		"api-url":     "GITHUB_API_URL",
+		"web-url":     "GITHUB_SERVER_URL",
+		"ca-bundle":   "GITHUB_CA_BUNDLE",
+		"proxy":       "GITHUB_PROXY",
+
+		"app-id":          "GITHUB_APP_ID",
+		"app-private-key": "GITHUB_APP_PRIVATE_KEY",
+		"installation-id": "GITHUB_APP_INSTALLATION_ID",
+
+		"record": "",
+		"replay": "",
+
+		"listen":         "WEBHOOK_LISTEN",
+		"webhook-secret": "GITHUB_WEBHOOK_SECRET",
+
+		"gitlab-url":   "GITLAB_URL",
+		"gitlab-token": "GITLAB_TOKEN",
+
+		"project":       "GITHUB_PROJECT_NUMBER",
+		"project-owner": "GITHUB_PROJECT_OWNER",
+		"project-field": "GITHUB_PROJECT_FIELD",
+
+		"exclude-authors": "GITHUB_EXCLUDE_AUTHORS",
+		"labels":          "GITHUB_LABELS",
+		"milestones":      "GITHUB_MILESTONES",
+		"states":          "",
+		"no-drafts":       "",
+		"no-bots":         "",
+		"include-removed": "",
 	}
 
 	for name, env := range m {
@@ -68,10 +184,129 @@ func GetFlags() FlagData {
 	}
 
 	// there has to be an easier way....
+	include, exclude, topics := viper.GetStringSlice("include"), viper.GetStringSlice("exclude"), viper.GetStringSlice("topics")
+	if len(include) != 0 {
+		include = strings.Split(include[0], ",")
+	}
+	if len(exclude) != 0 {
+		exclude = strings.Split(exclude[0], ",")
+	}
+	if len(topics) != 0 {
+		topics = strings.Split(topics[0], ",")
+	}
+
+	split := func(name string) []string {
+		v := viper.GetStringSlice(name)
+		if len(v) != 0 {
+			v = strings.Split(v[0], ",")
+		}
+		return v
+	}
+
 	return FlagData{
-		Token:     viper.GetString("token"),
-		Repos:     repos,
-		Authors:   authors,
-		CachePath: viper.GetString("cache"),
+		Token:       viper.GetString("token"),
+		TokenFile:   viper.GetString("token-file"),
+		Repos:       repos,
+		Org:         owner,
+		Include:     include,
+		Exclude:     exclude,
+		Topics:      topics,
+		Archived:    viper.GetBool("archived"),
+		Forks:       viper.GetBool("forks"),
+		Authors:     authors,
+		CachePath:   viper.GetString("cache"),
+		FullFetch:   viper.GetBool("full"),
+		Resume:      viper.GetBool("resume"),
+		Concurrency: viper.GetInt("concurrency"),
+		Backend:     viper.GetString("backend"),
+		APIURL:      viper.GetString("api-url"),
+		WebURL:      viper.GetString("web-url"),
+		CABundle:    viper.GetString("ca-bundle"),
+		Proxy:       viper.GetString("proxy"),
+
+		AppID:          viper.GetString("app-id"),
+		AppPrivateKey:  viper.GetString("app-private-key"),
+		InstallationID: viper.GetString("installation-id"),
+
+		Record: viper.GetString("record"),
+		Replay: viper.GetString("replay"),
+
+		Listen:        viper.GetString("listen"),
+		WebhookSecret: viper.GetString("webhook-secret"),
+
+		GitLabURL:   viper.GetString("gitlab-url"),
+		GitLabToken: viper.GetString("gitlab-token"),
+
+		Project:      viper.GetInt("project"),
+		ProjectOwner: viper.GetString("project-owner"),
+		ProjectField: viper.GetString("project-field"),
+
+		ExcludeAuthors: split("exclude-authors"),
+		Labels:         split("labels"),
+		Milestones:     split("milestones"),
+		States:         split("states"),
+		NoDrafts:       viper.GetBool("no-drafts"),
+		NoBots:         viper.GetBool("no-bots"),
+		IncludeRemoved: viper.GetBool("include-removed"),
+	}
+}
+
+// Filter is what the report & graphs are narrowed to for repos.
+func (f FlagData) Filter(repos []string) cache.Filter {
+	filter := cache.Filter{
+		Repos:          repos,
+		Authors:        f.Authors,
+		ExcludeAuthors: f.ExcludeAuthors,
+		Labels:         f.Labels,
+		Milestones:     f.Milestones,
+		States:         f.States,
+		Removed:        f.IncludeRemoved,
+	}
+
+	if f.NoDrafts {
+		filter.Drafts = pointer.To(false)
 	}
+	if f.NoBots {
+		filter.Bots = pointer.To(false)
+	}
+
+	return filter
+}
+
+// Host is the github instance the flags point at.
+func (f FlagData) Host() gh.Host {
+	return gh.Host{
+		APIURL:   f.APIURL,
+		WebURL:   f.WebURL,
+		CABundle: f.CABundle,
+		Proxy:    f.Proxy,
+	}
+}
+
+// Tokens returns every token given with --token and --token-file.
+func (f FlagData) Tokens() ([]string, error) {
+	var tokens []string
+	for _, t := range strings.Split(f.Token, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tokens = append(tokens, t)
+		}
+	}
+
+	if f.TokenFile != "" {
+		data, err := os.ReadFile(f.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading token file %s: %w", f.TokenFile, err)
+		}
+
+		for _, l := range strings.Split(string(data), "\n") {
+			if i := strings.Index(l, "#"); i >= 0 {
+				l = l[:i]
+			}
+			if l = strings.TrimSpace(l); l != "" {
+				tokens = append(tokens, l)
+			}
+		}
+	}
+
+	return tokens, nil
 }
@@ -0,0 +1,200 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	c "github.com/gookit/color" // nolint:misspell
+	"github.com/katbyte/gogo-repo-stats/lib/cache"
+	"github.com/katbyte/gogo-repo-stats/lib/gh"
+	"github.com/katbyte/gogo-repo-stats/lib/provider"
+)
+
+// repos can either be listed by hand with --repos or discovered for a whole org/user with --org. discovery lists
+// the org through the API and stores every repo in the cache, the filters are then applied to what is stored so
+// report & graphs see exactly what fetch did without talking to github
+
+// repoPattern matches repo names with a glob (terraform-provider-*) or, prefixed with re:, a regex
+type repoPattern struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+func newRepoPattern(p string) (repoPattern, error) {
+	if strings.HasPrefix(p, "re:") {
+		re, err := regexp.Compile(strings.TrimPrefix(p, "re:"))
+		if err != nil {
+			return repoPattern{}, fmt.Errorf("invalid repo regex %q: %w", p, err)
+		}
+		return repoPattern{re: re}, nil
+	}
+
+	if _, err := path.Match(p, ""); err != nil {
+		return repoPattern{}, fmt.Errorf("invalid repo glob %q: %w", p, err)
+	}
+	return repoPattern{glob: p}, nil
+}
+
+func (p repoPattern) matches(name string) bool {
+	if p.re != nil {
+		return p.re.MatchString(name)
+	}
+
+	ok, _ := path.Match(p.glob, name)
+	return ok
+}
+
+type repoFilter struct {
+	Include  []repoPattern
+	Exclude  []repoPattern
+	Topics   []string
+	Archived bool
+	Forks    bool
+}
+
+func newRepoFilter(f FlagData) (*repoFilter, error) {
+	rf := repoFilter{
+		Topics:   f.Topics,
+		Archived: f.Archived,
+		Forks:    f.Forks,
+	}
+
+	for _, p := range f.Include {
+		rp, err := newRepoPattern(p)
+		if err != nil {
+			return nil, err
+		}
+		rf.Include = append(rf.Include, rp)
+	}
+
+	for _, p := range f.Exclude {
+		rp, err := newRepoPattern(p)
+		if err != nil {
+			return nil, err
+		}
+		rf.Exclude = append(rf.Exclude, rp)
+	}
+
+	return &rf, nil
+}
+
+// matches returns true if the repo is included by any include pattern (or there are none), excluded by none of
+// the exclude patterns and has at least one of the topics (or there are none).
+func (f repoFilter) matches(r cache.Repo) bool {
+	if r.Archived && !f.Archived {
+		return false
+	}
+	if r.Fork && !f.Forks {
+		return false
+	}
+
+	included := len(f.Include) == 0
+	for _, p := range f.Include {
+		if p.matches(r.Name) {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+
+	for _, p := range f.Exclude {
+		if p.matches(r.Name) {
+			return false
+		}
+	}
+
+	if len(f.Topics) == 0 {
+		return true
+	}
+	for _, want := range f.Topics {
+		for _, t := range r.Topics {
+			if strings.EqualFold(want, t) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// resolveRepos returns the repos to work on: those given with --repos plus, with --org, every repo of the org that
+// passes the filters. with auth the org is discovered from github and the cache updated first, otherwise the repos
+// discovered by the last fetch are used.
+func resolveRepos(f FlagData, theCache *cache.Cache, auth *ghAuth) ([]string, error) {
+	host := f.Host()
+
+	// repos of an enterprise server are keyed by host in the cache, ones given as host/owner/name already are as are
+	// those of other providers
+	given := make([]string, 0, len(f.Repos))
+	for _, r := range f.Repos {
+		if kind, _ := provider.Split(r); kind == provider.GitHub && strings.Count(r, "/") == 1 {
+			r = host.RepoKey(r)
+		}
+		given = append(given, r)
+	}
+
+	if f.Org == "" {
+		return given, nil
+	}
+
+	filter, err := newRepoFilter(f)
+	if err != nil {
+		return nil, err
+	}
+
+	if auth != nil {
+		c.Printf("Discovering repos for <white>%s</>...\n", f.Org)
+		o := gh.NewOwner(f.Org, "")
+		o.Host = host
+		auth.apply(&o.Token, f.Org)
+
+		found, err := o.GetAllRepos()
+		if err != nil {
+			return nil, fmt.Errorf("discovering repos for %s: %w", f.Org, err)
+		}
+
+		if err = theCache.ReplaceOwnerReposFromGH(host.Name(), f.Org, *found); err != nil {
+			return nil, fmt.Errorf("caching repos for %s: %w", f.Org, err)
+		}
+	}
+
+	known, err := theCache.GetOwnerRepos(host.Name(), f.Org)
+	if err != nil {
+		return nil, err
+	}
+	if len(known) == 0 {
+		return nil, fmt.Errorf("no repos have been discovered for %s, run fetch with --org first", f.Org)
+	}
+
+	seen := map[string]bool{}
+	repos := make([]string, 0, len(known)+len(given))
+	for _, r := range given {
+		if !seen[r] {
+			seen[r] = true
+			repos = append(repos, r)
+		}
+	}
+
+	matched := 0
+	for _, r := range known {
+		if !filter.matches(r) {
+			continue
+		}
+
+		matched++
+		if name := r.FullName(); !seen[name] {
+			seen[name] = true
+			repos = append(repos, name)
+		}
+	}
+	sort.Strings(repos)
+
+	c.Printf("  <white>%d</> of <white>%d</> repos in <white>%s</> match\n", matched, len(known), f.Org)
+
+	return repos, nil
+}
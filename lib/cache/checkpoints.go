@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// FetchCheckpoint is the last listing page of a repo & kind whose items have all been written to the cache.
+type FetchCheckpoint struct {
+	Repo   string
+	Kind   string
+	Page   int
+	Cursor sql.NullString // graphql pages by cursor rather than number
+	Last   int            // number of the last item on the page
+	Newest time.Time      // most recent updated_at seen so far, becomes the watermark when the fetch completes
+}
+
+func (cache Cache) GetFetchCheckpoint(repo, kind string) (*FetchCheckpoint, error) {
+	cp := FetchCheckpoint{}
+
+	row := cache.DB.QueryRow(`SELECT repo, kind, page, cursor, last, newest FROM fetch_checkpoints WHERE repo = ? AND kind = ?`, repo, kind)
+	if err := row.Scan(&cp.Repo, &cp.Kind, &cp.Page, &cp.Cursor, &cp.Last, &cp.Newest); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get fetch checkpoint for %s/%s: %w", repo, kind, err)
+	}
+
+	return &cp, nil
+}
+
+func (cache Cache) UpsertFetchCheckpoint(cp FetchCheckpoint) error {
+	stmt, err := cache.DB.Prepare(`
+		INSERT OR REPLACE INTO fetch_checkpoints (repo, kind, page, cursor, last, newest, updated)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement for fetch checkpoint %s/%s: %w", cp.Repo, cp.Kind, err)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.Exec(cp.Repo, cp.Kind, cp.Page, cp.Cursor, cp.Last, cp.Newest.UTC(), time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to insert fetch checkpoint %s/%s: %w", cp.Repo, cp.Kind, err)
+	}
+
+	return nil
+}
+
+// ClearFetchCheckpoints removes all checkpoints for a repo once its fetch has completed.
+func (cache Cache) ClearFetchCheckpoints(repo string) error {
+	if _, err := cache.DB.Exec(`DELETE FROM fetch_checkpoints WHERE repo = ?`, repo); err != nil {
+		return fmt.Errorf("failed to clear fetch checkpoints for %s: %w", repo, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,319 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// check runs and commit statuses are both stored as checks. a check run re-run on the same commit shows up as a new
+// run with the same name, so each is numbered with the attempt it was for that name & commit
+
+const (
+	CheckKindRun    = "check_run"
+	CheckKindStatus = "status"
+)
+
+type Check struct {
+	Repo       string
+	PR         int
+	SHA        string
+	Kind       string
+	ID         int64
+	Name       string
+	Status     string // queued, in_progress, completed or pending for a status that never finished
+	Conclusion string // success, failure, neutral, cancelled, skipped, timed_out, action_required or error
+	Started    sql.NullTime
+	Completed  sql.NullTime
+	Attempt    int
+}
+
+func (c Check) Passed() bool {
+	return c.Status == "completed" && (c.Conclusion == "success" || c.Conclusion == "neutral" || c.Conclusion == "skipped")
+}
+
+func (c Check) Failed() bool {
+	return c.Status == "completed" && (c.Conclusion == "failure" || c.Conclusion == "timed_out" || c.Conclusion == "error")
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil || t.IsZero() {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+// ChecksFromGH converts the check runs and statuses of a commit into checks.
+func ChecksFromGH(repo string, pr int, sha string, runs []github.CheckRun, statuses []github.RepoStatus) []Check {
+	var checks []Check
+
+	for _, r := range runs {
+		ch := Check{
+			Repo:       repo,
+			PR:         pr,
+			SHA:        sha,
+			Kind:       CheckKindRun,
+			ID:         r.GetID(),
+			Name:       r.GetName(),
+			Status:     r.GetStatus(),
+			Conclusion: r.GetConclusion(),
+		}
+		if r.StartedAt != nil {
+			ch.Started = nullTime(&r.StartedAt.Time)
+		}
+		if r.CompletedAt != nil {
+			ch.Completed = nullTime(&r.CompletedAt.Time)
+		}
+		checks = append(checks, ch)
+	}
+
+	// every change of a status is its own entry, a pending followed by its result becomes a single check
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].GetCreatedAt().Before(statuses[j].GetCreatedAt())
+	})
+	pending := map[string]*time.Time{}
+	for _, s := range statuses {
+		name := s.GetContext()
+		if s.GetState() == "pending" {
+			if pending[name] == nil {
+				pending[name] = s.CreatedAt
+			}
+			continue
+		}
+
+		started := pending[name]
+		if started == nil {
+			started = s.CreatedAt
+		}
+		delete(pending, name)
+
+		checks = append(checks, Check{
+			Repo:       repo,
+			PR:         pr,
+			SHA:        sha,
+			Kind:       CheckKindStatus,
+			ID:         s.GetID(),
+			Name:       name,
+			Status:     "completed",
+			Conclusion: s.GetState(),
+			Started:    nullTime(started),
+			Completed:  nullTime(s.CreatedAt),
+		})
+	}
+	for _, s := range statuses {
+		if s.GetState() == "pending" && pending[s.GetContext()] != nil && pending[s.GetContext()].Equal(s.GetCreatedAt()) {
+			delete(pending, s.GetContext())
+			checks = append(checks, Check{
+				Repo:    repo,
+				PR:      pr,
+				SHA:     sha,
+				Kind:    CheckKindStatus,
+				ID:      s.GetID(),
+				Name:    s.GetContext(),
+				Status:  "pending",
+				Started: nullTime(s.CreatedAt),
+			})
+		}
+	}
+
+	numberAttempts(checks)
+	return checks
+}
+
+// CompletedSHAs returns the commits every check of which had completed, nothing more will change on them short of a
+// re-run.
+func CompletedSHAs(checks []Check) map[string]bool {
+	completed := map[string]bool{}
+	for _, ch := range checks {
+		if done, ok := completed[ch.SHA]; !ok || done {
+			completed[ch.SHA] = ch.Status == "completed"
+		}
+	}
+
+	for sha, done := range completed {
+		if !done {
+			delete(completed, sha)
+		}
+	}
+
+	return completed
+}
+
+// numberAttempts numbers the checks with the same commit & name in the order they started.
+func numberAttempts(checks []Check) {
+	sort.SliceStable(checks, func(i, j int) bool {
+		return checks[i].Started.Time.Before(checks[j].Started.Time)
+	})
+
+	attempts := map[string]int{}
+	for i := range checks {
+		k := checks[i].SHA + "/" + checks[i].Name
+		attempts[k]++
+		checks[i].Attempt = attempts[k]
+	}
+}
+
+// ReplacePRChecks replaces all checks stored for a PR.
+func (cache Cache) ReplacePRChecks(repo string, pr int, checks []Check) error {
+	tx, err := cache.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for checks %s#%d: %w", repo, pr, err)
+	}
+	defer tx.Rollback() // nolint:errcheck
+
+	if _, err = tx.Exec(`DELETE FROM checks WHERE repo = ? AND pr = ?`, repo, pr); err != nil {
+		return fmt.Errorf("failed to clear checks %s#%d: %w", repo, pr, err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT OR REPLACE INTO checks (repo, pr, sha, kind, id, name, status, conclusion, started, completed, attempt) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement for checks %s#%d: %w", repo, pr, err)
+	}
+	defer stmt.Close()
+
+	for _, ch := range checks {
+		_, err = stmt.Exec(repo, pr, ch.SHA, ch.Kind, ch.ID, ch.Name, ch.Status, ch.Conclusion, ch.Started, ch.Completed, ch.Attempt)
+		if err != nil {
+			return fmt.Errorf("failed to insert check %s#%d/%s: %w", repo, pr, ch.Name, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit checks %s#%d: %w", repo, pr, err)
+	}
+
+	return nil
+}
+
+// GetChecksFor returns all checks of a PR in the order they started.
+func (cache Cache) GetChecksFor(repo string, number int) ([]Check, error) {
+	rows, err := cache.DB.Query(`
+		SELECT repo, pr, sha, kind, id, name, status, conclusion, started, completed, attempt 
+		FROM checks 
+		WHERE
+			repo = ? AND
+			pr = ?
+		ORDER BY started
+	`, repo, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query checks for pr %d: %w", number, err)
+	}
+	defer rows.Close()
+
+	var checks []Check
+	for rows.Next() {
+		ch := Check{}
+		err = rows.Scan(&ch.Repo, &ch.PR, &ch.SHA, &ch.Kind, &ch.ID, &ch.Name, &ch.Status, &ch.Conclusion, &ch.Started, &ch.Completed, &ch.Attempt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan checks for pr %d: %w", number, err)
+		}
+
+		checks = append(checks, ch)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get checks for pr %d: %w", number, err)
+	}
+
+	return checks, nil
+}
+
+// ComputeAndUpdatePRCIStats works out how long a PR took to first go green, if it was merged with a failing check on
+// its head commit, and how many checks failed and then passed on a re-run of the same commit.
+func (cache Cache) ComputeAndUpdatePRCIStats(repo string, number int, head string) (daysToGreen *float64, mergedFailing bool, flaky int, err error) {
+	pr, err := cache.GetPR(repo, number)
+	if err != nil {
+		return nil, false, 0, fmt.Errorf("failed to get pr %d: %w", number, err)
+	}
+	if pr == nil {
+		return nil, false, 0, fmt.Errorf("pr %d is not cached", number)
+	}
+
+	checks, err := cache.GetChecksFor(repo, number)
+	if err != nil {
+		return nil, false, 0, err
+	}
+
+	// only the latest attempt of a check on a commit decides if the commit is green
+	latest := map[string]map[string]Check{}
+	failed := map[string]bool{}
+	flakes := map[string]bool{}
+	for _, ch := range checks {
+		k := ch.SHA + "/" + ch.Name
+		if ch.Failed() {
+			failed[k] = true
+		}
+		if ch.Passed() && failed[k] {
+			flakes[k] = true
+		}
+
+		if latest[ch.SHA] == nil {
+			latest[ch.SHA] = map[string]Check{}
+		}
+		if l, ok := latest[ch.SHA][ch.Name]; !ok || ch.Attempt > l.Attempt {
+			latest[ch.SHA][ch.Name] = ch
+		}
+	}
+	flaky = len(flakes)
+
+	var green *time.Time
+	for _, byName := range latest {
+		var done time.Time
+		for _, ch := range byName {
+			if !ch.Passed() {
+				done = time.Time{}
+				break
+			}
+			if ch.Completed.Time.After(done) {
+				done = ch.Completed.Time
+			}
+		}
+		if done.IsZero() {
+			continue
+		}
+		if green == nil || done.Before(*green) {
+			green = &done
+		}
+	}
+
+	if pr.Merged {
+		for _, ch := range latest[head] {
+			if ch.Failed() {
+				mergedFailing = true
+				break
+			}
+		}
+	}
+
+	var dtg sql.NullFloat64
+	if green != nil {
+		d := math.Floor(green.Sub(pr.Created).Hours()/24*100) / 100
+		if d < 0 {
+			d = 0
+		}
+		daysToGreen = &d
+		dtg = sql.NullFloat64{Float64: d, Valid: true}
+	}
+
+	_, err = cache.DB.Exec(`
+		UPDATE prs 
+		SET daystogreen = ?,
+		    mergedfailing = ?,
+		    flakychecks = ?
+		WHERE
+		    repo=? AND
+			number=?;
+	`, dtg, mergedFailing, flaky, repo, number)
+	if err != nil {
+		return nil, false, 0, fmt.Errorf("failed to update ci stats for pr %s#%d: %w", repo, number, err)
+	}
+
+	return daysToGreen, mergedFailing, flaky, nil
+}
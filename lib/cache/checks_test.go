@@ -0,0 +1,22 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompletedSHAs(t *testing.T) {
+	checks := []Check{
+		{SHA: "aaa", Name: "build", Status: "completed", Conclusion: "success"},
+		{SHA: "aaa", Name: "lint", Status: "completed", Conclusion: "failure"},
+		{SHA: "bbb", Name: "build", Status: "completed", Conclusion: "success"},
+		{SHA: "bbb", Name: "test", Status: "in_progress"},
+		{SHA: "ccc", Name: "ci/jenkins", Kind: CheckKindStatus, Status: "pending"},
+		{SHA: "ccc", Name: "build", Status: "completed", Conclusion: "success"},
+	}
+
+	expected := map[string]bool{"aaa": true}
+	if got := CompletedSHAs(checks); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
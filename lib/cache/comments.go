@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+const (
+	CommentKindIssue  = "issue"  // the conversation on an issue or pr
+	CommentKindReview = "review" // left on a line of a pr's diff
+)
+
+// MaintainerAssociations are the author associations of people who can act on an item.
+var MaintainerAssociations = []string{"OWNER", "MEMBER", "COLLABORATOR"}
+
+type Comment struct {
+	Repo        string
+	Kind        string
+	ID          int64
+	Item        int
+	User        string
+	Association string
+	Created     time.Time
+	Updated     sql.NullTime
+	BodyLength  int
+	InReplyTo   sql.NullInt64
+	Path        sql.NullString
+	Line        sql.NullInt64
+	URL         string
+}
+
+func (cache Cache) upsertComment(cm Comment) error {
+	stmt, err := cache.DB.Prepare(`
+		INSERT OR REPLACE INTO comments (repo, kind, id, item, user, association, created, updated, body_length, in_reply_to, path, line, url) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement for comment %d/%d: %w", cm.Item, cm.ID, err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(cm.Repo, cm.Kind, cm.ID, cm.Item, cm.User, cm.Association, cm.Created, cm.Updated, cm.BodyLength, cm.InReplyTo, cm.Path, cm.Line, cm.URL)
+	if err != nil {
+		return fmt.Errorf("failed to insert comment %s#%d/%d: %w", cm.Repo, cm.Item, cm.ID, err)
+	}
+
+	return nil
+}
+
+func (cache Cache) UpsertIssueCommentFromGH(repo string, number int, comment *github.IssueComment) error {
+	cm := Comment{
+		Repo:        repo,
+		Kind:        CommentKindIssue,
+		ID:          comment.GetID(),
+		Item:        number,
+		User:        comment.GetUser().GetLogin(),
+		Association: comment.GetAuthorAssociation(),
+		Created:     comment.GetCreatedAt(),
+		BodyLength:  len(comment.GetBody()),
+		URL:         comment.GetHTMLURL(),
+	}
+	if comment.UpdatedAt != nil {
+		cm.Updated = sql.NullTime{Time: *comment.UpdatedAt, Valid: true}
+	}
+
+	return cache.upsertComment(cm)
+}
+
+func (cache Cache) UpsertReviewCommentFromGH(repo string, number int, comment *github.PullRequestComment) error {
+	cm := Comment{
+		Repo:        repo,
+		Kind:        CommentKindReview,
+		ID:          comment.GetID(),
+		Item:        number,
+		User:        comment.GetUser().GetLogin(),
+		Association: comment.GetAuthorAssociation(),
+		Created:     comment.GetCreatedAt(),
+		BodyLength:  len(comment.GetBody()),
+		URL:         comment.GetHTMLURL(),
+	}
+	if comment.UpdatedAt != nil {
+		cm.Updated = sql.NullTime{Time: *comment.UpdatedAt, Valid: true}
+	}
+	if comment.InReplyTo != nil {
+		cm.InReplyTo = sql.NullInt64{Int64: *comment.InReplyTo, Valid: true}
+	}
+	if comment.Path != nil {
+		cm.Path = sql.NullString{String: *comment.Path, Valid: true}
+	}
+	if comment.Line != nil {
+		cm.Line = sql.NullInt64{Int64: int64(*comment.Line), Valid: true}
+	} else if comment.OriginalLine != nil {
+		cm.Line = sql.NullInt64{Int64: int64(*comment.OriginalLine), Valid: true}
+	}
+
+	return cache.upsertComment(cm)
+}
+
+func (cache Cache) queryComments(q string, args ...any) ([]Comment, error) {
+	rows, err := cache.DB.Query(q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comments: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []Comment
+	for rows.Next() {
+		cm := Comment{}
+		err = rows.Scan(
+			&cm.Repo,
+			&cm.Kind,
+			&cm.ID,
+			&cm.Item,
+			&cm.User,
+			&cm.Association,
+			&cm.Created,
+			&cm.Updated,
+			&cm.BodyLength,
+			&cm.InReplyTo,
+			&cm.Path,
+			&cm.Line,
+			&cm.URL,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+
+		comments = append(comments, cm)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+
+	return comments, nil
+}
+
+// GetCommentsFor returns all comments of both kinds on an issue or pr, oldest first.
+func (cache Cache) GetCommentsFor(repo string, number int) ([]Comment, error) {
+	comments, err := cache.queryComments(`
+		SELECT repo, kind, id, item, user, association, created, updated, body_length, in_reply_to, path, line, url 
+		FROM comments 
+		WHERE
+			repo = ? AND
+			item = ?
+		ORDER BY created
+	`, repo, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments for %s#%d: %w", repo, number, err)
+	}
+
+	return comments, nil
+}
+
+// GetFirstMaintainerCommentFor returns the earliest comment on an item by a maintainer other than the item's author,
+// nil if there has not been one. author is the login of whoever opened the issue or pr.
+func (cache Cache) GetFirstMaintainerCommentFor(repo string, number int, author string) (*Comment, error) {
+	comments, err := cache.queryComments(`
+		SELECT repo, kind, id, item, user, association, created, updated, body_length, in_reply_to, path, line, url 
+		FROM comments 
+		WHERE
+			repo = ? AND
+			item = ? AND
+			user != ? AND
+			association IN (?, ?, ?)
+		ORDER BY created
+		LIMIT 1
+	`, repo, number, author, MaintainerAssociations[0], MaintainerAssociations[1], MaintainerAssociations[2])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get first maintainer comment for %s#%d: %w", repo, number, err)
+	}
+
+	if len(comments) == 0 {
+		return nil, nil
+	}
+
+	return &comments[0], nil
+}
+
+// GetCommentCounts returns the number of comments of both kinds on every item of a repo that has any.
+func (cache Cache) GetCommentCounts(repo string) (map[int]int, error) {
+	rows, err := cache.DB.Query(`SELECT item, COUNT(*) FROM comments WHERE repo = ? GROUP BY item`, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comment counts for %s: %w", repo, err)
+	}
+	defer rows.Close()
+
+	counts := map[int]int{}
+	for rows.Next() {
+		var item, count int
+		if err = rows.Scan(&item, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan comment counts for %s: %w", repo, err)
+		}
+		counts[item] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get comment counts for %s: %w", repo, err)
+	}
+
+	return counts, nil
+}
+
+// DeleteComment removes a comment that was deleted on github.
+func (cache Cache) DeleteComment(repo, kind string, id int64) error {
+	if _, err := cache.DB.Exec(`DELETE FROM comments WHERE repo = ? AND kind = ? AND id = ?`, repo, kind, id); err != nil {
+		return fmt.Errorf("failed to delete %s comment %s/%d: %w", kind, repo, id, err)
+	}
+
+	return nil
+}
@@ -14,32 +14,57 @@ type Cache struct {
 }
 
 func Open(path string) (*Cache, error) {
+	cache, err := open(path, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// bring caches made by older versions up to date, a cache made by a newer version is refused
+	if _, err := cache.Migrate(); err != nil {
+		cache.DB.Close()
+		return nil, err
+	}
+
+	return cache, nil
+}
+
+// OpenUnmigrated opens an existing cache without migrating it, ie to see what migrating it would do.
+func OpenUnmigrated(path string) (*Cache, error) {
+	return open(path, false)
+}
+
+func open(path string, create bool) (*Cache, error) {
 	// exists?
 	if _, err := os.Stat(path); err == nil {
 		c.Printf("Opening <magenta>%s</>...\n", path)
-		db, err := sql.Open("sqlite3", path)
-		if err != nil {
-			return nil, fmt.Errorf("failed to open db %s: %w", path, err)
+	} else if create {
+		// create file, the tables are all created by migrating it
+		c.Printf("Creating <magenta>%s</>...\n", path)
+		if _, err := os.Create(path); err != nil {
+			return nil, fmt.Errorf("failed to create db %s: %w", path, err)
 		}
-
-		return &Cache{path, db}, nil
+	} else {
+		return nil, fmt.Errorf("no cache at %s: %w", path, err)
 	}
 
-	// create file
-	c.Printf("Creating <magenta>%s</>...\n", path)
-	_, err := os.Create(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create db %s: %w", path, err)
-	}
 	db, err := sql.Open("sqlite3", path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open db %s: %w", path, err)
 	}
 
+	// sqlite only allows a single writer, funnel everything through one connection so concurrent users queue rather than fail with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	return &Cache{path, db}, nil
+}
+
+// createInitialTables creates the prs, issues & events tables as they were before the schema was versioned, every
+// column added since is added by its own migration so new & old caches end up with the same schema.
+func (cache Cache) createInitialTables(tx *sql.Tx) error {
 	// id, title, user, email, state, milestone, merged, merger, merger_email, created, closed
 	c.Printf("  table <white>prs</>...\n")
-	_, err = db.Exec(`
-	CREATE TABLE "prs" (
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS "prs" (
 	    "repo" CHAR(64) NOT NULL, 
 	    "number" INTEGER, 
 	    "title" VARCHAR(256) NOT NULL, 
@@ -57,13 +82,13 @@ func Open(path string) (*Cache, error) {
 	)
 	`)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create PR table %s: %w", path, err)
+		return fmt.Errorf("failed to create PR table %s: %w", cache.Path, err)
 	}
 
 	// id, title, user, email, state, milestone, merged, merger, merger_email, created, closed
 	c.Printf("  table <white>issues</>...\n")
-	_, err = db.Exec(`
-	CREATE TABLE "issues" (
+	_, err = tx.Exec(`
+	CREATE TABLE IF NOT EXISTS "issues" (
 	    "repo" CHAR(64) NOT NULL, 
 	    "number" INTEGER, 
 	    "title" VARCHAR(256) NOT NULL, 
@@ -78,15 +103,15 @@ func Open(path string) (*Cache, error) {
 	)
 	`)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create PR table %s: %w", path, err)
+		return fmt.Errorf("failed to create PR table %s: %w", cache.Path, err)
 	}
 
 	// TODO change pr to number? pr_or_issue
 
 	// id, title, user, email, state, milestone, merged, merger, merger_email, created, closed
 	c.Printf("  table <white>events</>...\n")
-	_, err = db.Exec(`
-	CREATE TABLE "events" (
+	_, err = tx.Exec(`
+	CREATE TABLE IF NOT EXISTS "events" (
 	    "repo" CHAR(64) NOT NULL, 
 	    "pr" INTEGER,  
 	    "date" DATE NOT NULL,
@@ -103,8 +128,374 @@ func Open(path string) (*Cache, error) {
 	)
 	`)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create events table %s: %w", path, err)
+		return fmt.Errorf("failed to create events table %s: %w", cache.Path, err)
 	}
 
-	return &Cache{path, db}, nil
+	return nil
+}
+
+// createSyncTables creates the fetch bookkeeping tables.
+func (cache Cache) createSyncTables(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS "sync_state" (
+	    "repo" CHAR(64) NOT NULL, 
+	    "kind" CHAR(32) NOT NULL,
+	    "updated" DATE NOT NULL,
+	    PRIMARY KEY (repo, kind)
+	)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create sync_state table %s: %w", cache.Path, err)
+	}
+
+	// items github no longer returns (deleted, transferred, converted to a discussion) found by a full fetch
+	_, err = tx.Exec(`
+	CREATE TABLE IF NOT EXISTS "removed" (
+	    "repo" CHAR(64) NOT NULL, 
+	    "kind" CHAR(32) NOT NULL,
+	    "number" INTEGER NOT NULL,
+	    "detected" DATE NOT NULL,
+	    PRIMARY KEY (repo, kind, number)
+	)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create removed table %s: %w", cache.Path, err)
+	}
+
+	// validators & payloads of github GET responses so refetches can be conditional requests
+	_, err = tx.Exec(`
+	CREATE TABLE IF NOT EXISTS "http_cache" (
+	    "url" VARCHAR NOT NULL, 
+	    "etag" VARCHAR,
+	    "last_modified" VARCHAR,
+	    "header" VARCHAR,
+	    "body" BLOB,
+	    "updated" DATE NOT NULL,
+	    PRIMARY KEY (url)
+	)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create http_cache table %s: %w", cache.Path, err)
+	}
+
+	// how far through listing a repo the last fetch got, so an interrupted fetch can be resumed
+	_, err = tx.Exec(`
+	CREATE TABLE IF NOT EXISTS "fetch_checkpoints" (
+	    "repo" CHAR(64) NOT NULL, 
+	    "kind" CHAR(32) NOT NULL,
+	    "page" INTEGER NOT NULL,
+	    "cursor" VARCHAR,
+	    "last" INTEGER,
+	    "newest" DATE,
+	    "updated" DATE NOT NULL,
+	    PRIMARY KEY (repo, kind)
+	)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create fetch_checkpoints table %s: %w", cache.Path, err)
+	}
+
+	return nil
+}
+
+// createDataTables creates the tables & columns added for data beyond the original prs, issues & events, older caches
+// fill them as they are refetched.
+func (cache Cache) createDataTables(tx *sql.Tx) error {
+	// size & diff stats, caches created before these were captured get them added and filled on the next full fetch
+	for _, col := range []string{"additions", "deletions", "changed_files", "commits"} {
+		if err := cache.ensureColumn(tx, "prs", col, "INTEGER"); err != nil {
+			return err
+		}
+	}
+
+	// ci stats, computed from the checks table
+	for _, col := range [][2]string{{"daystogreen", "REAL"}, {"mergedfailing", "INTEGER"}, {"flakychecks", "INTEGER"}} {
+		if err := cache.ensureColumn(tx, "prs", col[0], col[1]); err != nil {
+			return err
+		}
+	}
+
+	// the first release a merged pr shipped in, mapped after each fetch from the releases table. releasechecked is when
+	// the last release compared against an unreleased pr was published so each release is only compared once
+	for _, col := range [][2]string{{"mergesha", "CHAR(40)"}, {"release", "VARCHAR"}, {"released", "DATE"}, {"daystorelease", "REAL"}, {"mergedtorelease", "REAL"}, {"releasechecked", "DATE"}} {
+		if err := cache.ensureColumn(tx, "prs", col[0], col[1]); err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS "reviews" (
+	    "repo" CHAR(64) NOT NULL, 
+	    "pr" INTEGER NOT NULL,
+	    "id" INTEGER NOT NULL,
+	    "user" CHAR(64) NOT NULL,
+	    "state" CHAR(32) NOT NULL,
+	    "submitted" DATE NOT NULL,
+	    "commit_id" CHAR(40),
+	    "association" CHAR(32),
+	    "body" VARCHAR,
+	    "url" CHAR(128),
+	    PRIMARY KEY (repo, pr, id)
+	)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create reviews table %s: %w", cache.Path, err)
+	}
+
+	// the paths changed by a PR, replaced whenever the PR is refetched as force pushes can drop files
+	_, err = tx.Exec(`
+	CREATE TABLE IF NOT EXISTS "pr_files" (
+	    "repo" CHAR(64) NOT NULL, 
+	    "pr" INTEGER NOT NULL,
+	    "path" VARCHAR NOT NULL,
+	    "status" CHAR(16),
+	    "additions" INTEGER,
+	    "deletions" INTEGER,
+	    PRIMARY KEY (repo, pr, path)
+	)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create pr_files table %s: %w", cache.Path, err)
+	}
+
+	// issue comments (the conversation on issues & prs) and review comments (left on a pr's diff), the body is only
+	// kept as a length as the text is not needed for stats
+	_, err = tx.Exec(`
+	CREATE TABLE IF NOT EXISTS "comments" (
+	    "repo" CHAR(64) NOT NULL, 
+	    "kind" CHAR(16) NOT NULL,
+	    "id" INTEGER NOT NULL,
+	    "item" INTEGER NOT NULL,
+	    "user" CHAR(64) NOT NULL,
+	    "association" CHAR(32),
+	    "created" DATE NOT NULL,
+	    "updated" DATE,
+	    "body_length" INTEGER,
+	    "in_reply_to" INTEGER,
+	    "path" VARCHAR,
+	    "line" INTEGER,
+	    "url" CHAR(128),
+	    PRIMARY KEY (repo, kind, id)
+	)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create comments table %s: %w", cache.Path, err)
+	}
+
+	_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS "comments_item" ON "comments" (repo, item)`)
+	if err != nil {
+		return fmt.Errorf("failed to create comments index %s: %w", cache.Path, err)
+	}
+
+	// repositories discovered for an org or user, replaced whenever it is rediscovered. it is only a record of the last
+	// discovery so caches from before repos were keyed by host just drop it and rediscover on the next fetch
+	hasHost, err := cache.hasColumn(tx, "repos", "host")
+	if err != nil {
+		return err
+	}
+	if !hasHost {
+		if _, err = tx.Exec(`DROP TABLE IF EXISTS "repos"`); err != nil {
+			return fmt.Errorf("failed to drop repos table %s: %w", cache.Path, err)
+		}
+	}
+
+	_, err = tx.Exec(`
+	CREATE TABLE IF NOT EXISTS "repos" (
+	    "host" CHAR(64) NOT NULL,
+	    "owner" CHAR(64) NOT NULL, 
+	    "name" CHAR(64) NOT NULL,
+	    "default_branch" CHAR(64),
+	    "stars" INTEGER,
+	    "archived" INTEGER,
+	    "fork" INTEGER,
+	    "topics" VARCHAR,
+	    "created" DATE,
+	    "pushed" DATE,
+	    "discovered" DATE NOT NULL,
+	    PRIMARY KEY (host, owner, name)
+	)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create repos table %s: %w", cache.Path, err)
+	}
+
+	// check runs & commit statuses of every commit a pr has had
+	_, err = tx.Exec(`
+	CREATE TABLE IF NOT EXISTS "checks" (
+	    "repo" CHAR(64) NOT NULL, 
+	    "pr" INTEGER NOT NULL,
+	    "sha" CHAR(40) NOT NULL,
+	    "kind" CHAR(16) NOT NULL,
+	    "id" INTEGER NOT NULL,
+	    "name" VARCHAR NOT NULL,
+	    "status" CHAR(16) NOT NULL,
+	    "conclusion" CHAR(16),
+	    "started" DATE,
+	    "completed" DATE,
+	    "attempt" INTEGER NOT NULL,
+	    PRIMARY KEY (repo, kind, id)
+	)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create checks table %s: %w", cache.Path, err)
+	}
+
+	_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS "checks_pr" ON "checks" (repo, pr)`)
+	if err != nil {
+		return fmt.Errorf("failed to create checks index %s: %w", cache.Path, err)
+	}
+
+	// published releases, drafts have no tag yet so are skipped
+	_, err = tx.Exec(`
+	CREATE TABLE IF NOT EXISTS "releases" (
+	    "repo" CHAR(64) NOT NULL, 
+	    "id" INTEGER NOT NULL,
+	    "tag" VARCHAR NOT NULL,
+	    "name" VARCHAR NOT NULL,
+	    "prerelease" INTEGER NOT NULL,
+	    "created" DATE NOT NULL,
+	    "published" DATE NOT NULL,
+	    "body" TEXT NOT NULL,
+	    PRIMARY KEY (repo, id)
+	)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create releases table %s: %w", cache.Path, err)
+	}
+
+	return nil
+}
+
+// addEventDetailColumns adds the details of the timeline events that have more than a state, label, milestone or
+// body, rows stored before these were added get them on the next full fetch.
+func (cache Cache) addEventDetailColumns(tx *sql.Tx) error {
+	eventColumns := [][2]string{
+		{"assignee", "CHAR(64)"},
+		{"reviewer", "CHAR(64)"},
+		{"team", "CHAR(64)"},
+		{"rename_from", "VARCHAR"},
+		{"rename_to", "VARCHAR"},
+		{"source_repo", "CHAR(128)"},
+		{"source_number", "INTEGER"},
+		{"source_kind", "CHAR(16)"},
+		{"commit_id", "CHAR(40)"},
+		{"before_commit", "CHAR(40)"},
+		{"lock_reason", "CHAR(32)"},
+	}
+	for _, col := range eventColumns {
+		if err := cache.ensureColumn(tx, "events", col[0], col[1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createProjectTables creates the tables projects (v2) boards are stored in.
+func (cache Cache) createProjectTables(tx *sql.Tx) error {
+	// items on a projects (v2) board, removed is set once an item is no longer on the board
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS "project_items" (
+	    "project" CHAR(128) NOT NULL, 
+	    "id" CHAR(64) NOT NULL,
+	    "type" CHAR(32) NOT NULL,
+	    "repo" CHAR(64),
+	    "number" INTEGER,
+	    "title" VARCHAR,
+	    "archived" INTEGER NOT NULL,
+	    "updated" DATE NOT NULL,
+	    "removed" DATE,
+	    PRIMARY KEY (project, id)
+	)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create project_items table %s: %w", cache.Path, err)
+	}
+
+	// every value a project item's field has had, a row is added each time a fetch sees it change. a null value is
+	// the field being cleared
+	_, err = tx.Exec(`
+	CREATE TABLE IF NOT EXISTS "project_field_values" (
+	    "project" CHAR(128) NOT NULL, 
+	    "item" CHAR(64) NOT NULL,
+	    "field" VARCHAR NOT NULL,
+	    "value" VARCHAR,
+	    "since" DATE NOT NULL,
+	    PRIMARY KEY (project, item, field, since)
+	)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create project_field_values table %s: %w", cache.Path, err)
+	}
+
+	return nil
+}
+
+// addIssueStatsColumns adds the waiting & first response stats UpsertIssueStats has always written to issues but were
+// never part of the table.
+func (cache Cache) addIssueStatsColumns(tx *sql.Tx) error {
+	for _, col := range []string{"dayswaiting", "daystofirst"} {
+		if err := cache.ensureColumn(tx, "issues", col, "REAL"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addEventKindColumn adds the kind of item an event belongs to, see EventKindIssue. events stored before it are left
+// without one, gitlab issue events among them are kept apart once their issue is fetched again.
+func (cache Cache) addEventKindColumn(tx *sql.Tx) error {
+	return cache.ensureColumn(tx, "events", "kind", "CHAR(16)")
+}
+
+// addPRLabelsAndDraftColumns adds the labels (comma separated like issues) & draft state of prs so they can be filtered
+// on, prs stored before these were added get them on the next full fetch.
+func (cache Cache) addPRLabelsAndDraftColumns(tx *sql.Tx) error {
+	if err := cache.ensureColumn(tx, "prs", "labels", "VARCHAR(256)"); err != nil {
+		return err
+	}
+
+	return cache.ensureColumn(tx, "prs", "draft", "INTEGER")
+}
+
+// ensureColumn adds a column to an existing table if it is not already there.
+func (cache Cache) ensureColumn(tx *sql.Tx, table, column, decl string) error {
+	has, err := cache.hasColumn(tx, table, column)
+	if err != nil || has {
+		return err
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE "%s" ADD COLUMN "%s" %s`, table, column, decl)); err != nil {
+		return fmt.Errorf("failed to add column %s to %s: %w", column, table, err)
+	}
+
+	return nil
+}
+
+// hasColumn checks if a table has a column, a table that does not exist has none.
+func (cache Cache) hasColumn(tx *sql.Tx, table, column string) (bool, error) {
+	rows, err := tx.Query(fmt.Sprintf(`PRAGMA table_info("%s")`, table))
+	if err != nil {
+		return false, fmt.Errorf("failed to get columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notnull, pk int
+		var name, ctype string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &ctype, &notnull, &dflt, &pk); err != nil {
+			return false, fmt.Errorf("failed to scan columns of %s: %w", table, err)
+		}
+
+		if name == column {
+			return true, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to get columns of %s: %w", table, err)
+	}
+
+	return false, nil
 }
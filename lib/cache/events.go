@@ -1,10 +1,14 @@
 package cache
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"strconv"
 	"time"
 
-	"github.com/google/go-github/v45/github"
+	"github.com/katbyte/gogo-repo-stats/lib/gh"
+	"github.com/katbyte/gogo-repo-stats/lib/provider"
 )
 
 // for now all we care about is events. pr events with automation is how we can do an easy "time waiting"
@@ -12,11 +16,26 @@ import (
 // this is more reliable than just looking at reviews as waiting for response can be added without a review via a comment left (same for its removal)
 // therefore it is the best proxy we have for "how long has this PR been waiting"
 
-// TODO - table per event type?
+// events that carry more than a state, label, milestone or body have their details in columns of their own (see
+// provider.Event), empty for every other kind of event
+//
+// events are keyed by their node id as several can happen in the same second (a label & milestone set together, a
+// merge and its close). those without one, made from a webhook delivery or stored before events had ids, are keyed by
+// a local id made from the event itself
+
+// LocalEventIDPrefix starts the ids made up for events github gave no id for
+const LocalEventIDPrefix = "local:"
+
+// EventKindIssue is the kind of the events of an issue fetched through a provider. gitlab numbers merge requests &
+// issues apart so !5 and #5 can both exist, the kind keeps the issue's events out of the pr's. github numbers them
+// together so its events have no kind
+const EventKindIssue = "issue"
 
 type Event struct {
 	Repo  string
 	PR    int
+	Kind  string
+	ID    string
 	Date  time.Time
 	Event string
 	User  string
@@ -26,75 +45,135 @@ type Event struct {
 	Milestone string
 	Body      string
 
+	Assignee     string
+	Reviewer     string
+	Team         string
+	RenameFrom   string
+	RenameTo     string
+	SourceRepo   string
+	SourceNumber int
+	SourceKind   string
+	CommitID     string
+	BeforeCommit string
+	LockReason   string
+
 	URL string
 }
 
-func (cache Cache) UpsertEvent(repo string, pr int, event *github.Timeline) error {
-	stmt, err := cache.DB.Prepare("INSERT OR REPLACE INTO events (repo, pr, date, event, user, state, label, milestone, body, url) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+func (cache Cache) UpsertEvent(repo string, pr int, event *gh.TimelineEvent) error {
+	e, err := gh.NormalizeEvent(event)
 	if err != nil {
-		return fmt.Errorf("failed to prepare insert statement for event %d/%s: %w", pr, event.GetURL(), err)
+		return fmt.Errorf("pr %d: %w", pr, err)
 	}
 
-	// get user - it is either User/Actor
-	u := ""
-	if event.Actor != nil && event.User != nil {
-		if event.GetActor() == event.GetUser() {
-			return fmt.Errorf("both actor and user exist and differ  %d/%s: %s != %s", pr, event.GetURL(), event.GetActor(), event.GetUser())
-		}
-	}
+	return cache.UpsertRepoEvent(repo, pr, e)
+}
 
-	if event.Actor != nil {
-		u = event.Actor.GetLogin()
-	}
-	if event.User != nil {
-		u = event.User.GetLogin()
+// localEventID makes an id for an event from everything about it, so storing it again replaces rather than duplicates it
+func localEventID(e provider.Event) string {
+	h := sha256.New()
+	for _, v := range []string{
+		e.Event, e.Date.UTC().Format(time.RFC3339Nano), e.User, e.State, e.Label, e.Milestone, e.Body,
+		e.Assignee, e.Reviewer, e.Team, e.RenameFrom, e.RenameTo, e.SourceRepo, strconv.Itoa(e.SourceNumber), e.SourceKind,
+		e.CommitID, e.BeforeCommit, e.LockReason,
+	} {
+		h.Write([]byte(v + "\x00"))
 	}
 
-	// get correct date - createdAt opr SubmittedAt
-	if event.CreatedAt != nil && event.SubmittedAt != nil {
-		if event.GetActor() == event.GetUser() {
-			return fmt.Errorf("both actor and user exist and differ %d:  %s  == %s", pr, event.GetActor(), event.GetUser())
+	return LocalEventIDPrefix + hex.EncodeToString(h.Sum(nil))[:20]
+}
+
+// UpsertRepoEvent stores an event of a pr, or of an issue numbered together with prs.
+func (cache Cache) UpsertRepoEvent(repo string, pr int, event provider.Event) error {
+	return cache.upsertRepoEvent(repo, "", pr, event)
+}
+
+// UpsertRepoIssueEvent stores an event of an issue kept apart from the events of a pr with the same number.
+func (cache Cache) UpsertRepoIssueEvent(repo string, number int, event provider.Event) error {
+	return cache.upsertRepoEvent(repo, EventKindIssue, number, event)
+}
+
+func (cache Cache) upsertRepoEvent(repo, kind string, pr int, event provider.Event) error {
+	id := event.ID
+	if id == "" {
+		id = localEventID(event)
+
+		// the same thing happening to an issue & a pr with the same number is two events, they are keyed apart
+		if kind != "" {
+			id += ":" + kind
 		}
 	}
 
-	var t time.Time
-	if event.CreatedAt != nil {
-		t = event.GetCreatedAt()
-	}
-	if event.SubmittedAt != nil {
-		t = event.GetSubmittedAt()
+	stmt, err := cache.DB.Prepare(`
+		INSERT OR REPLACE INTO events (
+			repo, pr, kind, id, date, event, user, state, label, milestone, body,
+			assignee, reviewer, team, rename_from, rename_to, source_repo, source_number, source_kind, commit_id, before_commit, lock_reason,
+			url
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement for event %d/%s: %w", pr, event.URL, err)
 	}
 
 	_, err = stmt.Exec(
 		repo,
 		pr,
-		t,
-		event.GetEvent(),
-		u,
-
-		event.GetState(),
-		event.GetLabel().GetName(),
-		event.GetMilestone().GetTitle(),
-		event.GetBody(),
-
-		event.GetURL(),
+		kind,
+		id,
+		event.Date,
+		event.Event,
+		event.User,
+
+		event.State,
+		event.Label,
+		event.Milestone,
+		event.Body,
+
+		event.Assignee,
+		event.Reviewer,
+		event.Team,
+		event.RenameFrom,
+		event.RenameTo,
+		event.SourceRepo,
+		event.SourceNumber,
+		event.SourceKind,
+		event.CommitID,
+		event.BeforeCommit,
+		event.LockReason,
+
+		event.URL,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to insert event %d/%s: %w", pr, event.GetURL(), err)
+		return fmt.Errorf("failed to insert event %d/%s: %w", pr, event.URL, err)
 	}
 	stmt.Close()
 
 	return nil
 }
 
+// GetEventsFor returns the events of a pr, or of an issue numbered together with prs.
 func (cache Cache) GetEventsFor(repo string, number int) ([]Event, error) {
-	rows, err := cache.DB.Query(fmt.Sprintf(`
-		SELECT repo, pr, date, event, user, state, label, milestone, body, url 
+	return cache.getEventsFor(repo, number, `COALESCE(kind, '') = ''`)
+}
+
+// GetIssueEventsFor returns the events of an issue whether or not it is numbered apart from prs.
+func (cache Cache) GetIssueEventsFor(repo string, number int) ([]Event, error) {
+	return cache.getEventsFor(repo, number, `COALESCE(kind, '') IN ('', '`+EventKindIssue+`')`)
+}
+
+func (cache Cache) getEventsFor(repo string, number int, kind string) ([]Event, error) {
+	rows, err := cache.DB.Query(`
+		SELECT repo, pr, COALESCE(kind, ''), id, date, event, user, state, label, milestone, body,
+			COALESCE(assignee, ''), COALESCE(reviewer, ''), COALESCE(team, ''), COALESCE(rename_from, ''), COALESCE(rename_to, ''),
+			COALESCE(source_repo, ''), COALESCE(source_number, 0), COALESCE(source_kind, ''),
+			COALESCE(commit_id, ''), COALESCE(before_commit, ''), COALESCE(lock_reason, ''),
+			url 
 		FROM events 
 		WHERE
-			repo='%s' AND
-		    pr='%d' ORDER BY date
-	`, repo, number))
+			repo = ? AND
+		    pr = ? AND
+		    `+kind+` ORDER BY date, rowid
+	`, repo, number)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query events for pr %d: %w", number, err)
 	}
@@ -111,6 +190,8 @@ func (cache Cache) GetEventsFor(repo string, number int) ([]Event, error) {
 		err = rows.Scan(
 			&e.Repo,
 			&e.PR,
+			&e.Kind,
+			&e.ID,
 			&e.Date,
 			&e.Event,
 			&e.User,
@@ -118,6 +199,17 @@ func (cache Cache) GetEventsFor(repo string, number int) ([]Event, error) {
 			&e.Label,
 			&e.Milestone,
 			&e.Body,
+			&e.Assignee,
+			&e.Reviewer,
+			&e.Team,
+			&e.RenameFrom,
+			&e.RenameTo,
+			&e.SourceRepo,
+			&e.SourceNumber,
+			&e.SourceKind,
+			&e.CommitID,
+			&e.BeforeCommit,
+			&e.LockReason,
 			&e.URL,
 		)
 
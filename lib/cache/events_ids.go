@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+
+	c "github.com/gookit/color" // nolint:misspell
+	"github.com/katbyte/gogo-repo-stats/lib/provider"
+)
+
+// rekeyEvents moves events of caches made before they had ids from being keyed by (repo, pr, date) to (repo, pr, id).
+// sqlite can't change a primary key so the table is rebuilt, each existing event getting a local id. events lost to
+// same second collisions before are only recovered by refetching their pr or issue
+func (cache Cache) rekeyEvents(tx *sql.Tx) error {
+	has, err := cache.hasColumn(tx, "events", "id")
+	if err != nil || has {
+		return err
+	}
+
+	c.Printf("  rekeying <white>events</> by id...\n")
+	_, err = tx.Exec(`
+	CREATE TABLE "events_rekeyed" (
+	    "repo" CHAR(64) NOT NULL,
+	    "pr" INTEGER,
+	    "id" CHAR(128) NOT NULL,
+	    "date" DATE NOT NULL,
+	    "event" CHAR(32) NOT NULL,
+	    "user" CHAR(64) NOT NULL,
+
+	    "state" CHAR(32),
+	    "label" CHAR(64),
+	    "milestone" CHAR(64),
+	    "body" VARCHAR,
+
+	    "url" CHAR(128) NOT NULL,
+
+	    "assignee" CHAR(64),
+	    "reviewer" CHAR(64),
+	    "team" CHAR(64),
+	    "rename_from" VARCHAR,
+	    "rename_to" VARCHAR,
+	    "source_repo" CHAR(128),
+	    "source_number" INTEGER,
+	    "source_kind" CHAR(16),
+	    "commit_id" CHAR(40),
+	    "before_commit" CHAR(40),
+	    "lock_reason" CHAR(32),
+	    PRIMARY KEY (repo, pr, id)
+	)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create rekeyed events table %s: %w", cache.Path, err)
+	}
+
+	rows, err := tx.Query(`
+		SELECT rowid, date, event, user, COALESCE(state, ''), COALESCE(label, ''), COALESCE(milestone, ''), COALESCE(body, ''),
+			COALESCE(assignee, ''), COALESCE(reviewer, ''), COALESCE(team, ''), COALESCE(rename_from, ''), COALESCE(rename_to, ''),
+			COALESCE(source_repo, ''), COALESCE(source_number, 0), COALESCE(source_kind, ''),
+			COALESCE(commit_id, ''), COALESCE(before_commit, ''), COALESCE(lock_reason, '')
+		FROM events
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query events to rekey: %w", err)
+	}
+
+	ids := map[int64]string{}
+	for rows.Next() {
+		var rowid int64
+		e := provider.Event{}
+		err = rows.Scan(&rowid, &e.Date, &e.Event, &e.User, &e.State, &e.Label, &e.Milestone, &e.Body,
+			&e.Assignee, &e.Reviewer, &e.Team, &e.RenameFrom, &e.RenameTo,
+			&e.SourceRepo, &e.SourceNumber, &e.SourceKind,
+			&e.CommitID, &e.BeforeCommit, &e.LockReason)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan events to rekey: %w", err)
+		}
+		ids[rowid] = localEventID(e)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to get events to rekey: %w", err)
+	}
+
+	// rowids are kept so events in the same second stay in the order they were stored
+	stmt, err := tx.Prepare(`
+		INSERT INTO events_rekeyed (
+			rowid, repo, pr, id, date, event, user, state, label, milestone, body, url,
+			assignee, reviewer, team, rename_from, rename_to, source_repo, source_number, source_kind, commit_id, before_commit, lock_reason
+		)
+		SELECT
+			rowid, repo, pr, ?, date, event, user, state, label, milestone, body, url,
+			assignee, reviewer, team, rename_from, rename_to, source_repo, source_number, source_kind, commit_id, before_commit, lock_reason
+		FROM events WHERE rowid = ?
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare rekeying events: %w", err)
+	}
+	defer stmt.Close()
+
+	for rowid, id := range ids {
+		if _, err = stmt.Exec(id, rowid); err != nil {
+			return fmt.Errorf("failed to rekey event %d: %w", rowid, err)
+		}
+	}
+
+	if _, err = tx.Exec(`DROP TABLE events`); err != nil {
+		return fmt.Errorf("failed to drop old events table: %w", err)
+	}
+	if _, err = tx.Exec(`ALTER TABLE events_rekeyed RENAME TO events`); err != nil {
+		return fmt.Errorf("failed to rename rekeyed events table: %w", err)
+	}
+
+	c.Printf("    rekeyed <white>%d</> events\n", len(ids))
+
+	return nil
+}
+
+// EventAudit is what AuditEvents found for a repo.
+type EventAudit struct {
+	// Duplicates are events stored under a local id that have since been fetched with their own, they are removed
+	Duplicates int
+
+	// Collisions are the pr or issue timestamps shared by more than one event, before events had ids all but one
+	// of them would have been lost
+	Collisions int
+
+	// Numbers are the prs & issues whose events changed
+	Numbers []int
+}
+
+// AuditEvents removes events duplicated under a local id and counts the timestamps shared by several events.
+func (cache Cache) AuditEvents(repo string) (*EventAudit, error) {
+	audit := EventAudit{}
+
+	// a local id is only ever a stand in, once the same event turns up with github's id it is a duplicate
+	rows, err := cache.DB.Query(`
+		SELECT DISTINCT l.pr, l.id
+		FROM events l
+		JOIN events r ON
+			r.repo = l.repo AND
+			r.pr = l.pr AND
+			COALESCE(r.kind, '') = COALESCE(l.kind, '') AND
+			r.event = l.event AND
+			r.date = l.date AND
+			r.user = l.user AND
+			COALESCE(r.label, '') = COALESCE(l.label, '') AND
+			COALESCE(r.milestone, '') = COALESCE(l.milestone, '') AND
+			r.id NOT LIKE ? || '%'
+		WHERE
+			l.repo = ? AND
+			l.id LIKE ? || '%'
+	`, LocalEventIDPrefix, repo, LocalEventIDPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duplicate events of %s: %w", repo, err)
+	}
+
+	type key struct {
+		pr int
+		id string
+	}
+	var dupes []key
+	for rows.Next() {
+		k := key{}
+		if err = rows.Scan(&k.pr, &k.id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan duplicate events of %s: %w", repo, err)
+		}
+		dupes = append(dupes, k)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get duplicate events of %s: %w", repo, err)
+	}
+
+	changed := map[int]bool{}
+	for _, k := range dupes {
+		if _, err = cache.DB.Exec(`DELETE FROM events WHERE repo = ? AND pr = ? AND id = ?`, repo, k.pr, k.id); err != nil {
+			return nil, fmt.Errorf("failed to remove duplicate event %s#%d %s: %w", repo, k.pr, k.id, err)
+		}
+
+		audit.Duplicates++
+		if !changed[k.pr] {
+			changed[k.pr] = true
+			audit.Numbers = append(audit.Numbers, k.pr)
+		}
+	}
+
+	err = cache.DB.QueryRow(`
+		SELECT COUNT(*) FROM (
+			SELECT 1 FROM events WHERE repo = ? GROUP BY pr, kind, date HAVING COUNT(*) > 1
+		)
+	`, repo).Scan(&audit.Collisions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count event collisions of %s: %w", repo, err)
+	}
+
+	return &audit, nil
+}
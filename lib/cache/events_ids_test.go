@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/katbyte/gogo-repo-stats/lib/provider"
+)
+
+func TestRekeyEvents(t *testing.T) {
+	cache, err := open(filepath.Join(t.TempDir(), "cache.db"), true)
+	if err != nil {
+		t.Fatalf("opening cache: %v", err)
+	}
+	t.Cleanup(func() { cache.DB.Close() })
+
+	// events as they were before they had ids, keyed by when they happened
+	_, err = cache.DB.Exec(`
+	CREATE TABLE "events" (
+	    "repo" CHAR(64) NOT NULL,
+	    "pr" INTEGER,
+	    "date" DATE NOT NULL,
+	    "event" CHAR(32) NOT NULL,
+	    "user" CHAR(64) NOT NULL,
+	    "state" CHAR(32),
+	    "label" CHAR(64),
+	    "milestone" CHAR(64),
+	    "body" VARCHAR,
+	    "url" CHAR(128) NOT NULL,
+	    PRIMARY KEY (repo, pr, date)
+	)
+	`)
+	if err != nil {
+		t.Fatalf("creating legacy events table: %v", err)
+	}
+
+	legacy := []Event{
+		{PR: 1, Date: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), Event: "labeled", User: "alice", Label: "bug"},
+		{PR: 1, Date: time.Date(2024, 3, 2, 9, 30, 0, 0, time.UTC), Event: "reviewed", User: "bob", State: "approved", Body: "lgtm"},
+		{PR: 2, Date: time.Date(2024, 3, 3, 8, 0, 0, 0, time.UTC), Event: "milestoned", User: "carol", Milestone: "v1.0"},
+	}
+	for _, e := range legacy {
+		_, err = cache.DB.Exec(`INSERT INTO events (repo, pr, date, event, user, state, label, milestone, body, url) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, '')`,
+			"katbyte/a", e.PR, e.Date, e.Event, e.User, e.State, e.Label, e.Milestone, e.Body)
+		if err != nil {
+			t.Fatalf("inserting legacy event: %v", err)
+		}
+	}
+
+	if _, err = cache.Migrate(); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+
+	for _, pr := range []int{1, 2} {
+		events, err := cache.GetEventsFor("katbyte/a", pr)
+		if err != nil {
+			t.Fatalf("getting events of #%d: %v", pr, err)
+		}
+
+		var expected, got []string
+		for _, e := range legacy {
+			if e.PR == pr {
+				expected = append(expected, localEventID(provider.Event{Date: e.Date, Event: e.Event, User: e.User, State: e.State, Label: e.Label, Milestone: e.Milestone, Body: e.Body}))
+			}
+		}
+		for _, e := range events {
+			got = append(got, e.ID)
+		}
+		sort.Strings(expected)
+		sort.Strings(got)
+
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected #%d's events to be keyed by %v, got %v", pr, expected, got)
+		}
+	}
+}
+
+func TestAuditEvents(t *testing.T) {
+	cache := openTestCache(t)
+
+	at := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	labeled := provider.Event{Date: at, Event: "labeled", User: "alice", Label: "bug"}
+
+	// stored without an id before github's was known, then again with it
+	if err := cache.UpsertRepoEvent("katbyte/a", 1, labeled); err != nil {
+		t.Fatalf("upserting event: %v", err)
+	}
+	withID := labeled
+	withID.ID = "LE_kwDOAAAAAc4AAAAB"
+	if err := cache.UpsertRepoEvent("katbyte/a", 1, withID); err != nil {
+		t.Fatalf("upserting event: %v", err)
+	}
+
+	// the same event on an issue numbered apart from the pr is not a duplicate of it
+	if err := cache.UpsertRepoIssueEvent("katbyte/a", 1, labeled); err != nil {
+		t.Fatalf("upserting issue event: %v", err)
+	}
+
+	// different events in the same second, before ids only one of them would have been kept
+	for _, user := range []string{"bob", "carol"} {
+		e := provider.Event{ID: "LE_" + user, Date: at, Event: "labeled", User: user, Label: "bug"}
+		if err := cache.UpsertRepoEvent("katbyte/a", 2, e); err != nil {
+			t.Fatalf("upserting event: %v", err)
+		}
+	}
+
+	audit, err := cache.AuditEvents("katbyte/a")
+	if err != nil {
+		t.Fatalf("auditing events: %v", err)
+	}
+
+	expected := EventAudit{Duplicates: 1, Collisions: 1, Numbers: []int{1}}
+	if !reflect.DeepEqual(*audit, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, *audit)
+	}
+
+	events, err := cache.GetEventsFor("katbyte/a", 1)
+	if err != nil {
+		t.Fatalf("getting events: %v", err)
+	}
+	if len(events) != 1 || events[0].ID != withID.ID {
+		t.Errorf("expected only the event with github's id to be left, got %+v", events)
+	}
+
+	issueEvents, err := cache.GetIssueEventsFor("katbyte/a", 1)
+	if err != nil {
+		t.Fatalf("getting issue events: %v", err)
+	}
+	if len(issueEvents) != 2 {
+		t.Errorf("expected the issue's event to be kept, got %+v", issueEvents)
+	}
+
+	// nothing is left to remove the second time round
+	audit, err = cache.AuditEvents("katbyte/a")
+	if err != nil {
+		t.Fatalf("auditing events again: %v", err)
+	}
+	if audit.Duplicates != 0 || len(audit.Numbers) != 0 {
+		t.Errorf("expected no more duplicates, got %+v", *audit)
+	}
+}
@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"strings"
+	"time"
+)
+
+// Filter narrows the prs or issues a query works on. the zero value matches everything, each set field is ANDed and
+// the values within a field are ORed. it compiles to parameterised sql so names with quotes in them are safe.
+type Filter struct {
+	Repos          []string
+	Authors        []string
+	ExcludeAuthors []string
+	Labels         []string // has any of these labels
+	Milestones     []string
+	States         []string // open or closed, merged prs are closed
+
+	// zero times leave that end of the range open
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	ClosedFrom  time.Time
+	ClosedTo    time.Time
+
+	// nil matches either, true only drafts (or bots) and false none. drafts only apply to prs
+	Drafts *bool
+	Bots   *bool
+
+	// Removed includes the items a full fetch found github no longer has (deleted, transferred or converted to a
+	// discussion), by default they are left out
+	Removed bool
+}
+
+// filterTimeFormat is how times are compared, dates are stored with their zone so this is a prefix of them
+const filterTimeFormat = "2006-01-02 15:04:05"
+
+// botSuffix is how github names the users of apps
+const botSuffix = "[bot]"
+
+// sql returns the filter's conditions ANDed together, or "1 = 1" when there are none, and their args. kind is the
+// SyncKind of the items, empty for tables without any, and table the table or alias the columns are prefixed with in
+// queries that join.
+func (f Filter) sql(kind, table string) (string, []any) {
+	col := func(name string) string {
+		if table == "" {
+			return name
+		}
+		return table + "." + name
+	}
+
+	var conds []string
+	var args []any
+
+	in := func(column string, values []string, not bool) {
+		if len(values) == 0 {
+			return
+		}
+
+		op := "IN"
+		if not {
+			op = "NOT IN"
+		}
+		conds = append(conds, col(column)+" "+op+" ("+strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")+")")
+		for _, v := range values {
+			args = append(args, v)
+		}
+	}
+	in("repo", f.Repos, false)
+	in("user", f.Authors, false)
+	in("user", f.ExcludeAuthors, true)
+	in("milestone", f.Milestones, false)
+	in("state", f.States, false)
+
+	// labels are stored comma separated so each is matched with its commas, instr avoids LIKE wildcards in names
+	if len(f.Labels) > 0 {
+		var ors []string
+		for _, l := range f.Labels {
+			ors = append(ors, "instr(',' || COALESCE("+col("labels")+", '') || ',', ?) > 0")
+			args = append(args, ","+l+",")
+		}
+		conds = append(conds, "("+strings.Join(ors, " OR ")+")")
+	}
+
+	between := func(column string, from, to time.Time) {
+		if !from.IsZero() {
+			conds = append(conds, col(column)+" >= ?")
+			args = append(args, from.Format(filterTimeFormat))
+		}
+		// stored dates go on past the seconds, so <= would miss those in to's second
+		if !to.IsZero() {
+			conds = append(conds, col(column)+" < ?")
+			args = append(args, to.Truncate(time.Second).Add(time.Second).Format(filterTimeFormat))
+		}
+	}
+	between("created", f.CreatedFrom, f.CreatedTo)
+	between("closed", f.ClosedFrom, f.ClosedTo)
+
+	if f.Drafts != nil {
+		conds = append(conds, "COALESCE("+col("draft")+", 0) = ?")
+		args = append(args, *f.Drafts)
+	}
+
+	if f.Bots != nil {
+		op := "NOT LIKE"
+		if *f.Bots {
+			op = "LIKE"
+		}
+		conds = append(conds, col("user")+" "+op+" ?")
+		args = append(args, "%"+botSuffix)
+	}
+
+	// the kinds are named after their tables, which the subquery needs to tell the item's columns from its own
+	if kind != "" && !f.Removed {
+		outer := table
+		if outer == "" {
+			outer = kind
+		}
+		conds = append(conds, "NOT EXISTS (SELECT 1 FROM removed rm WHERE rm.repo = "+outer+".repo AND rm.kind = ? AND rm.number = "+outer+".number)")
+		args = append(args, kind)
+	}
+
+	if len(conds) == 0 {
+		return "1 = 1", nil
+	}
+
+	return strings.Join(conds, " AND "), args
+}
@@ -0,0 +1,194 @@
+package cache
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/katbyte/gogo-repo-stats/lib/pointer"
+	"github.com/katbyte/gogo-repo-stats/lib/provider"
+)
+
+func TestFilterSQL(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 6, 30, 23, 59, 59, 0, time.UTC)
+
+	cases := []struct {
+		name   string
+		filter Filter
+		kind   string
+		table  string
+		where  string
+		args   []any
+	}{
+		{
+			name:  "zero matches everything",
+			where: "1 = 1",
+		},
+		{
+			name:   "repos",
+			filter: Filter{Repos: []string{"katbyte/a", "katbyte/b"}},
+			where:  "repo IN (?, ?)",
+			args:   []any{"katbyte/a", "katbyte/b"},
+		},
+		{
+			name:   "authors & excluded authors",
+			filter: Filter{Authors: []string{"alice"}, ExcludeAuthors: []string{"bob", "carol"}},
+			where:  "user IN (?) AND user NOT IN (?, ?)",
+			args:   []any{"alice", "bob", "carol"},
+		},
+		{
+			name:   "prefixed with the table",
+			filter: Filter{Milestones: []string{"v1.0"}, States: []string{"open"}},
+			table:  "p",
+			where:  "p.milestone IN (?) AND p.state IN (?)",
+			args:   []any{"v1.0", "open"},
+		},
+		{
+			name:   "labels are matched with their commas",
+			filter: Filter{Labels: []string{"bug", "won't fix"}},
+			where:  "(instr(',' || COALESCE(labels, '') || ',', ?) > 0 OR instr(',' || COALESCE(labels, '') || ',', ?) > 0)",
+			args:   []any{",bug,", ",won't fix,"},
+		},
+		{
+			name:   "created range",
+			filter: Filter{CreatedFrom: from, CreatedTo: to},
+			where:  "created >= ? AND created < ?",
+			args:   []any{"2024-01-01 00:00:00", "2024-07-01 00:00:00"},
+		},
+		{
+			name:   "open ended closed range",
+			filter: Filter{ClosedTo: to},
+			where:  "closed < ?",
+			args:   []any{"2024-07-01 00:00:00"},
+		},
+		{
+			name:   "no drafts",
+			filter: Filter{Drafts: pointer.To(false)},
+			where:  "COALESCE(draft, 0) = ?",
+			args:   []any{false},
+		},
+		{
+			name:   "only bots",
+			filter: Filter{Bots: pointer.To(true)},
+			where:  "user LIKE ?",
+			args:   []any{"%[bot]"},
+		},
+		{
+			name:   "no bots",
+			filter: Filter{Bots: pointer.To(false)},
+			table:  "p",
+			where:  "p.user NOT LIKE ?",
+			args:   []any{"%[bot]"},
+		},
+		{
+			name:   "removed are left out",
+			filter: Filter{States: []string{"open"}},
+			kind:   SyncKindPRs,
+			where:  "state IN (?) AND NOT EXISTS (SELECT 1 FROM removed rm WHERE rm.repo = prs.repo AND rm.kind = ? AND rm.number = prs.number)",
+			args:   []any{"open", "prs"},
+		},
+		{
+			name:  "removed are left out of an alias",
+			kind:  SyncKindPRs,
+			table: "p",
+			where: "NOT EXISTS (SELECT 1 FROM removed rm WHERE rm.repo = p.repo AND rm.kind = ? AND rm.number = p.number)",
+			args:  []any{"prs"},
+		},
+		{
+			name:   "removed included",
+			filter: Filter{Removed: true},
+			kind:   SyncKindIssues,
+			where:  "1 = 1",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			where, args := tc.filter.sql(tc.kind, tc.table)
+			if where != tc.where {
+				t.Errorf("expected where:\n  %s\ngot:\n  %s", tc.where, where)
+			}
+			if !reflect.DeepEqual(args, tc.args) {
+				t.Errorf("expected args %#v, got %#v", tc.args, args)
+			}
+		})
+	}
+}
+
+func TestFilterPRs(t *testing.T) {
+	cache := openTestCache(t)
+
+	day := func(d int) time.Time {
+		return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC)
+	}
+	prs := map[string][]provider.PR{
+		"katbyte/a": {
+			{Number: 1, Title: "one", User: "alice", State: "open", Labels: []string{"bug"}, Created: day(1)},
+			{Number: 2, Title: "two", User: "bob", State: "closed", Labels: []string{"bugfix"}, Created: day(5), Closed: day(6)},
+			{Number: 3, Title: "three", User: "dependabot[bot]", State: "open", Labels: []string{"deps", "won't fix"}, Created: day(10)},
+			{Number: 4, Title: "four", User: "alice", State: "open", Draft: true, Milestone: "v1.0", Created: day(15)},
+		},
+		"katbyte/b": {
+			{Number: 1, Title: "other", User: "carol", State: "closed", Labels: []string{"bug"}, Created: day(2), Closed: day(20)},
+		},
+	}
+	for repo, rprs := range prs {
+		for _, pr := range rprs {
+			if err := cache.UpsertRepoPR(repo, pr); err != nil {
+				t.Fatalf("upserting %s#%d: %v", repo, pr.Number, err)
+			}
+		}
+	}
+
+	// a full fetch found b#1 gone, and an issue a#2 that does not hide the pr
+	for _, r := range []struct {
+		repo, kind string
+		number     int
+	}{{"katbyte/b", SyncKindPRs, 1}, {"katbyte/a", SyncKindIssues, 2}} {
+		if _, err := cache.MarkRemoved(r.repo, r.kind, r.number); err != nil {
+			t.Fatalf("marking %s#%d removed: %v", r.repo, r.number, err)
+		}
+	}
+
+	cases := []struct {
+		name     string
+		filter   Filter
+		expected []string
+	}{
+		{"everything", Filter{Removed: true}, []string{"katbyte/a#1", "katbyte/a#2", "katbyte/a#3", "katbyte/a#4", "katbyte/b#1"}},
+		{"removed are left out", Filter{}, []string{"katbyte/a#1", "katbyte/a#2", "katbyte/a#3", "katbyte/a#4"}},
+		{"repo", Filter{Repos: []string{"katbyte/b"}, Removed: true}, []string{"katbyte/b#1"}},
+		{"label is not matched by prefix", Filter{Labels: []string{"bug"}, Removed: true}, []string{"katbyte/a#1", "katbyte/b#1"}},
+		{"label with a quote", Filter{Labels: []string{"won't fix"}}, []string{"katbyte/a#3"}},
+		{"excluded authors", Filter{Repos: []string{"katbyte/a"}, ExcludeAuthors: []string{"alice"}}, []string{"katbyte/a#2", "katbyte/a#3"}},
+		{"milestone", Filter{Milestones: []string{"v1.0"}}, []string{"katbyte/a#4"}},
+		{"state", Filter{States: []string{"closed"}, Removed: true}, []string{"katbyte/a#2", "katbyte/b#1"}},
+		{"created, to is inclusive", Filter{CreatedFrom: day(2), CreatedTo: day(10), Removed: true}, []string{"katbyte/a#2", "katbyte/a#3", "katbyte/b#1"}},
+		{"closed", Filter{ClosedFrom: day(10), Removed: true}, []string{"katbyte/b#1"}},
+		{"no drafts", Filter{Repos: []string{"katbyte/a"}, Drafts: pointer.To(false)}, []string{"katbyte/a#1", "katbyte/a#2", "katbyte/a#3"}},
+		{"no bots", Filter{Repos: []string{"katbyte/a"}, Bots: pointer.To(false)}, []string{"katbyte/a#1", "katbyte/a#2", "katbyte/a#4"}},
+		{"only bots", Filter{Bots: pointer.To(true)}, []string{"katbyte/a#3"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			found, err := cache.GetRepoPRs(tc.filter)
+			if err != nil {
+				t.Fatalf("getting prs: %v", err)
+			}
+
+			var got []string
+			for _, pr := range *found {
+				got = append(got, pr.Repo+"#"+strconv.Itoa(pr.Number))
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
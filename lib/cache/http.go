@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ConditionalResponse is the validators & payload of a github GET response, kept so fetches can revalidate rather than
+// redownload.
+type ConditionalResponse struct {
+	ETag         string
+	LastModified string
+	Header       http.Header
+	Body         []byte
+}
+
+func (cache Cache) GetConditionalResponse(url string) (*ConditionalResponse, error) {
+	var etag, lastModified, header sql.NullString
+	var body []byte
+
+	row := cache.DB.QueryRow(`SELECT etag, last_modified, header, body FROM http_cache WHERE url = ?`, url)
+	if err := row.Scan(&etag, &lastModified, &header, &body); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get http cache entry for %s: %w", url, err)
+	}
+
+	r := ConditionalResponse{
+		ETag:         etag.String,
+		LastModified: lastModified.String,
+		Header:       http.Header{},
+		Body:         body,
+	}
+	if header.String != "" {
+		if err := json.Unmarshal([]byte(header.String), &r.Header); err != nil {
+			return nil, fmt.Errorf("failed to decode http cache headers for %s: %w", url, err)
+		}
+	}
+
+	return &r, nil
+}
+
+// TouchConditionalResponse marks an entry as still current after github revalidated it with a 304, so the entries
+// revalidated most often are not the ones pruned.
+func (cache Cache) TouchConditionalResponse(url string) error {
+	if _, err := cache.DB.Exec(`UPDATE http_cache SET updated = ? WHERE url = ?`, time.Now().UTC(), url); err != nil {
+		return fmt.Errorf("failed to touch http cache entry %s: %w", url, err)
+	}
+
+	return nil
+}
+
+// PruneConditionalResponses removes the http cache entries last stored or revalidated before, returning how many were.
+func (cache Cache) PruneConditionalResponses(before time.Time) (int64, error) {
+	res, err := cache.DB.Exec(`DELETE FROM http_cache WHERE updated < ?`, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune http cache: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+func (cache Cache) UpsertConditionalResponse(url string, r ConditionalResponse) error {
+	header, err := json.Marshal(r.Header)
+	if err != nil {
+		return fmt.Errorf("failed to encode http cache headers for %s: %w", url, err)
+	}
+
+	stmt, err := cache.DB.Prepare(`
+		INSERT OR REPLACE INTO http_cache (url, etag, last_modified, header, body, updated)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement for http cache %s: %w", url, err)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.Exec(url, r.ETag, r.LastModified, string(header), r.Body, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to insert http cache entry %s: %w", url, err)
+	}
+
+	return nil
+}
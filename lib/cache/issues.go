@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/google/go-github/v45/github"
+	"github.com/katbyte/gogo-repo-stats/lib/gh"
+	"github.com/katbyte/gogo-repo-stats/lib/provider"
 )
 
 const ColumnsIssues = "repo, number, title, user, state, milestone, labels, created, closed, daysopen"
@@ -28,33 +30,31 @@ type Issue struct {
 }
 
 func (cache Cache) UpsertRepoIssueFromGH(repo string, issue *github.Issue) error {
+	return cache.UpsertRepoIssue(repo, gh.NormalizeIssue(issue))
+}
+
+func (cache Cache) UpsertRepoIssue(repo string, issue provider.Issue) error {
 	stmt, err := cache.DB.Prepare(`
 		INSERT OR REPLACE INTO issues (repo, number, title, user, state, milestone, labels, created, closed ) 
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
-		return fmt.Errorf("failed to prepare insert statement for issue %d: %w", issue.GetNumber(), err)
-	}
-
-	// get labels
-	labels := make([]string, 0)
-	for _, label := range issue.Labels {
-		labels = append(labels, label.GetName())
+		return fmt.Errorf("failed to prepare insert statement for issue %d: %w", issue.Number, err)
 	}
 
 	_, err = stmt.Exec(
 		repo,
-		strconv.Itoa(issue.GetNumber()),
-		issue.GetTitle(),
-		issue.User.GetLogin(),
-		issue.GetState(),
-		issue.GetMilestone().GetTitle(),
-		strings.Join(labels, ","),
-		issue.GetCreatedAt(),
-		issue.GetClosedAt(),
+		strconv.Itoa(issue.Number),
+		issue.Title,
+		issue.User,
+		issue.State,
+		issue.Milestone,
+		strings.Join(issue.Labels, ","),
+		issue.Created,
+		issue.Closed,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to insert issue %s#%d: %w", repo, issue.GetNumber(), err)
+		return fmt.Errorf("failed to insert issue %s#%d: %w", repo, issue.Number, err)
 	}
 	stmt.Close()
 
@@ -83,10 +83,9 @@ func (cache Cache) UpsertIssueStats(repo string, number int, daysOpen, daysWaiti
 	return nil
 }
 
-func (cache Cache) QueryForIssues(qfmt string, a ...any) (*[]Issue, error) {
-	q := fmt.Sprintf(qfmt, a...)
-
-	rows, err := cache.DB.Query(q)
+// QueryForIssues runs a query selecting ColumnsIssues with args for its placeholders.
+func (cache Cache) QueryForIssues(q string, args ...any) (*[]Issue, error) {
+	rows, err := cache.DB.Query(q, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare issue query '%s': %w", q, err)
 	}
@@ -125,12 +124,12 @@ func (cache Cache) QueryForIssues(qfmt string, a ...any) (*[]Issue, error) {
 
 func (cache Cache) GetIssue(repo string, number int) (*Issue, error) {
 	issues, err := cache.QueryForIssues(`
-	SELECT %s 
+	SELECT `+ColumnsIssues+` 
 	FROM issues 
 	WHERE 
-	    repo = '%s' AND
-	    number='%d'
-	`, ColumnsIssues, repo, number)
+	    repo = ? AND
+	    number = ?
+	`, repo, number)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to query for issue %d: %w", number, err)
@@ -144,43 +143,39 @@ func (cache Cache) GetIssue(repo string, number int) (*Issue, error) {
 	return &issue, nil
 }
 
-func (cache Cache) GetAllRepoIssues(repos []string) (*[]Issue, error) {
-	repoClause := ""
-	if len(repos) > 0 {
-		repoClause = " WHERE repo in ('" + strings.Join(repos, "', '") + "')"
-	}
+// issueSQL compiles the filter for the issues table, which has no drafts
+func issueSQL(f Filter) (string, []any) {
+	f.Drafts = nil
+	return f.sql(SyncKindIssues, "")
+}
+
+// GetRepoIssues returns every issue matching the filter.
+func (cache Cache) GetRepoIssues(f Filter) (*[]Issue, error) {
+	where, args := issueSQL(f)
 
 	return cache.QueryForIssues(`
-		SELECT %s FROM prs %s
-	`, ColumnsIssues, repoClause)
+		SELECT `+ColumnsIssues+` FROM issues WHERE `+where, args...)
 }
 
-func (cache Cache) GetRepoIssuesCreatedForDateRange(repos []string, from, to time.Time) (*[]Issue, error) {
-	repoClause := ""
-	if len(repos) > 0 {
-		repoClause = " AND repo in ('" + strings.Join(repos, "', '") + "')"
-	}
+// GetRepoIssuesCreatedForDateRange returns the issues matching the filter created in the range.
+func (cache Cache) GetRepoIssuesCreatedForDateRange(f Filter, from, to time.Time) (*[]Issue, error) {
+	f.CreatedFrom, f.CreatedTo = from, to
 
-	return cache.QueryForIssues(`
-		SELECT %s FROM prs
-		WHERE 
-		    created BETWEEN '%s' AND '%s'
-			%s
-	`, ColumnsIssues, from.Format("2006-01-02"), to.Format("2006-01-02"), repoClause)
+	return cache.GetRepoIssues(f)
 }
 
-func (cache Cache) GetRepoIssuesOpenForDateRange(repos []string, from, to time.Time) (*[]Issue, error) {
-	repoClause := ""
-	if len(repos) > 0 {
-		repoClause = " AND repo in ('" + strings.Join(repos, "', '") + "')"
-	}
+// GetRepoIssuesOpenForDateRange returns the issues matching the filter that were created or closed in the range, or
+// are still open.
+func (cache Cache) GetRepoIssuesOpenForDateRange(f Filter, from, to time.Time) (*[]Issue, error) {
+	where, args := issueSQL(f)
+	start, end := from.Format(filterTimeFormat), to.Format(filterTimeFormat)
 
-	return cache.QueryForIssues(fmt.Sprintf(`
-		SELECT %[1]s  FROM issues
+	return cache.QueryForIssues(`
+		SELECT `+ColumnsIssues+` FROM issues
 		WHERE
-		    (created BETWEEN '%[2]s' AND '%[3]s' OR 
-		    closed BETWEEN '%[2]s' AND '%[3]s' OR
-		    closed < '1977-7-7')
-		    %[4]s
-	`, ColumnsIssues, from.Format("2006-01-02"), to.Format("2006-01-02"), repoClause))
+		    (created BETWEEN ? AND ? OR 
+		    closed BETWEEN ? AND ? OR
+		    closed < '1977-7-7') AND
+		    `+where,
+		append([]any{start, end, start, end}, args...)...)
 }
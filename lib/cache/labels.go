@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RenameLabel carries a label rename through to the cached prs, issues and events so stats keyed on label names keep
+// matching, it returns the numbers of the prs & issues with events for the label.
+func (cache Cache) RenameLabel(repo, from, to string) ([]int, error) {
+	rows, err := cache.DB.Query(`SELECT DISTINCT pr FROM events WHERE repo = ? AND label = ?`, repo, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events labeled %s in %s: %w", from, repo, err)
+	}
+
+	var numbers []int
+	for rows.Next() {
+		var n int
+		if err = rows.Scan(&n); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan events labeled %s in %s: %w", from, repo, err)
+		}
+		numbers = append(numbers, n)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get events labeled %s in %s: %w", from, repo, err)
+	}
+
+	if _, err = cache.DB.Exec(`UPDATE events SET label = ? WHERE repo = ? AND label = ?`, to, repo, from); err != nil {
+		return nil, fmt.Errorf("failed to rename label %s in %s events: %w", from, repo, err)
+	}
+
+	// pr & issue labels are stored comma separated, so the rename has to be done a row at a time
+	for _, table := range []string{"issues", "prs"} {
+		if err = cache.renameLabelIn(table, repo, from, to); err != nil {
+			return nil, err
+		}
+	}
+
+	return numbers, nil
+}
+
+func (cache Cache) renameLabelIn(table, repo, from, to string) error {
+	rows, err := cache.DB.Query(`SELECT number, labels FROM `+table+` WHERE repo = ? AND ',' || labels || ',' LIKE ?`, repo, "%,"+from+",%")
+	if err != nil {
+		return fmt.Errorf("failed to query %s labeled %s in %s: %w", table, from, repo, err)
+	}
+
+	renamed := map[int]string{}
+	for rows.Next() {
+		var n int
+		var labels string
+		if err = rows.Scan(&n, &labels); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan %s labeled %s in %s: %w", table, from, repo, err)
+		}
+
+		names := strings.Split(labels, ",")
+		for i, l := range names {
+			if l == from {
+				names[i] = to
+			}
+		}
+		renamed[n] = strings.Join(names, ",")
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to get %s labeled %s in %s: %w", table, from, repo, err)
+	}
+
+	for n, labels := range renamed {
+		if _, err = cache.DB.Exec(`UPDATE `+table+` SET labels = ? WHERE repo = ? AND number = ?`, labels, repo, n); err != nil {
+			return fmt.Errorf("failed to rename label %s on %s#%d: %w", from, repo, n, err)
+		}
+	}
+
+	return nil
+}
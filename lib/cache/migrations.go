@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	c "github.com/gookit/color" // nolint:misspell
+)
+
+// Migration is a change to the cache's schema, applied once in version order and recorded in schema_version.
+type Migration struct {
+	Version     int
+	Description string
+
+	// up runs in the same transaction as recording the version so a failed step leaves the cache as it was. with a
+	// single connection anything it does through cache.DB would wait on the transaction forever, so use tx
+	up func(Cache, *sql.Tx) error
+}
+
+// migrations are every change made to the schema, in order. only ever append to this. caches made before versioning
+// are at version 0 and have some or all of these already so every step has to be safe to run on a cache that has it
+var migrations = []Migration{
+	{1, "prs, issues & events tables", Cache.createInitialTables},
+	{2, "fetch bookkeeping tables", Cache.createSyncTables},
+	{3, "reviews, files, comments, repos, checks & releases", Cache.createDataTables},
+	{4, "timeline event details", Cache.addEventDetailColumns},
+	{5, "events keyed by id", Cache.rekeyEvents},
+	{6, "projects (v2) tables", Cache.createProjectTables},
+	{7, "issue waiting & first response stats", Cache.addIssueStatsColumns},
+	{8, "events of issues numbered apart from prs", Cache.addEventKindColumn},
+	{9, "pr labels & draft state", Cache.addPRLabelsAndDraftColumns},
+}
+
+// LatestSchemaVersion is the schema version this build migrates caches to.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// SchemaVersion is the version the cache's schema has been migrated to, 0 for a new cache or one made before versioning.
+func (cache Cache) SchemaVersion() (int, error) {
+	var tables int
+	err := cache.DB.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_version'`).Scan(&tables)
+	if err != nil {
+		return 0, fmt.Errorf("failed to look for schema_version table %s: %w", cache.Path, err)
+	}
+	if tables == 0 {
+		return 0, nil
+	}
+
+	var version int
+	if err = cache.DB.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to get schema version %s: %w", cache.Path, err)
+	}
+
+	return version, nil
+}
+
+// PendingMigrations returns the migrations the cache has yet to have applied, a cache made by a newer build is an error
+// as this one does not know what has changed.
+func (cache Cache) PendingMigrations() ([]Migration, error) {
+	version, err := cache.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	if latest := LatestSchemaVersion(); version > latest {
+		return nil, fmt.Errorf("cache %s is at schema version %d but this build only knows up to %d, use a newer build", cache.Path, version, latest)
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if m.Version > version {
+			pending = append(pending, m)
+		}
+	}
+
+	return pending, nil
+}
+
+// Migrate applies every pending migration in order and returns those that were.
+func (cache Cache) Migrate() ([]Migration, error) {
+	pending, err := cache.PendingMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	_, err = cache.DB.Exec(`
+	CREATE TABLE IF NOT EXISTS "schema_version" (
+	    "version" INTEGER NOT NULL,
+	    "description" VARCHAR NOT NULL,
+	    "applied" DATE NOT NULL,
+	    PRIMARY KEY (version)
+	)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema_version table %s: %w", cache.Path, err)
+	}
+
+	for i, m := range pending {
+		c.Printf("  migrating to <white>%d</>: %s...\n", m.Version, m.Description)
+		if err = cache.migrate(m); err != nil {
+			return pending[:i], err
+		}
+	}
+
+	return pending, nil
+}
+
+// migrate applies a migration and records it in one transaction, sqlite's ddl is transactional so a failure part way
+// through rolls back the tables & columns it had already changed.
+func (cache Cache) migrate(m Migration) error {
+	tx, err := cache.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin migrating %s to schema version %d: %w", cache.Path, m.Version, err)
+	}
+	defer tx.Rollback() // nolint:errcheck
+
+	if err = m.up(cache, tx); err != nil {
+		return fmt.Errorf("failed to migrate %s to schema version %d: %w", cache.Path, m.Version, err)
+	}
+
+	_, err = tx.Exec(`INSERT INTO schema_version (version, description, applied) VALUES (?, ?, ?)`, m.Version, m.Description, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record schema version %d of %s: %w", m.Version, cache.Path, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit schema version %d of %s: %w", m.Version, cache.Path, err)
+	}
+
+	return nil
+}
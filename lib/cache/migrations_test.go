@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openTestCache(t *testing.T) *Cache {
+	t.Helper()
+
+	cache, err := Open(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatalf("opening cache: %v", err)
+	}
+	t.Cleanup(func() { cache.DB.Close() })
+
+	return cache
+}
+
+func TestMigrateToLatest(t *testing.T) {
+	cache := openTestCache(t)
+
+	version, err := cache.SchemaVersion()
+	if err != nil {
+		t.Fatalf("getting schema version: %v", err)
+	}
+	if version != LatestSchemaVersion() {
+		t.Fatalf("expected schema version %d, got %d", LatestSchemaVersion(), version)
+	}
+
+	// migrating again is a no-op
+	applied, err := cache.Migrate()
+	if err != nil {
+		t.Fatalf("migrating again: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Fatalf("expected no migrations to apply, got %d", len(applied))
+	}
+}
+
+func TestMigrateRollsBackFailedStep(t *testing.T) {
+	cache := openTestCache(t)
+
+	latest := LatestSchemaVersion()
+	failing := Migration{latest + 1, "fails part way", func(cache Cache, tx *sql.Tx) error {
+		if err := cache.ensureColumn(tx, "prs", "half_done", "INTEGER"); err != nil {
+			return err
+		}
+		return errors.New("boom")
+	}}
+
+	defer func(m []Migration) { migrations = m }(migrations)
+	migrations = append(migrations, failing)
+
+	applied, err := cache.Migrate()
+	if err == nil {
+		t.Fatalf("expected the failing migration to error")
+	}
+	if len(applied) != 0 {
+		t.Fatalf("expected no migrations to be applied, got %d", len(applied))
+	}
+
+	version, err := cache.SchemaVersion()
+	if err != nil {
+		t.Fatalf("getting schema version: %v", err)
+	}
+	if version != latest {
+		t.Fatalf("expected schema version to stay at %d, got %d", latest, version)
+	}
+
+	tx, err := cache.DB.Begin()
+	if err != nil {
+		t.Fatalf("beginning transaction: %v", err)
+	}
+	defer tx.Rollback() // nolint:errcheck
+
+	has, err := cache.hasColumn(tx, "prs", "half_done")
+	if err != nil {
+		t.Fatalf("checking for column: %v", err)
+	}
+	if has {
+		t.Fatalf("expected the column added by the failed migration to be rolled back")
+	}
+}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/v45/github"
+)
+
+type PRFile struct {
+	Repo      string
+	PR        int
+	Path      string
+	Status    string // added, removed, modified, renamed, copied, changed
+	Additions int
+	Deletions int
+}
+
+// ReplacePRFilesFromGH replaces the files stored for a PR, a force push can drop files so they are not upserted.
+func (cache Cache) ReplacePRFilesFromGH(repo string, pr int, files []github.CommitFile) error {
+	tx, err := cache.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for pr files %s#%d: %w", repo, pr, err)
+	}
+	defer tx.Rollback() // nolint:errcheck
+
+	if _, err = tx.Exec(`DELETE FROM pr_files WHERE repo = ? AND pr = ?`, repo, pr); err != nil {
+		return fmt.Errorf("failed to clear pr files %s#%d: %w", repo, pr, err)
+	}
+
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO pr_files (repo, pr, path, status, additions, deletions) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement for pr files %s#%d: %w", repo, pr, err)
+	}
+	defer stmt.Close()
+
+	for _, f := range files {
+		if _, err = stmt.Exec(repo, pr, f.GetFilename(), f.GetStatus(), f.GetAdditions(), f.GetDeletions()); err != nil {
+			return fmt.Errorf("failed to insert pr file %s#%d/%s: %w", repo, pr, f.GetFilename(), err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit pr files %s#%d: %w", repo, pr, err)
+	}
+
+	return nil
+}
+
+func (cache Cache) GetPRFilesFor(repo string, number int) ([]PRFile, error) {
+	rows, err := cache.DB.Query(`
+		SELECT repo, pr, path, status, additions, deletions 
+		FROM pr_files 
+		WHERE
+			repo = ? AND
+			pr = ?
+		ORDER BY path
+	`, repo, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query files for pr %d: %w", number, err)
+	}
+	defer rows.Close()
+
+	var files []PRFile
+	for rows.Next() {
+		f := PRFile{}
+		if err = rows.Scan(&f.Repo, &f.PR, &f.Path, &f.Status, &f.Additions, &f.Deletions); err != nil {
+			return nil, fmt.Errorf("failed to scan files for pr %d: %w", number, err)
+		}
+
+		files = append(files, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get files for pr %d: %w", number, err)
+	}
+
+	return files, nil
+}
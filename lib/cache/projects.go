@@ -0,0 +1,255 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ProjectNoValue is what an item without a value for a field is shown as
+const ProjectNoValue = "(none)"
+
+// ProjectItem is an item on a projects (v2) board, a linked issue or pr or a draft.
+type ProjectItem struct {
+	ID       string
+	Type     string // ISSUE, PULL_REQUEST, DRAFT_ISSUE or REDACTED
+	Repo     string // the repo's cache key, empty for drafts
+	Number   int
+	Title    string
+	Archived bool
+	Updated  time.Time
+
+	// Fields is the value of each set field by name
+	Fields map[string]string
+}
+
+// UpsertProjectItem stores an item of a project, item.Repo should already be the repo's cache key. archived items are
+// off the board so are marked removed as of when they were archived.
+func (cache Cache) UpsertProjectItem(project string, item ProjectItem) error {
+	var removed sql.NullTime
+	if item.Archived {
+		removed = sql.NullTime{Time: item.Updated, Valid: true}
+	}
+
+	_, err := cache.DB.Exec(`
+		INSERT INTO project_items (project, id, type, repo, number, title, archived, updated, removed)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (project, id) DO UPDATE SET
+			type = excluded.type,
+			repo = excluded.repo,
+			number = excluded.number,
+			title = excluded.title,
+			archived = excluded.archived,
+			updated = excluded.updated,
+			removed = CASE WHEN excluded.archived THEN COALESCE(project_items.removed, excluded.removed) ELSE NULL END
+	`, project, item.ID, item.Type, item.Repo, item.Number, item.Title, item.Archived, item.Updated, removed)
+	if err != nil {
+		return fmt.Errorf("failed to upsert project item %s %s: %w", project, item.ID, err)
+	}
+
+	return nil
+}
+
+// RecordProjectFieldValues adds a row for every field of the item whose value differs from the last one stored,
+// including fields that have been cleared. only the item's updated time is known so that is when they changed.
+func (cache Cache) RecordProjectFieldValues(project string, item ProjectItem) (int, error) {
+	rows, err := cache.DB.Query(`
+		SELECT field, value
+		FROM project_field_values
+		WHERE project = ? AND item = ?
+		ORDER BY since
+	`, project, item.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query field values of project item %s %s: %w", project, item.ID, err)
+	}
+
+	last := map[string]sql.NullString{}
+	for rows.Next() {
+		var field string
+		var value sql.NullString
+		if err = rows.Scan(&field, &value); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan field values of project item %s %s: %w", project, item.ID, err)
+		}
+		last[field] = value
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to get field values of project item %s %s: %w", project, item.ID, err)
+	}
+
+	changes := map[string]sql.NullString{}
+	for field, value := range item.Fields {
+		if v, ok := last[field]; !ok || !v.Valid || v.String != value {
+			changes[field] = sql.NullString{String: value, Valid: true}
+		}
+	}
+	for field, v := range last {
+		if _, ok := item.Fields[field]; !ok && v.Valid {
+			changes[field] = sql.NullString{}
+		}
+	}
+
+	for field, value := range changes {
+		_, err = cache.DB.Exec(`
+			INSERT OR REPLACE INTO project_field_values (project, item, field, value, since)
+			VALUES (?, ?, ?, ?, ?)
+		`, project, item.ID, field, value, item.Updated)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert field %s of project item %s %s: %w", field, project, item.ID, err)
+		}
+	}
+
+	return len(changes), nil
+}
+
+// MarkProjectItemsRemoved marks every item of the project still on the board that was not seen as removed at.
+func (cache Cache) MarkProjectItemsRemoved(project string, seen map[string]bool, at time.Time) (int, error) {
+	rows, err := cache.DB.Query(`SELECT id FROM project_items WHERE project = ? AND removed IS NULL`, project)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query items of project %s: %w", project, err)
+	}
+
+	var gone []string
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan items of project %s: %w", project, err)
+		}
+		if !seen[id] {
+			gone = append(gone, id)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to get items of project %s: %w", project, err)
+	}
+
+	for _, id := range gone {
+		if _, err = cache.DB.Exec(`UPDATE project_items SET removed = ? WHERE project = ? AND id = ?`, at, project, id); err != nil {
+			return 0, fmt.Errorf("failed to mark project item %s %s removed: %w", project, id, err)
+		}
+	}
+
+	return len(gone), nil
+}
+
+// ProjectFieldSpan is a value a project item's field had from Since until Until, which is zero while it still has it.
+type ProjectFieldSpan struct {
+	Item  string
+	Title string
+	Value string
+	Since time.Time
+	Until time.Time
+}
+
+// GetProjectFieldHistory returns the spans of every value each item of the project has had for field, ending them
+// when the item left the board. items never given a value for the field are left out.
+func (cache Cache) GetProjectFieldHistory(project, field string) ([]ProjectFieldSpan, error) {
+	rows, err := cache.DB.Query(`
+		SELECT v.item, COALESCE(i.title, ''), v.value, v.since, i.removed
+		FROM project_field_values v
+		JOIN project_items i ON i.project = v.project AND i.id = v.item
+		WHERE v.project = ? AND v.field = ?
+		ORDER BY v.item, v.since
+	`, project, field)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s history of project %s: %w", field, project, err)
+	}
+	defer rows.Close()
+
+	var spans []ProjectFieldSpan
+	var removed []sql.NullTime
+	for rows.Next() {
+		s := ProjectFieldSpan{}
+		var value sql.NullString
+		var r sql.NullTime
+		if err = rows.Scan(&s.Item, &s.Title, &value, &s.Since, &r); err != nil {
+			return nil, fmt.Errorf("failed to scan %s history of project %s: %w", field, project, err)
+		}
+
+		s.Value = ProjectNoValue
+		if value.Valid {
+			s.Value = value.String
+		}
+
+		// the previous value of the same item lasted until this one
+		if n := len(spans); n > 0 && spans[n-1].Item == s.Item {
+			spans[n-1].Until = s.Since
+		}
+
+		spans = append(spans, s)
+		removed = append(removed, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get %s history of project %s: %w", field, project, err)
+	}
+
+	for i, r := range removed {
+		if r.Valid && spans[i].Until.IsZero() {
+			spans[i].Until = r.Time
+		}
+	}
+
+	return spans, nil
+}
+
+type ProjectColumnStats struct {
+	Value string
+
+	// Items is how many items have ever had the value and Current how many still do
+	Items   int
+	Current int
+
+	// DaysAverage is how long items spent with the value, counting those still with it up until now
+	DaysAverage        float64
+	CurrentDaysAverage float64
+}
+
+// CalculateProjectColumnStats returns how long items sit with each value of field, ie each column of a board.
+func (cache Cache) CalculateProjectColumnStats(project, field string, now time.Time) ([]ProjectColumnStats, error) {
+	spans, err := cache.GetProjectFieldHistory(project, field)
+	if err != nil {
+		return nil, err
+	}
+
+	byValue := map[string]*ProjectColumnStats{}
+	for _, s := range spans {
+		cs, ok := byValue[s.Value]
+		if !ok {
+			cs = &ProjectColumnStats{Value: s.Value}
+			byValue[s.Value] = cs
+		}
+
+		until := s.Until
+		if until.IsZero() {
+			until = now
+		}
+		days := until.Sub(s.Since).Hours() / 24
+
+		cs.Items++
+		cs.DaysAverage += days
+		if s.Until.IsZero() {
+			cs.Current++
+			cs.CurrentDaysAverage += days
+		}
+	}
+
+	stats := make([]ProjectColumnStats, 0, len(byValue))
+	for _, cs := range byValue {
+		cs.DaysAverage /= float64(cs.Items)
+		if cs.Current > 0 {
+			cs.CurrentDaysAverage /= float64(cs.Current)
+		}
+		stats = append(stats, *cs)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Value < stats[j].Value
+	})
+
+	return stats, nil
+}
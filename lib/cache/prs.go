@@ -8,11 +8,13 @@ import (
 	"time"
 
 	"github.com/google/go-github/v45/github"
+	"github.com/katbyte/gogo-repo-stats/lib/gh"
+	"github.com/katbyte/gogo-repo-stats/lib/provider"
 )
 
 // TODO switch to an ORM ?
 
-const ColumnsPR = "repo, number, title, user, state, milestone, merged, merger, created, closed, daysopen, dayswaiting, daystofirst"
+const ColumnsPR = "repo, number, title, user, state, milestone, COALESCE(labels, ''), COALESCE(draft, 0), merged, merger, created, closed, daysopen, dayswaiting, daystofirst, additions, deletions, changed_files, commits"
 
 type PR struct {
 	Repo      string
@@ -21,6 +23,8 @@ type PR struct {
 	User      string
 	State     string // todo should we make this boolean "open" or 2 boolean so we have open/closed/merged ?
 	Milestone string
+	Labels    string // comma separated, empty for prs cached before these were captured
+	Draft     bool
 	Merged    bool
 	Merger    string
 	Created   time.Time
@@ -30,31 +34,66 @@ type PR struct {
 	DaysOpen    sql.NullFloat64
 	DaysWaiting sql.NullFloat64
 	DaysToFirst sql.NullFloat64
+
+	// size, null for prs cached before these were captured
+	Additions    sql.NullInt64
+	Deletions    sql.NullInt64
+	ChangedFiles sql.NullInt64
+	Commits      sql.NullInt64
 }
 
 func (cache Cache) UpsertRepoPRFromGH(repo string, pr *github.PullRequest) error {
+	return cache.UpsertRepoPR(repo, gh.NormalizePR(pr))
+}
+
+// UpsertRepoPR stores a pr, only updating the fetched columns of one already cached so the stats & release computed
+// for it are kept. sizes are only known when a pr is fetched on its own so a listing does not clear them.
+func (cache Cache) UpsertRepoPR(repo string, pr provider.PR) error {
 	stmt, err := cache.DB.Prepare(`
-		INSERT OR REPLACE INTO prs (repo, number, title, user, state, milestone, merged, merger, created, closed ) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO prs (repo, number, title, user, state, milestone, labels, draft, merged, merger, created, closed, additions, deletions, changed_files, commits, mergesha) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (repo, number) DO UPDATE SET
+			title = excluded.title,
+			user = excluded.user,
+			state = excluded.state,
+			milestone = excluded.milestone,
+			labels = excluded.labels,
+			draft = excluded.draft,
+			merged = excluded.merged,
+			merger = excluded.merger,
+			created = excluded.created,
+			closed = excluded.closed,
+			additions = COALESCE(excluded.additions, prs.additions),
+			deletions = COALESCE(excluded.deletions, prs.deletions),
+			changed_files = COALESCE(excluded.changed_files, prs.changed_files),
+			commits = COALESCE(excluded.commits, prs.commits),
+			mergesha = COALESCE(excluded.mergesha, prs.mergesha)
 	`)
 	if err != nil {
-		return fmt.Errorf("failed to prepare insert statement for pr %d: %w", pr.GetNumber(), err)
+		return fmt.Errorf("failed to prepare insert statement for pr %d: %w", pr.Number, err)
 	}
 
 	_, err = stmt.Exec(
 		repo,
-		strconv.Itoa(pr.GetNumber()),
-		pr.GetTitle(),
-		pr.User.GetLogin(),
-		pr.GetState(),
-		pr.GetMilestone().GetTitle(),
-		strconv.FormatBool(pr.GetMerged()),
-		pr.MergedBy.GetLogin(),
-		pr.GetCreatedAt(),
-		pr.GetClosedAt(),
+		strconv.Itoa(pr.Number),
+		pr.Title,
+		pr.User,
+		pr.State,
+		pr.Milestone,
+		strings.Join(pr.Labels, ","),
+		pr.Draft,
+		strconv.FormatBool(pr.Merged),
+		pr.Merger,
+		pr.Created,
+		pr.Closed,
+		pr.Additions,
+		pr.Deletions,
+		pr.ChangedFiles,
+		pr.Commits,
+		sql.NullString{String: pr.MergeSHA, Valid: pr.MergeSHA != ""},
 	)
 	if err != nil {
-		return fmt.Errorf("failed to insert pr %s#%d: %w", repo, pr.GetNumber(), err)
+		return fmt.Errorf("failed to insert pr %s#%d: %w", repo, pr.Number, err)
 	}
 	stmt.Close()
 
@@ -83,10 +122,9 @@ func (cache Cache) UpsertPRStats(repo string, number int, daysOpen, daysWaiting,
 	return nil
 }
 
-func (cache Cache) QueryForPRs(qfmt string, a ...any) (*[]PR, error) {
-	q := fmt.Sprintf(qfmt, a...)
-
-	rows, err := cache.DB.Query(q)
+// QueryForPRs runs a query selecting ColumnsPR with args for its placeholders.
+func (cache Cache) QueryForPRs(q string, args ...any) (*[]PR, error) {
+	rows, err := cache.DB.Query(q, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare query '%s': %w", q, err)
 	}
@@ -108,6 +146,8 @@ func (cache Cache) QueryForPRs(qfmt string, a ...any) (*[]PR, error) {
 			&pr.User,
 			&pr.State,
 			&pr.Milestone,
+			&pr.Labels,
+			&pr.Draft,
 			&pr.Merged,
 			&pr.Merger,
 			&pr.Created,
@@ -115,6 +155,10 @@ func (cache Cache) QueryForPRs(qfmt string, a ...any) (*[]PR, error) {
 			&pr.DaysOpen,
 			&pr.DaysWaiting,
 			&pr.DaysToFirst,
+			&pr.Additions,
+			&pr.Deletions,
+			&pr.ChangedFiles,
+			&pr.Commits,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan pr: %w", err)
@@ -128,76 +172,52 @@ func (cache Cache) QueryForPRs(qfmt string, a ...any) (*[]PR, error) {
 
 func (cache Cache) GetPR(repo string, number int) (*PR, error) {
 	prs, err := cache.QueryForPRs(`
-	SELECT %s 
+	SELECT `+ColumnsPR+` 
 	FROM prs 
 	WHERE 
-	    repo = '%s' AND
-	    number='%d'
-	`, ColumnsPR, repo, number)
+	    repo = ? AND
+	    number = ?
+	`, repo, number)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to query for pr %d: %w", number, err)
 	}
 
 	if len(*prs) != 1 {
-		return nil, fmt.Errorf("didn't find a single pr: %d", len(*prs))
+		return nil, nil
 	}
 
 	pr := (*prs)[0]
 	return &pr, nil
 }
 
-func (cache Cache) GetAllRepoPRs(repos []string) (*[]PR, error) {
-	repoClause := ""
-	if len(repos) > 0 {
-		repoClause = " WHERE repo in ('" + strings.Join(repos, "', '") + "')"
-	}
+// GetRepoPRs returns every pr matching the filter.
+func (cache Cache) GetRepoPRs(f Filter) (*[]PR, error) {
+	where, args := f.sql(SyncKindPRs, "")
 
 	return cache.QueryForPRs(`
-		SELECT %s FROM prs %s
-	`, ColumnsPR, repoClause)
+		SELECT `+ColumnsPR+` FROM prs WHERE `+where, args...)
 }
 
-func (cache Cache) GetRepoPRsWithState(repos []string, state string) (*[]PR, error) {
-	repoClause := ""
-	if len(repos) > 0 {
-		repoClause = " AND repo in ('" + strings.Join(repos, "', '") + "')"
-	}
+// GetRepoPRsCreatedForDateRange returns the prs matching the filter created in the range.
+func (cache Cache) GetRepoPRsCreatedForDateRange(f Filter, from, to time.Time) (*[]PR, error) {
+	f.CreatedFrom, f.CreatedTo = from, to
 
-	return cache.QueryForPRs(`
-		SELECT %s FROM prs 
-		WHERE
-		    state='%s'
-			%s
-		`, ColumnsPR, state, repoClause)
+	return cache.GetRepoPRs(f)
 }
 
-func (cache Cache) GetRepoPRsCreatedForDateRange(repos []string, from, to time.Time) (*[]PR, error) {
-	repoClause := ""
-	if len(repos) > 0 {
-		repoClause = " AND repo in ('" + strings.Join(repos, "', '") + "')"
-	}
+// GetRepoPRsOpenForDateRange returns the prs matching the filter that were created or closed in the range, or are
+// still open.
+func (cache Cache) GetRepoPRsOpenForDateRange(f Filter, from, to time.Time) (*[]PR, error) {
+	where, args := f.sql(SyncKindPRs, "")
+	start, end := from.Format(filterTimeFormat), to.Format(filterTimeFormat)
 
 	return cache.QueryForPRs(`
-		SELECT %s FROM prs
-		WHERE 
-		    created BETWEEN '%s' AND '%s'
-			%s
-	`, ColumnsPR, from.Format("2006-01-02"), to.Format("2006-01-02"), repoClause)
-}
-
-func (cache Cache) GetRepoPRsOpenForDateRange(repos []string, from, to time.Time) (*[]PR, error) {
-	repoClause := ""
-	if len(repos) > 0 {
-		repoClause = " AND repo in ('" + strings.Join(repos, "', '") + "')"
-	}
-
-	return cache.QueryForPRs(fmt.Sprintf(`
-		SELECT %[1]s  FROM prs
+		SELECT `+ColumnsPR+` FROM prs
 		WHERE
-		    (created BETWEEN '%[2]s' AND '%[3]s' OR 
-		    closed BETWEEN '%[2]s' AND '%[3]s' OR 
-		    closed < '1977-7-7')
-		    %[4]s
-	`, ColumnsPR, from.Format("2006-01-02"), to.Format("2006-01-02"), repoClause))
+		    (created BETWEEN ? AND ? OR 
+		    closed BETWEEN ? AND ? OR 
+		    closed < '1977-7-7') AND
+		    `+where,
+		append([]any{start, end, start, end}, args...)...)
 }
@@ -3,6 +3,7 @@ package cache
 import (
 	"fmt"
 	"math"
+	"strings"
 	"time"
 
 	c "github.com/gookit/color" // nolint:misspell
@@ -15,6 +16,9 @@ func (cache Cache) ComputeAndUpdatePRStats(repo string, number int) (open, waiti
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("failed to get pr %d: %w", number, err)
 	}
+	if pr == nil {
+		return nil, nil, nil, fmt.Errorf("pr %d is not cached", number)
+	}
 
 	// get events
 	events, err := cache.GetEventsFor(repo, number)
@@ -114,6 +118,36 @@ func (cache Cache) ComputeAndUpdatePRStats(repo string, number int) (open, waiti
 			break
 		}
 	}
+
+	// a review by someone other than the author is also an action, the timeline can miss these so use the reviews table
+	reviews, err := cache.GetReviewsFor(repo, number)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("getting reviews for PR %d: %w", number, err)
+	}
+	for _, r := range reviews {
+		if r.User == pr.User {
+			continue
+		}
+
+		if d := r.Submitted.Sub(pr.Created); duration == 0 || d < duration {
+			duration = d
+			clog.Log.Debugf(c.Sprintf("      first: %s review @ %s\n", strings.ToLower(r.State), r.Submitted.Format("2006-01-02")))
+		}
+		break
+	}
+
+	// as is a maintainer commenting, for repos that don't label or milestone
+	comment, err := cache.GetFirstMaintainerCommentFor(repo, number, pr.User)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if comment != nil {
+		if d := comment.Created.Sub(pr.Created); duration == 0 || d < duration {
+			duration = d
+			clog.Log.Debugf(c.Sprintf("      first: %s comment @ %s\n", strings.ToLower(comment.Association), comment.Created.Format("2006-01-02")))
+		}
+	}
+
 	if duration == 0 {
 		// if closed uses closed, if open used open
 		if pr.State == "closed" {
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// prs are bucketed by lines changed (additions + deletions), the same way the common size labelers do it
+
+var PRSizes = []string{"XS", "S", "M", "L", "XL"}
+
+// prSizeLimits are the exclusive upper bounds of every size but XL
+var prSizeLimits = []int64{10, 50, 250, 1000}
+
+// PRSizeFor returns the size bucket for the given number of lines changed.
+func PRSizeFor(lines int64) string {
+	for i, limit := range prSizeLimits {
+		if lines < limit {
+			return PRSizes[i]
+		}
+	}
+	return PRSizes[len(PRSizes)-1]
+}
+
+type PRSizeStats struct {
+	Size  string
+	Total int
+	Open  int
+
+	LinesAverage       sql.NullFloat64
+	FilesAverage       sql.NullFloat64
+	DaysOpenAverage    sql.NullFloat64
+	DaysToFirstAverage sql.NullFloat64
+}
+
+// CalculateRepoPRSizeStatsForDateRange returns stats for every size bucket, smallest first, of the prs matching the
+// filter created in the range. prs without a size are left out.
+func (cache Cache) CalculateRepoPRSizeStatsForDateRange(from, to time.Time, f Filter) ([]PRSizeStats, error) {
+	f.CreatedFrom, f.CreatedTo = from, to
+	where, args := f.sql(SyncKindPRs, "")
+
+	sizeCase := "CASE"
+	for i, limit := range prSizeLimits {
+		sizeCase += fmt.Sprintf(" WHEN additions + deletions < %d THEN '%s'", limit, PRSizes[i])
+	}
+	sizeCase += fmt.Sprintf(" ELSE '%s' END", PRSizes[len(PRSizes)-1])
+
+	q := fmt.Sprintf(`
+		SELECT
+			%s as size,
+			COUNT(*) as total,
+			COUNT(CASE WHEN state = 'open'  THEN 1 END) as open,
+			AVG(additions + deletions) as linesAvg,
+			AVG(changed_files) as filesAvg,
+			AVG(daysopen) as openAvg,
+			AVG(daystofirst) as firstAvg
+		FROM prs
+		WHERE 
+		    additions IS NOT NULL AND deletions IS NOT NULL AND
+		    %s
+		GROUP BY size
+	`, sizeCase, where)
+	rows, err := cache.DB.Query(q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pr size stats: %w", err)
+	}
+	defer rows.Close()
+
+	bySize := map[string]PRSizeStats{}
+	for rows.Next() {
+		r := PRSizeStats{}
+		err := rows.Scan(
+			&r.Size,
+			&r.Total,
+			&r.Open,
+			&r.LinesAverage,
+			&r.FilesAverage,
+			&r.DaysOpenAverage,
+			&r.DaysToFirstAverage,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pr size stats: %w", err)
+		}
+		bySize[r.Size] = r
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get pr size stats: %w", err)
+	}
+
+	// always return every bucket so tables & graphs line up
+	stats := make([]PRSizeStats, 0, len(PRSizes))
+	for _, size := range PRSizes {
+		s := bySize[size]
+		s.Size = size
+		stats = append(stats, s)
+	}
+
+	return stats, nil
+}
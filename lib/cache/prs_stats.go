@@ -3,7 +3,6 @@ package cache
 import (
 	"database/sql"
 	"fmt"
-	"strings"
 	"time"
 )
 
@@ -18,21 +17,25 @@ type PRsStats struct {
 	DaysToFirstAverage sql.NullFloat64
 
 	DaysToFirstOver int
-}
 
-func (cache Cache) CalculateRepoPRStatsForDateRange(from, to time.Time, repos []string, authors []string) (*PRsStats, error) {
-	authorClause := ""
-	if len(authors) > 0 {
-		authorClause = " AND user in ('" + strings.Join(authors, "', '") + "')"
-	}
+	// ci, only for prs fetched with their checks
+	DaysToGreenAverage sql.NullFloat64
+	MergedFailing      int
+	Flaky              int
 
-	repoClause := ""
-	if len(repos) > 0 {
-		repoClause = " AND repo in ('" + strings.Join(repos, "', '") + "')"
-	}
+	// lead time from opened & merged to the first release that shipped the pr
+	Released               int
+	DaysToReleaseAverage   sql.NullFloat64
+	MergedToReleaseAverage sql.NullFloat64
+}
+
+// CalculateRepoPRStatsForDateRange returns stats of the prs matching the filter created in the range.
+func (cache Cache) CalculateRepoPRStatsForDateRange(from, to time.Time, f Filter) (*PRsStats, error) {
+	f.CreatedFrom, f.CreatedTo = from, to
+	where, args := f.sql(SyncKindPRs, "")
 
 	// COUNT(case WHEN merged is 'true' THEN 1 END) as merged,
-	q := fmt.Sprintf(`
+	q := `
 		SELECT
 			COUNT(*) as total,
 			COUNT(CASE WHEN state = 'open'  THEN 1 END) as open,
@@ -41,12 +44,16 @@ func (cache Cache) CalculateRepoPRStatsForDateRange(from, to time.Time, repos []
 			AVG(daysopen) as openAvg,
 			AVG(dayswaiting) as waitAvg,
 			AVG(daystofirst) as firstAvg,
-			COUNT(CASE WHEN daystofirst  > 14 THEN 1 END) as firstGreaterThen
+			COUNT(CASE WHEN daystofirst  > 14 THEN 1 END) as firstGreaterThen,
+			AVG(daystogreen) as greenAvg,
+			COUNT(CASE WHEN mergedfailing = 1 THEN 1 END) as mergedFailing,
+			COUNT(CASE WHEN flakychecks > 0 THEN 1 END) as flaky,
+			COUNT(release) as released,
+			AVG(daystorelease) as releaseAvg,
+			AVG(mergedtorelease) as mergedReleaseAvg
 		FROM prs
-		WHERE 
-		    created BETWEEN '%s' AND '%s' %s %s
-	`, from.Format("2006-01-02 15:04:05"), to.Format("2006-01-02 15:04:05"), authorClause, repoClause)
-	row := cache.DB.QueryRow(q)
+		WHERE ` + where
+	row := cache.DB.QueryRow(q, args...)
 
 	r := PRsStats{}
 	err := row.Scan(
@@ -58,6 +65,12 @@ func (cache Cache) CalculateRepoPRStatsForDateRange(from, to time.Time, repos []
 		&r.DaysWaitingAverage,
 		&r.DaysToFirstAverage,
 		&r.DaysToFirstOver,
+		&r.DaysToGreenAverage,
+		&r.MergedFailing,
+		&r.Flaky,
+		&r.Released,
+		&r.DaysToReleaseAverage,
+		&r.MergedToReleaseAverage,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare query: %w", err)
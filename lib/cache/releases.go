@@ -0,0 +1,263 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+type Release struct {
+	Repo       string
+	ID         int64
+	Tag        string
+	Name       string
+	Prerelease bool
+	Created    time.Time
+	Published  time.Time
+	Body       string
+}
+
+// release notes reference prs as #123 or by their url, generated notes use the url
+var releasePRRefRegex = regexp.MustCompile(`(?:^|[^\w/&])#(\d+)\b|/pull/(\d+)\b`)
+
+// ReferencedPRs returns the pr (or issue, they share numbers) numbers mentioned in the release notes.
+func (r Release) ReferencedPRs() []int {
+	var numbers []int
+	seen := map[int]bool{}
+	for _, m := range releasePRRefRegex.FindAllStringSubmatch(r.Body, -1) {
+		s := m[1]
+		if s == "" {
+			s = m[2]
+		}
+
+		n, err := strconv.Atoi(s)
+		if err != nil || seen[n] {
+			continue
+		}
+		seen[n] = true
+		numbers = append(numbers, n)
+	}
+
+	return numbers
+}
+
+// ReplaceReleasesFromGH replaces the stored releases of a repo, so deleted releases drop out.
+func (cache Cache) ReplaceReleasesFromGH(repo string, releases []github.RepositoryRelease) error {
+	tx, err := cache.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for releases of %s: %w", repo, err)
+	}
+	defer tx.Rollback() // nolint:errcheck
+
+	if _, err = tx.Exec(`DELETE FROM releases WHERE repo = ?`, repo); err != nil {
+		return fmt.Errorf("failed to clear releases of %s: %w", repo, err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT OR REPLACE INTO releases (repo, id, tag, name, prerelease, created, published, body) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement for releases of %s: %w", repo, err)
+	}
+	defer stmt.Close()
+
+	for _, r := range releases {
+		_, err = stmt.Exec(
+			repo,
+			r.GetID(),
+			r.GetTagName(),
+			r.GetName(),
+			r.GetPrerelease(),
+			r.GetCreatedAt().Time,
+			r.GetPublishedAt().Time,
+			r.GetBody(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert release %s@%s: %w", repo, r.GetTagName(), err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit releases of %s: %w", repo, err)
+	}
+
+	return nil
+}
+
+// GetReleases returns the releases of a repo in the order they were published.
+func (cache Cache) GetReleases(repo string) ([]Release, error) {
+	rows, err := cache.DB.Query(`
+		SELECT repo, id, tag, name, prerelease, created, published, body 
+		FROM releases 
+		WHERE repo = ? 
+		ORDER BY published
+	`, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query releases of %s: %w", repo, err)
+	}
+	defer rows.Close()
+
+	var releases []Release
+	for rows.Next() {
+		r := Release{}
+		err = rows.Scan(&r.Repo, &r.ID, &r.Tag, &r.Name, &r.Prerelease, &r.Created, &r.Published, &r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan releases of %s: %w", repo, err)
+		}
+
+		releases = append(releases, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get releases of %s: %w", repo, err)
+	}
+
+	return releases, nil
+}
+
+// UnreleasedPR is a merged pr that has not been mapped to a release yet.
+type UnreleasedPR struct {
+	Number   int
+	MergeSHA string
+	Created  time.Time
+	Merged   time.Time
+
+	// Checked is when the last release compared against it was published, zero if none have been
+	Checked time.Time
+}
+
+// GetUnreleasedMergedPRs returns the merged prs of a repo without a release, oldest merge first.
+func (cache Cache) GetUnreleasedMergedPRs(repo string) ([]UnreleasedPR, error) {
+	rows, err := cache.DB.Query(`
+		SELECT number, COALESCE(mergesha, ''), created, closed, releasechecked 
+		FROM prs 
+		WHERE
+			repo = ? AND
+			merger != '' AND
+			release IS NULL
+		ORDER BY closed
+	`, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unreleased prs of %s: %w", repo, err)
+	}
+	defer rows.Close()
+
+	var prs []UnreleasedPR
+	for rows.Next() {
+		pr := UnreleasedPR{}
+		var checked sql.NullTime
+		if err = rows.Scan(&pr.Number, &pr.MergeSHA, &pr.Created, &pr.Merged, &checked); err != nil {
+			return nil, fmt.Errorf("failed to scan unreleased prs of %s: %w", repo, err)
+		}
+		pr.Checked = checked.Time
+
+		prs = append(prs, pr)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get unreleased prs of %s: %w", repo, err)
+	}
+
+	return prs, nil
+}
+
+// UpdatePRRelease records the release a pr first shipped in along with its lead times.
+func (cache Cache) UpdatePRRelease(repo string, pr UnreleasedPR, release Release) error {
+	days := func(from time.Time) float64 {
+		d := release.Published.Sub(from).Hours() / 24
+		return math.Max(0, math.Floor(d*100)/100)
+	}
+
+	_, err := cache.DB.Exec(`
+		UPDATE prs 
+		SET release = ?,
+		    released = ?,
+		    daystorelease = ?,
+		    mergedtorelease = ?
+		WHERE
+		    repo=? AND
+			number=?;
+	`, release.Tag, release.Published, days(pr.Created), days(pr.Merged), repo, pr.Number)
+	if err != nil {
+		return fmt.Errorf("failed to update release for pr %s#%d: %w", repo, pr.Number, err)
+	}
+
+	return nil
+}
+
+// UpdatePRReleaseChecked records that every release published up to checked has been compared against a pr and did
+// not contain it.
+func (cache Cache) UpdatePRReleaseChecked(repo string, number int, checked time.Time) error {
+	_, err := cache.DB.Exec(`UPDATE prs SET releasechecked = ? WHERE repo = ? AND number = ?`, checked, repo, number)
+	if err != nil {
+		return fmt.Errorf("failed to update release checked for pr %s#%d: %w", repo, number, err)
+	}
+
+	return nil
+}
+
+type ReleaseStats struct {
+	Repo      string
+	Tag       string
+	Published time.Time
+	PRs       int
+
+	DaysToReleaseAverage   sql.NullFloat64
+	MergedToReleaseAverage sql.NullFloat64
+}
+
+// CalculateReleaseStatsForDateRange returns the lead times of the prs matching the filter shipped in each release of
+// the filter's repos published in the range.
+func (cache Cache) CalculateReleaseStatsForDateRange(from, to time.Time, f Filter) ([]ReleaseStats, error) {
+	// the filter picks the prs counted, releases without any still show so only the repos apply to them
+	prs, args := f.sql(SyncKindPRs, "p")
+	repos, repoArgs := Filter{Repos: f.Repos}.sql("", "r")
+	args = append(args, from.Format(filterTimeFormat), to.Format(filterTimeFormat))
+	args = append(args, repoArgs...)
+
+	q := `
+		SELECT
+			r.repo,
+			r.tag,
+			r.published,
+			COUNT(p.number) as prs,
+			AVG(p.daystorelease) as releaseAvg,
+			AVG(p.mergedtorelease) as mergedAvg
+		FROM releases r
+		LEFT JOIN prs p ON p.repo = r.repo AND p.release = r.tag AND ` + prs + `
+		WHERE 
+		    r.published BETWEEN ? AND ? AND ` + repos + `
+		GROUP BY r.repo, r.tag, r.published
+	`
+	rows, err := cache.DB.Query(q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query release stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []ReleaseStats
+	for rows.Next() {
+		s := ReleaseStats{}
+		if err = rows.Scan(&s.Repo, &s.Tag, &s.Published, &s.PRs, &s.DaysToReleaseAverage, &s.MergedToReleaseAverage); err != nil {
+			return nil, fmt.Errorf("failed to scan release stats: %w", err)
+		}
+		stats = append(stats, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get release stats: %w", err)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Published.Before(stats[j].Published)
+	})
+
+	return stats, nil
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/katbyte/gogo-repo-stats/lib/gh"
+)
+
+type Repo struct {
+	Host          string
+	Owner         string
+	Name          string
+	DefaultBranch string
+	Stars         int
+	Archived      bool
+	Fork          bool
+	Topics        []string
+	Created       time.Time
+	Pushed        time.Time
+	Discovered    time.Time
+}
+
+// FullName is the key used everywhere else in the cache, owner/name or host/owner/name for enterprise servers.
+func (r Repo) FullName() string {
+	return gh.Host{WebURL: "https://" + r.Host}.RepoKey(r.Owner + "/" + r.Name)
+}
+
+// ReplaceOwnerReposFromGH replaces the stored repos of an owner with those just discovered, so deleted and
+// transferred repos drop out.
+func (cache Cache) ReplaceOwnerReposFromGH(host, owner string, repos []github.Repository) error {
+	tx, err := cache.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for repos of %s: %w", owner, err)
+	}
+	defer tx.Rollback() // nolint:errcheck
+
+	if _, err = tx.Exec(`DELETE FROM repos WHERE host = ? AND owner = ?`, host, owner); err != nil {
+		return fmt.Errorf("failed to clear repos of %s: %w", owner, err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT OR REPLACE INTO repos (host, owner, name, default_branch, stars, archived, fork, topics, created, pushed, discovered) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement for repos of %s: %w", owner, err)
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+	for _, r := range repos {
+		_, err = stmt.Exec(
+			host,
+			owner,
+			r.GetName(),
+			r.GetDefaultBranch(),
+			r.GetStargazersCount(),
+			r.GetArchived(),
+			r.GetFork(),
+			strings.Join(r.Topics, ","),
+			r.GetCreatedAt().Time,
+			r.GetPushedAt().Time,
+			now,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert repo %s/%s: %w", owner, r.GetName(), err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit repos of %s: %w", owner, err)
+	}
+
+	return nil
+}
+
+// GetOwnerRepos returns the repos last discovered for an owner on a host ordered by name.
+func (cache Cache) GetOwnerRepos(host, owner string) ([]Repo, error) {
+	rows, err := cache.DB.Query(`
+		SELECT host, owner, name, default_branch, stars, archived, fork, topics, created, pushed, discovered 
+		FROM repos 
+		WHERE host = ? AND owner = ? 
+		ORDER BY name
+	`, host, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query repos of %s: %w", owner, err)
+	}
+	defer rows.Close()
+
+	var repos []Repo
+	for rows.Next() {
+		r := Repo{}
+		var topics string
+		err = rows.Scan(
+			&r.Host,
+			&r.Owner,
+			&r.Name,
+			&r.DefaultBranch,
+			&r.Stars,
+			&r.Archived,
+			&r.Fork,
+			&topics,
+			&r.Created,
+			&r.Pushed,
+			&r.Discovered,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan repos of %s: %w", owner, err)
+		}
+
+		if topics != "" {
+			r.Topics = strings.Split(topics, ",")
+		}
+		repos = append(repos, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get repos of %s: %w", owner, err)
+	}
+
+	return repos, nil
+}
@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// reviews are kept apart from the timeline events as they carry the review state (APPROVED, CHANGES_REQUESTED,
+// COMMENTED) & commit which is what approval based stats need
+
+const (
+	ReviewStateApproved         = "APPROVED"
+	ReviewStateChangesRequested = "CHANGES_REQUESTED"
+	ReviewStateCommented        = "COMMENTED"
+	ReviewStateDismissed        = "DISMISSED"
+)
+
+type Review struct {
+	Repo        string
+	PR          int
+	ID          int64
+	User        string
+	State       string
+	Submitted   time.Time
+	CommitID    string
+	Association string
+	Body        string
+	URL         string
+}
+
+func (cache Cache) UpsertReviewFromGH(repo string, pr int, review *github.PullRequestReview) error {
+	stmt, err := cache.DB.Prepare(`
+		INSERT OR REPLACE INTO reviews (repo, pr, id, user, state, submitted, commit_id, association, body, url) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement for review %d/%d: %w", pr, review.GetID(), err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(
+		repo,
+		pr,
+		review.GetID(),
+		review.GetUser().GetLogin(),
+		review.GetState(),
+		review.GetSubmittedAt(),
+		review.GetCommitID(),
+		review.GetAuthorAssociation(),
+		review.GetBody(),
+		review.GetHTMLURL(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert review %s#%d/%d: %w", repo, pr, review.GetID(), err)
+	}
+
+	return nil
+}
+
+// GetReviewsFor returns all reviews of a PR oldest first.
+func (cache Cache) GetReviewsFor(repo string, number int) ([]Review, error) {
+	rows, err := cache.DB.Query(`
+		SELECT repo, pr, id, user, state, submitted, commit_id, association, body, url 
+		FROM reviews 
+		WHERE
+			repo = ? AND
+			pr = ?
+		ORDER BY submitted
+	`, repo, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reviews for pr %d: %w", number, err)
+	}
+	defer rows.Close()
+
+	var reviews []Review
+	for rows.Next() {
+		r := Review{}
+		err = rows.Scan(
+			&r.Repo,
+			&r.PR,
+			&r.ID,
+			&r.User,
+			&r.State,
+			&r.Submitted,
+			&r.CommitID,
+			&r.Association,
+			&r.Body,
+			&r.URL,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reviews for pr %d: %w", number, err)
+		}
+
+		reviews = append(reviews, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get reviews for pr %d: %w", number, err)
+	}
+
+	return reviews, nil
+}
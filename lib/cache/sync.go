@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// the sync watermark is the updated_at of the most recently updated item we have fully synced for a repo
+// anything github reports as updated after it needs to be fetched again, everything before it is already in the cache
+
+const (
+	SyncKindPRs    = "prs"
+	SyncKindIssues = "issues"
+)
+
+// GetSyncWatermark returns the last synced updated_at for a repo & kind, or a zero time if it has never been synced.
+func (cache Cache) GetSyncWatermark(repo, kind string) (time.Time, error) {
+	var t time.Time
+
+	row := cache.DB.QueryRow(`SELECT updated FROM sync_state WHERE repo = ? AND kind = ?`, repo, kind)
+	if err := row.Scan(&t); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("failed to get sync watermark for %s/%s: %w", repo, kind, err)
+	}
+
+	return t, nil
+}
+
+func (cache Cache) UpsertSyncWatermark(repo, kind string, updated time.Time) error {
+	stmt, err := cache.DB.Prepare(`
+		INSERT OR REPLACE INTO sync_state (repo, kind, updated)
+		VALUES (?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement for sync watermark %s/%s: %w", repo, kind, err)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.Exec(repo, kind, updated.UTC()); err != nil {
+		return fmt.Errorf("failed to insert sync watermark %s/%s: %w", repo, kind, err)
+	}
+
+	return nil
+}
+
+// MarkRemoved records that a cached pr or issue is no longer returned by github, returning false if it already was.
+func (cache Cache) MarkRemoved(repo, kind string, number int) (bool, error) {
+	stmt, err := cache.DB.Prepare(`
+		INSERT OR IGNORE INTO removed (repo, kind, number, detected)
+		VALUES (?, ?, ?, ?)
+	`)
+	if err != nil {
+		return false, fmt.Errorf("failed to prepare insert statement for removed %s %s#%d: %w", kind, repo, number, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(repo, kind, number, time.Now().UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to mark %s %s#%d as removed: %w", kind, repo, number, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark %s %s#%d as removed: %w", kind, repo, number, err)
+	}
+
+	return n == 1, nil
+}
+
+// ClearRemoved un-marks an item that github has started returning again.
+func (cache Cache) ClearRemoved(repo, kind string, number int) error {
+	if _, err := cache.DB.Exec(`DELETE FROM removed WHERE repo = ? AND kind = ? AND number = ?`, repo, kind, number); err != nil {
+		return fmt.Errorf("failed to clear removed %s %s#%d: %w", kind, repo, number, err)
+	}
+
+	return nil
+}
+
+// GetCachedNumbers returns the numbers of all prs or issues stored in the cache for a repo.
+func (cache Cache) GetCachedNumbers(repo, kind string) (map[int]bool, error) {
+	var table string
+	switch kind {
+	case SyncKindPRs:
+		table = "prs"
+	case SyncKindIssues:
+		table = "issues"
+	default:
+		return nil, fmt.Errorf("unknown kind %q", kind)
+	}
+
+	rows, err := cache.DB.Query(`SELECT number FROM `+table+` WHERE repo = ?`, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s numbers for %s: %w", kind, repo, err)
+	}
+	defer rows.Close()
+
+	numbers := map[int]bool{}
+	for rows.Next() {
+		var n int
+		if err := rows.Scan(&n); err != nil {
+			return nil, fmt.Errorf("failed to scan %s number for %s: %w", kind, repo, err)
+		}
+		numbers[n] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s numbers for %s: %w", kind, repo, err)
+	}
+
+	return numbers, nil
+}
@@ -0,0 +1,233 @@
+package chttp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// a cassette is a directory of recorded http exchanges, one json file per request. recording saves every exchange
+// made through the transport with credentials redacted, replaying serves them back without touching the network.
+//
+// requests are matched on method, url & body. the same request can be made more than once (retries, refetches) so
+// each is numbered in the order it was made and replayed in the same order, the last recording repeating after that.
+
+const (
+	CassetteRecord = "record"
+	CassetteReplay = "replay"
+)
+
+const redacted = "REDACTED"
+
+// headers that carry credentials, they are replaced before anything is written
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Hub-Signature", "X-Hub-Signature-256"}
+
+// github tokens have recognisable prefixes, catch any that end up in a url or body. installation token responses
+// carry the token in a json field so catch those too
+var tokenRegex = regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9_]{20,}|github_pat_[A-Za-z0-9_]{20,})\b`)
+var tokenFieldRegex = regexp.MustCompile(`("token"\s*:\s*)"[^"]*"`)
+
+// Redact removes credentials from a dump or body.
+func Redact(s string) string {
+	s = tokenRegex.ReplaceAllString(s, redacted)
+	return tokenFieldRegex.ReplaceAllString(s, `$1"`+redacted+`"`)
+}
+
+func redactHeaders(h http.Header) http.Header {
+	h = h.Clone()
+	for _, k := range sensitiveHeaders {
+		if h.Get(k) != "" {
+			h.Set(k, redacted)
+		}
+	}
+	return h
+}
+
+// NoRecordingError is returned when replaying a request that was never recorded, retrying it will not help.
+type NoRecordingError struct {
+	Dir    string
+	Method string
+	URL    string
+}
+
+func (e *NoRecordingError) Error() string {
+	return fmt.Sprintf("no recording in cassette %s for %s %s", e.Dir, e.Method, e.URL)
+}
+
+type Cassette struct {
+	Dir  string
+	Mode string
+
+	mu    sync.Mutex
+	count map[string]int
+}
+
+func NewCassette(dir, mode string) (*Cassette, error) {
+	switch mode {
+	case CassetteRecord:
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return nil, fmt.Errorf("creating cassette %s: %w", dir, err)
+		}
+	case CassetteReplay:
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("opening cassette %s: %w", dir, err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %q, expected %s or %s", mode, CassetteRecord, CassetteReplay)
+	}
+
+	return &Cassette{Dir: dir, Mode: mode, count: map[string]int{}}, nil
+}
+
+type cassetteRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body,omitempty"`
+}
+
+type cassetteResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body"`
+}
+
+type cassetteExchange struct {
+	Request  cassetteRequest  `json:"request"`
+	Response cassetteResponse `json:"response"`
+}
+
+// key identifies a request, it is made from the redacted url so recordings replay whatever token is used
+func cassetteKey(method, url string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + Redact(url) + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func (c *Cassette) path(key string, n int) string {
+	return filepath.Join(c.Dir, fmt.Sprintf("%s-%03d.json", key, n))
+}
+
+// next numbers the requests with the same key in the order they are made
+func (c *Cassette) next(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count[key]++
+	return c.count[key]
+}
+
+// Transport wraps base so every exchange is recorded, or in replay mode served from the cassette instead.
+func (c *Cassette) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &cassetteTransport{cassette: c, base: base}
+}
+
+type cassetteTransport struct {
+	cassette *Cassette
+	base     http.RoundTripper
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, fmt.Errorf("reading request body: %w", err)
+		}
+		req.Body.Close()
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	key := cassetteKey(req.Method, req.URL.String(), body)
+	n := t.cassette.next(key)
+
+	if t.cassette.Mode == CassetteReplay {
+		return t.cassette.replay(req, key, n)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	ex := cassetteExchange{
+		Request: cassetteRequest{
+			Method:  req.Method,
+			URL:     Redact(req.URL.String()),
+			Headers: redactHeaders(req.Header),
+			Body:    Redact(string(body)),
+		},
+		Response: cassetteResponse{
+			Status:  resp.StatusCode,
+			Headers: redactHeaders(resp.Header),
+			Body:    Redact(string(respBody)),
+		},
+	}
+
+	data, err := json.MarshalIndent(ex, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding cassette recording: %w", err)
+	}
+	if err = os.WriteFile(t.cassette.path(key, n), data, 0o600); err != nil {
+		return nil, fmt.Errorf("writing cassette recording: %w", err)
+	}
+
+	return resp, nil
+}
+
+func (c *Cassette) replay(req *http.Request, key string, n int) (*http.Response, error) {
+	// past the last recording of a request keep serving the last one
+	path := c.path(key, n)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		matches, _ := filepath.Glob(filepath.Join(c.Dir, key+"-*.json"))
+		if len(matches) == 0 {
+			return nil, &NoRecordingError{Dir: c.Dir, Method: req.Method, URL: Redact(req.URL.String())}
+		}
+		sort.Strings(matches)
+		path = matches[len(matches)-1]
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading cassette recording %s: %w", path, err)
+	}
+
+	var ex cassetteExchange
+	if err = json.Unmarshal(data, &ex); err != nil {
+		return nil, fmt.Errorf("decoding cassette recording %s: %w", path, err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", ex.Response.Status, http.StatusText(ex.Response.Status)),
+		StatusCode:    ex.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        ex.Response.Headers,
+		Body:          io.NopCloser(strings.NewReader(ex.Response.Body)),
+		ContentLength: int64(len(ex.Response.Body)),
+		Request:       req,
+	}, nil
+}
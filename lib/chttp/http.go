@@ -27,7 +27,7 @@ func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	reqData, err := httputil.DumpRequestOut(req, true)
 
 	if err == nil {
-		clog.Log.Tracef(logReqMsg, t.name, prettyPrintJSON(reqData))
+		clog.Log.Tracef(logReqMsg, t.name, redactDump(prettyPrintJSON(reqData)))
 	} else {
 		clog.Log.Debugf("%s API Request error: %#v", t.name, err)
 	}
@@ -39,7 +39,7 @@ func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 
 	respData, err := httputil.DumpResponse(resp, true)
 	if err == nil {
-		clog.Log.Tracef(logRespMsg, t.name, prettyPrintJSON(respData))
+		clog.Log.Tracef(logRespMsg, t.name, redactDump(prettyPrintJSON(respData)))
 	} else {
 		clog.Log.Debugf("%s API Response error: %#v", t.name, err)
 	}
@@ -51,6 +51,19 @@ func NewTransport(name string, t http.RoundTripper) *transport {
 	return &transport{name, t}
 }
 
+// redactDump removes credentials from a dumped request or response so trace logs can be shared.
+func redactDump(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		for _, h := range sensitiveHeaders {
+			if strings.HasPrefix(strings.ToLower(l), strings.ToLower(h)+":") {
+				lines[i] = h + ": " + redacted
+			}
+		}
+	}
+	return Redact(strings.Join(lines, "\n"))
+}
+
 // prettyPrintJSON iterates through a []byte line-by-line,
 // transforming any lines that are complete json into pretty-printed json.
 func prettyPrintJSON(b []byte) string {
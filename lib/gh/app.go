@@ -0,0 +1,221 @@
+package gh
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/katbyte/gogo-repo-stats/lib/chttp"
+	"github.com/katbyte/gogo-repo-stats/lib/clog"
+	"golang.org/x/oauth2"
+)
+
+// installation tokens last an hour, get a new one this long before so requests in flight never see it expire
+const appTokenRefreshBefore = 5 * time.Minute
+
+// App authenticates as a github app: a short-lived JWT signed with the app's private key is exchanged for an
+// installation token per org or user, which is cached and refreshed before it expires. an App is shared by every
+// client so all of them reuse the same installation tokens.
+type App struct {
+	ID         int64
+	PrivateKey *rsa.PrivateKey
+	Host       Host
+	Cassette   *chttp.Cassette
+
+	// Installation when set is used for every owner rather than looking up the app's installation on each
+	Installation int64
+
+	lock          sync.Mutex
+	installations map[string]int64
+	sources       map[string]*installationTokenSource
+}
+
+// NewApp creates an app from its id and private key, the key can either be the PEM itself or a path to it.
+func NewApp(id int64, key string) (*App, error) {
+	data := []byte(key)
+	if !strings.Contains(key, "-----BEGIN") {
+		var err error
+		if data, err = os.ReadFile(key); err != nil {
+			return nil, fmt.Errorf("reading app private key %s: %w", key, err)
+		}
+	}
+
+	pk, err := parseRSAPrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing app private key: %w", err)
+	}
+
+	return &App{
+		ID:            id,
+		PrivateKey:    pk,
+		installations: map[string]int64{},
+		sources:       map[string]*installationTokenSource{},
+	}, nil
+}
+
+// github generates PKCS#1 keys, accept PKCS#8 as well for keys that have been converted
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	if pk, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return pk, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("not a PKCS#1 or PKCS#8 key: %w", err)
+	}
+
+	pk, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("expected an RSA key, got %T", key)
+	}
+
+	return pk, nil
+}
+
+// JWT returns a token that authenticates as the app itself, it is only good for the app endpoints and lasts 10 minutes.
+func (a *App) JWT() (string, error) {
+	now := time.Now()
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	// backdate to allow for clock drift, github rejects tokens that expire more than 10 minutes out
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(a.ID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.PrivateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("signing app jwt: %w", err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// Token implements oauth2.TokenSource with a fresh JWT each time, for clients talking to the app endpoints.
+func (a *App) Token() (*oauth2.Token, error) {
+	jwt, err := a.JWT()
+	if err != nil {
+		return nil, err
+	}
+
+	return &oauth2.Token{AccessToken: jwt, TokenType: "Bearer"}, nil
+}
+
+// InstallationFor returns the id of the app's installation on an org or user, looked up once and then remembered.
+func (a *App) InstallationFor(owner string) (int64, error) {
+	if a.Installation != 0 {
+		return a.Installation, nil
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if id, ok := a.installations[owner]; ok {
+		return id, nil
+	}
+
+	client, ctx := Token{Host: a.Host, Source: a, Cassette: a.Cassette}.NewClient()
+
+	clog.Log.Debugf("Finding app %d installation for %s...", a.ID, owner)
+	inst, _, err := client.Apps.FindOrganizationInstallation(ctx, owner)
+	if err != nil {
+		var ghErr *github.ErrorResponse
+		if !errors.As(err, &ghErr) || ghErr.Response == nil || ghErr.Response.StatusCode != http.StatusNotFound {
+			return 0, fmt.Errorf("unable to find app installation for org %s: %w", owner, err)
+		}
+
+		// not an org, try as a user
+		inst, _, err = client.Apps.FindUserInstallation(ctx, owner)
+		if err != nil {
+			return 0, fmt.Errorf("unable to find app installation for %s, is the app installed there?: %w", owner, err)
+		}
+	}
+
+	a.installations[owner] = inst.GetID()
+	return inst.GetID(), nil
+}
+
+// TokenSource returns the installation token source for an org or user, the same source is returned for every call
+// so all clients for an owner share one token.
+func (a *App) TokenSource(owner string) oauth2.TokenSource {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if s, ok := a.sources[owner]; ok {
+		return s
+	}
+
+	s := &installationTokenSource{app: a, owner: owner}
+	a.sources[owner] = s
+	return s
+}
+
+// installationTokenSource hands out an installation token, exchanging a new JWT for another shortly before it expires.
+type installationTokenSource struct {
+	app   *App
+	owner string
+
+	lock  sync.Mutex
+	token *oauth2.Token
+}
+
+func (s *installationTokenSource) Token() (*oauth2.Token, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.token != nil && time.Until(s.token.Expiry) > appTokenRefreshBefore {
+		return s.token, nil
+	}
+
+	id, err := s.app.InstallationFor(s.owner)
+	if err != nil {
+		return nil, err
+	}
+
+	client, ctx := Token{Host: s.app.Host, Source: s.app, Cassette: s.app.Cassette}.NewClient()
+
+	clog.Log.Debugf("Creating app %d installation %d token for %s...", s.app.ID, id, s.owner)
+	it, _, err := client.Apps.CreateInstallationToken(ctx, id, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create installation %d token for %s: %w", id, s.owner, err)
+	}
+
+	s.token = &oauth2.Token{
+		AccessToken: it.GetToken(),
+		TokenType:   "token",
+		Expiry:      it.GetExpiresAt(),
+	}
+	clog.Log.Infof("app installation token for %s refreshed, expires at %s", s.owner, s.token.Expiry.Format(time.RFC3339))
+
+	return s.token, nil
+}
@@ -0,0 +1,269 @@
+package gh
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/katbyte/gogo-repo-stats/lib/clog"
+	"github.com/katbyte/gogo-repo-stats/lib/pointer"
+)
+
+// ci shows up on a commit in two ways: check runs from github apps (actions etc) and the older commit statuses
+
+// GetAllCheckRunsForRef returns every check run for a commit including earlier attempts that were re-run.
+func (r Repo) GetAllCheckRunsForRef(ref string) (*[]github.CheckRun, error) {
+	client, ctx := r.NewClient()
+
+	opts := &github.ListCheckRunsOptions{
+		Filter: pointer.To("all"),
+		ListOptions: github.ListOptions{
+			Page:    1,
+			PerPage: 100,
+		},
+	}
+
+	var allRuns []github.CheckRun
+	for {
+		clog.Log.Debugf("Listing all check runs for %s/%s@%s (Page %d)...", r.Owner, r.Name, ref, opts.Page)
+		result, resp, err := client.Checks.ListCheckRunsForRef(ctx, r.Owner, r.Name, ref, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list check runs for %s/%s@%s (Page %d): %w", r.Owner, r.Name, ref, opts.Page, err)
+		}
+
+		for i, run := range result.CheckRuns {
+			if run == nil {
+				clog.Log.Debugf("check runs[%d] was nil, skipping", i)
+				continue
+			}
+			allRuns = append(allRuns, *run)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return &allRuns, nil
+}
+
+// GetAllStatusesForRef returns every status set on a commit, newest first as github returns them.
+func (r Repo) GetAllStatusesForRef(ref string) (*[]github.RepoStatus, error) {
+	client, ctx := r.NewClient()
+
+	opts := &github.ListOptions{
+		Page:    1,
+		PerPage: 100,
+	}
+
+	var allStatuses []github.RepoStatus
+	for {
+		clog.Log.Debugf("Listing all statuses for %s/%s@%s (Page %d)...", r.Owner, r.Name, ref, opts.Page)
+		statuses, resp, err := client.Repositories.ListStatuses(ctx, r.Owner, r.Name, ref, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list statuses for %s/%s@%s (Page %d): %w", r.Owner, r.Name, ref, opts.Page, err)
+		}
+
+		for i, s := range statuses {
+			if s == nil {
+				clog.Log.Debugf("statuses[%d] was nil, skipping", i)
+				continue
+			}
+			allStatuses = append(allStatuses, *s)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return &allStatuses, nil
+}
+
+const graphQLCheckRunFields = `
+	databaseId name status conclusion startedAt completedAt
+`
+
+// check runs of a suite are only paged through when a suite has more than fit in the first page (ie big matrices)
+const graphQLQueryCommitChecks = `
+query($owner: String!, $name: String!, $oid: GitObjectID!, $cursor: String) {
+	repository(owner: $owner, name: $name) {
+		object(oid: $oid) {
+			... on Commit {
+				status { state }
+				checkSuites(first: 50, after: $cursor) {
+					pageInfo { hasNextPage endCursor }
+					nodes {
+						id
+						checkRuns(first: 100, filterBy: {checkType: ALL}) {
+							pageInfo { hasNextPage endCursor }
+							nodes {` + graphQLCheckRunFields + `}
+						}
+					}
+				}
+			}
+		}
+	}
+}`
+
+const graphQLQuerySuiteCheckRuns = `
+query($id: ID!, $cursor: String) {
+	node(id: $id) {
+		... on CheckSuite {
+			checkRuns(first: 100, after: $cursor, filterBy: {checkType: ALL}) {
+				pageInfo { hasNextPage endCursor }
+				nodes {` + graphQLCheckRunFields + `}
+			}
+		}
+	}
+}`
+
+type graphQLCheckRuns struct {
+	PageInfo graphQLPageInfo `json:"pageInfo"`
+	Nodes    []struct {
+		DatabaseID  int64      `json:"databaseId"`
+		Name        string     `json:"name"`
+		Status      string     `json:"status"`
+		Conclusion  *string    `json:"conclusion"`
+		StartedAt   *time.Time `json:"startedAt"`
+		CompletedAt *time.Time `json:"completedAt"`
+	} `json:"nodes"`
+}
+
+// toCheckRuns converts the runs into REST's, graphql has its enums in upper case
+func (rs graphQLCheckRuns) toCheckRuns() []github.CheckRun {
+	var runs []github.CheckRun
+
+	for _, n := range rs.Nodes {
+		run := github.CheckRun{
+			ID:     pointer.To(n.DatabaseID),
+			Name:   pointer.To(n.Name),
+			Status: pointer.To(strings.ToLower(n.Status)),
+		}
+		if n.Conclusion != nil {
+			run.Conclusion = pointer.To(strings.ToLower(*n.Conclusion))
+		}
+		if n.StartedAt != nil {
+			run.StartedAt = &github.Timestamp{Time: *n.StartedAt}
+		}
+		if n.CompletedAt != nil {
+			run.CompletedAt = &github.Timestamp{Time: *n.CompletedAt}
+		}
+		runs = append(runs, run)
+	}
+
+	return runs
+}
+
+// GetAllCheckRunsForRefGraphQL returns every check run for a commit including earlier attempts that were re-run, and
+// if the commit has any statuses. graphql only has the latest of each status so they are left to GetAllStatusesForRef.
+func (r Repo) GetAllCheckRunsForRefGraphQL(ref string) (*[]github.CheckRun, bool, error) {
+	var allRuns []github.CheckRun
+	hasStatuses := false
+
+	var cursor *string
+	for page := 1; ; page++ {
+		clog.Log.Debugf("GraphQL listing all check runs for %s/%s@%s (Page %d)...", r.Owner, r.Name, ref, page)
+
+		var data struct {
+			Repository struct {
+				Object *struct {
+					Status *struct {
+						State string `json:"state"`
+					} `json:"status"`
+					CheckSuites struct {
+						PageInfo graphQLPageInfo `json:"pageInfo"`
+						Nodes    []struct {
+							ID        string           `json:"id"`
+							CheckRuns graphQLCheckRuns `json:"checkRuns"`
+						} `json:"nodes"`
+					} `json:"checkSuites"`
+				} `json:"object"`
+			} `json:"repository"`
+		}
+		err := r.GraphQLQueryUnmarshal(graphQLQueryCommitChecks, map[string]interface{}{
+			"owner":  r.Owner,
+			"name":   r.Name,
+			"oid":    ref,
+			"cursor": cursor,
+		}, &data)
+		if err != nil {
+			return nil, false, fmt.Errorf("unable to list check runs for %s/%s@%s (Page %d): %w", r.Owner, r.Name, ref, page, err)
+		}
+
+		// a commit force pushed away can be gone entirely
+		o := data.Repository.Object
+		if o == nil {
+			clog.Log.Debugf("commit %s/%s@%s not found, skipping", r.Owner, r.Name, ref)
+			break
+		}
+		hasStatuses = hasStatuses || o.Status != nil
+
+		for _, suite := range o.CheckSuites.Nodes {
+			allRuns = append(allRuns, suite.CheckRuns.toCheckRuns()...)
+
+			pi := suite.CheckRuns.PageInfo
+			for pi.HasNextPage {
+				clog.Log.Debugf("GraphQL listing more check runs for %s/%s@%s...", r.Owner, r.Name, ref)
+
+				var more struct {
+					Node struct {
+						CheckRuns graphQLCheckRuns `json:"checkRuns"`
+					} `json:"node"`
+				}
+				err := r.GraphQLQueryUnmarshal(graphQLQuerySuiteCheckRuns, map[string]interface{}{
+					"id":     suite.ID,
+					"cursor": pi.EndCursor,
+				}, &more)
+				if err != nil {
+					return nil, false, fmt.Errorf("unable to list check runs for %s/%s@%s: %w", r.Owner, r.Name, ref, err)
+				}
+
+				allRuns = append(allRuns, more.Node.CheckRuns.toCheckRuns()...)
+				pi = more.Node.CheckRuns.PageInfo
+			}
+		}
+
+		if !o.CheckSuites.PageInfo.HasNextPage {
+			break
+		}
+		cursor = o.CheckSuites.PageInfo.EndCursor
+	}
+
+	return &allRuns, hasStatuses, nil
+}
+
+// GetAllPullRequestCommitSHAs returns the SHAs of every commit currently on a PR, oldest first.
+func (r Repo) GetAllPullRequestCommitSHAs(number int) ([]string, error) {
+	client, ctx := r.NewClient()
+
+	opts := &github.ListOptions{
+		Page:    1,
+		PerPage: 100,
+	}
+
+	var shas []string
+	for {
+		clog.Log.Debugf("Listing all commits for %s/%s/%d (Page %d)...", r.Owner, r.Name, number, opts.Page)
+		commits, resp, err := client.PullRequests.ListCommits(ctx, r.Owner, r.Name, number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list commits for %s/%s/%d (Page %d): %w", r.Owner, r.Name, number, opts.Page, err)
+		}
+
+		for _, cm := range commits {
+			if cm.GetSHA() != "" {
+				shas = append(shas, cm.GetSHA())
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return shas, nil
+}
@@ -0,0 +1,114 @@
+package gh
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/katbyte/gogo-repo-stats/lib/clog"
+)
+
+// issue comments are the conversation on both issues & prs, review comments are the ones left on a pr's diff
+
+func (r Repo) ListAllIssueComments(number int, cb func([]*github.IssueComment, *github.Response) error) error {
+	client, ctx := r.NewClient()
+
+	opts := &github.IssueListCommentsOptions{
+		ListOptions: github.ListOptions{
+			Page:    1,
+			PerPage: 100,
+		},
+	}
+
+	for {
+		clog.Log.Debugf("Listing all comments for %s/%s/%d (Page %d)...", r.Owner, r.Name, number, opts.Page)
+		comments, resp, err := client.Issues.ListComments(ctx, r.Owner, r.Name, number, opts)
+		if err != nil {
+			return fmt.Errorf("unable to list comments for %s/%s/%d (Page %d): %w", r.Owner, r.Name, number, opts.Page, err)
+		}
+
+		if err = cb(comments, resp); err != nil {
+			return fmt.Errorf("callback failed for %s/%s/%d (Page %d): %w", r.Owner, r.Name, number, opts.Page, err)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return nil
+}
+
+func (r Repo) GetAllIssueComments(number int) (*[]github.IssueComment, error) {
+	var allComments []github.IssueComment
+
+	err := r.ListAllIssueComments(number, func(comments []*github.IssueComment, resp *github.Response) error {
+		for i, cm := range comments {
+			if cm == nil {
+				clog.Log.Debugf("comments[%d] was nil, skipping", i)
+				continue
+			}
+
+			allComments = append(allComments, *cm)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all comments for %s/%s/%d: %w", r.Owner, r.Name, number, err)
+	}
+
+	return &allComments, nil
+}
+
+func (r Repo) ListAllPullRequestComments(number int, cb func([]*github.PullRequestComment, *github.Response) error) error {
+	client, ctx := r.NewClient()
+
+	opts := &github.PullRequestListCommentsOptions{
+		ListOptions: github.ListOptions{
+			Page:    1,
+			PerPage: 100,
+		},
+	}
+
+	for {
+		clog.Log.Debugf("Listing all review comments for %s/%s/%d (Page %d)...", r.Owner, r.Name, number, opts.Page)
+		comments, resp, err := client.PullRequests.ListComments(ctx, r.Owner, r.Name, number, opts)
+		if err != nil {
+			return fmt.Errorf("unable to list review comments for %s/%s/%d (Page %d): %w", r.Owner, r.Name, number, opts.Page, err)
+		}
+
+		if err = cb(comments, resp); err != nil {
+			return fmt.Errorf("callback failed for %s/%s/%d (Page %d): %w", r.Owner, r.Name, number, opts.Page, err)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return nil
+}
+
+func (r Repo) GetAllPullRequestComments(number int) (*[]github.PullRequestComment, error) {
+	var allComments []github.PullRequestComment
+
+	err := r.ListAllPullRequestComments(number, func(comments []*github.PullRequestComment, resp *github.Response) error {
+		for i, cm := range comments {
+			if cm == nil {
+				clog.Log.Debugf("review comments[%d] was nil, skipping", i)
+				continue
+			}
+
+			allComments = append(allComments, *cm)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all review comments for %s/%s/%d: %w", r.Owner, r.Name, number, err)
+	}
+
+	return &allComments, nil
+}
@@ -0,0 +1,143 @@
+package gh
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/katbyte/gogo-repo-stats/lib/clog"
+)
+
+// github does not count 304 Not Modified responses against the rate limit, so by remembering the validators
+// (ETag/Last-Modified) and payload of every GET we can revalidate unchanged prs & timelines for free
+//
+// the transport reads & writes its store from whichever goroutine makes the request, for a fetch that is the pool's
+// workers. this is the one exception to only the writer touching the cache: the entries are independent single row
+// upserts nothing else reads, and the cache funnels every query through one connection so they just queue. responses
+// bigger than maxConditionalBody are not stored and the cache drops entries not stored or revalidated for a while
+
+// maxConditionalBody is the largest payload kept, anything bigger is just refetched in full
+const maxConditionalBody = 2 << 20
+
+type ConditionalResponse struct {
+	ETag         string
+	LastModified string
+	Header       http.Header
+	Body         []byte
+}
+
+// ConditionalStore persists validators and payloads keyed by request URL.
+type ConditionalStore interface {
+	GetConditionalResponse(url string) (*ConditionalResponse, error)
+	UpsertConditionalResponse(url string, r ConditionalResponse) error
+
+	// TouchConditionalResponse records that a stored response was revalidated with a 304 and is still current
+	TouchConditionalResponse(url string) error
+}
+
+func NewConditionalTransport(store ConditionalStore, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &conditionalTransport{store: store, base: base}
+}
+
+type conditionalTransport struct {
+	store ConditionalStore
+	base  http.RoundTripper
+}
+
+func (t *conditionalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+
+	// the same url can be asked for in several media types, ie timelines with preview accept headers
+	key := req.URL.String()
+	if accept := req.Header.Get("Accept"); accept != "" {
+		key += " " + accept
+	}
+	stored, err := t.store.GetConditionalResponse(key)
+	if err != nil {
+		clog.Log.Warnf("unable to look up conditional response for %s: %v", key, err)
+		stored = nil
+	}
+
+	if stored != nil {
+		req = req.Clone(req.Context())
+		if stored.ETag != "" {
+			req.Header.Set("If-None-Match", stored.ETag)
+		}
+		if stored.LastModified != "" {
+			req.Header.Set("If-Modified-Since", stored.LastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && stored != nil {
+		clog.Log.Debugf("304 not modified, serving %s from the cache", key)
+		resp.Body.Close()
+
+		if err := t.store.TouchConditionalResponse(key); err != nil {
+			clog.Log.Warnf("unable to touch conditional response for %s: %v", key, err)
+		}
+
+		// the stored headers carry pagination links etc, the fresh ones the current rate limit
+		header := stored.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		for k, v := range resp.Header {
+			if strings.HasPrefix(strings.ToLower(k), "x-ratelimit") {
+				header[k] = v
+			}
+		}
+		header.Set("Content-Length", strconv.Itoa(len(stored.Body)))
+
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         resp.Proto,
+			ProtoMajor:    resp.ProtoMajor,
+			ProtoMinor:    resp.ProtoMinor,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(stored.Body)),
+			ContentLength: int64(len(stored.Body)),
+			Request:       resp.Request,
+		}, nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	if resp.StatusCode != http.StatusOK || (etag == "" && lastModified == "") || resp.ContentLength > maxConditionalBody {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if len(body) > maxConditionalBody {
+		return resp, nil
+	}
+
+	err = t.store.UpsertConditionalResponse(key, ConditionalResponse{
+		ETag:         etag,
+		LastModified: lastModified,
+		Header:       resp.Header.Clone(),
+		Body:         body,
+	})
+	if err != nil {
+		clog.Log.Warnf("unable to store conditional response for %s: %v", key, err)
+	}
+
+	return resp, nil
+}
@@ -2,13 +2,50 @@ package gh
 
 import (
 	"fmt"
+	"net/url"
 	"sort"
+	"strconv"
 
 	"github.com/google/go-github/v45/github"
 	"github.com/katbyte/gogo-repo-stats/lib/clog"
 )
 
-func (r Repo) ListAllIssueEvents(number int, cb func([]*github.Timeline, *github.Response) error) error {
+// TimelineEvent is a timeline event along with the fields go-github doesn't decode
+type TimelineEvent struct {
+	github.Timeline
+
+	// the event's graphql node id, unique across every kind of event (their numeric ids are per kind)
+	NodeID *string `json:"node_id,omitempty"`
+
+	// the team asked to review, review_requested & review_request_removed have either this or a reviewer
+	RequestedTeam *github.Team `json:"requested_team,omitempty"`
+
+	// why a locked event's conversation was locked: off-topic, too heated, resolved or spam
+	LockReason *string `json:"lock_reason,omitempty"`
+}
+
+func (e *TimelineEvent) GetNodeID() string {
+	if e == nil || e.NodeID == nil {
+		return ""
+	}
+	return *e.NodeID
+}
+
+func (e *TimelineEvent) GetRequestedTeam() *github.Team {
+	if e == nil {
+		return nil
+	}
+	return e.RequestedTeam
+}
+
+func (e *TimelineEvent) GetLockReason() string {
+	if e == nil || e.LockReason == nil {
+		return ""
+	}
+	return *e.LockReason
+}
+
+func (r Repo) ListAllIssueEvents(number int, cb func([]*TimelineEvent, *github.Response) error) error {
 	client, ctx := r.NewClient()
 
 	opts := &github.ListOptions{
@@ -18,7 +55,17 @@ func (r Repo) ListAllIssueEvents(number int, cb func([]*github.Timeline, *github
 
 	for {
 		clog.Log.Debugf("Listing all events for %s/%s/%d (Page %d)...", r.Owner, r.Name, number, opts.Page)
-		events, resp, err := client.Issues.ListIssueTimeline(ctx, r.Owner, r.Name, number, opts)
+
+		// the same request as client.Issues.ListIssueTimeline, decoded into our own type
+		q := url.Values{"page": {strconv.Itoa(opts.Page)}, "per_page": {strconv.Itoa(opts.PerPage)}}
+		req, err := client.NewRequest("GET", fmt.Sprintf("repos/%v/%v/issues/%v/timeline?%s", r.Owner, r.Name, number, q.Encode()), nil)
+		if err != nil {
+			return fmt.Errorf("unable to list events for %s/%s/%d (Page %d): %w", r.Owner, r.Name, number, opts.Page, err)
+		}
+		req.Header.Set("Accept", "application/vnd.github.mockingbird-preview+json, application/vnd.github.starfox-preview+json")
+
+		var events []*TimelineEvent
+		resp, err := client.Do(ctx, req, &events)
 		if err != nil {
 
 			return fmt.Errorf("unable to list events for %s/%s/%d (Page %d): %w", r.Owner, r.Name, number, opts.Page, err)
@@ -38,10 +85,10 @@ func (r Repo) ListAllIssueEvents(number int, cb func([]*github.Timeline, *github
 	return nil
 }
 
-func (r Repo) GetAllIssueEvents(number int) (*[]github.Timeline, error) {
-	var allEvents []github.Timeline
+func (r Repo) GetAllIssueEvents(number int) (*[]TimelineEvent, error) {
+	var allEvents []TimelineEvent
 
-	err := r.ListAllIssueEvents(number, func(events []*github.Timeline, resp *github.Response) error {
+	err := r.ListAllIssueEvents(number, func(events []*TimelineEvent, resp *github.Response) error {
 		for i, e := range events {
 			if e == nil {
 				clog.Log.Debugf("events[%d] was nil, skipping", i)
@@ -2,14 +2,15 @@ package gh
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/go-github/v45/github"
 	"github.com/hashicorp/go-retryablehttp"
+	"github.com/katbyte/gogo-repo-stats/lib/chttp"
 	"github.com/katbyte/gogo-repo-stats/lib/clog"
 	"github.com/katbyte/gogo-repo-stats/lib/pointer"
 	"golang.org/x/oauth2"
@@ -17,6 +18,28 @@ import (
 
 type Token struct {
 	Token *string
+
+	// Source when set supplies the token instead, ie a github app's installation tokens which expire & are refreshed
+	Source oauth2.TokenSource
+
+	// Budget when set is shared by every client created from this token
+	Budget *RateLimitBudget
+
+	// Throttle when set paces every request and backs off from secondary rate limits for all clients sharing it
+	Throttle *Throttle
+
+	// Pool when set authenticates each request with whichever of several tokens has the most budget left, it
+	// tracks a budget per token so replaces Budget
+	Pool *TokenPool
+
+	// Conditional when set is used to revalidate GET requests with If-None-Match/If-Modified-Since
+	Conditional ConditionalStore
+
+	// Host is the github instance to talk to, github.com when empty
+	Host Host
+
+	// Cassette when set records every exchange, or replays them without talking to github
+	Cassette *chttp.Cassette
 }
 
 type Repo struct {
@@ -28,6 +51,11 @@ type Repo struct {
 func NewRepo(repo, token string) (*Repo, error) {
 	parts := strings.Split(repo, "/")
 
+	// enterprise repos are keyed as host/owner/name in the cache, the host itself comes from Token.Host
+	if len(parts) == 3 {
+		parts = parts[1:]
+	}
+
 	if len(parts) != 2 {
 		return nil, fmt.Errorf("invalid repo format, expected owner/name got %q", repo)
 	}
@@ -74,6 +102,21 @@ func NewProject(owner string, number int, token string) Project {
 }
 
 func (t Token) NewClient() (*github.Client, context.Context) {
+	httpClient, ctx := t.NewHTTPClient()
+
+	if t.Host.Enterprise() {
+		client, err := github.NewEnterpriseClient(t.Host.API(), t.Host.Web()+"/api/uploads/", httpClient)
+		if err == nil {
+			return client, ctx
+		}
+		clog.Log.Errorf("unable to create client for %s, falling back to github.com: %s", t.Host.API(), err)
+	}
+
+	return github.NewClient(httpClient), ctx
+}
+
+// NewHTTPClient returns the authenticated, rate limit aware http client used for both the REST and GraphQL APIs.
+func (t Token) NewHTTPClient() (*http.Client, context.Context) {
 	ctx := context.Background()
 
 	// use retryablehttp to handle rate limiting
@@ -81,39 +124,93 @@ func (t Token) NewClient() (*github.Client, context.Context) {
 	retryClient.RetryMax = 7
 	retryClient.Logger = clog.Log
 
-	// github is.. special using 403 instead of 429 for rate limiting so we need to handle that here :(
+	// github is.. special using 403 for both rate limits and permission errors, so work out which it was (see throttle.go)
 	retryClient.Backoff = func(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
-		if resp != nil && resp.StatusCode == 403 {
-			// get x-rate-limit-reset header
-			reset := resp.Header.Get("x-ratelimit-reset")
-			if reset != "" {
-				i, err := strconv.ParseInt(reset, 10, 64)
-				if err == nil {
-					utime := time.Unix(i, 0)
-					wait := utime.Sub(time.Now()) + time.Minute // add an extra min to be safe
-					clog.Log.Errorf("ratelimited, parsed x-ratelimit-reset, waiting for %s", wait.String())
-					return wait
-				}
-				clog.Log.Errorf("unable to parse x-ratelimit-reset header: %s", err)
+		switch kind, wait, _ := classifyRateLimit(resp); kind {
+		case primaryRateLimit:
+			// the retry goes out with another token, so there is only a need to wait when they are all spent
+			if t.Pool != nil && t.Pool.Available() {
+				return time.Second
 			}
+
+			wait += time.Minute // add an extra min to be safe
+			clog.Log.Errorf("ratelimited, waiting for %s until x-ratelimit-reset", wait.String())
+			return wait
+		case secondaryRateLimit:
+			clog.Log.Errorf("secondary rate limit, waiting for %s", wait.String())
+			return wait
 		}
 
 		return retryablehttp.DefaultBackoff(min, max, attemptNum, resp)
 	}
 	retryClient.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
-		if resp.StatusCode == 403 {
-			return true, nil
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+
+		// a replay can't conjure up what was never recorded
+		var noRecording *chttp.NoRecordingError
+		if errors.As(err, &noRecording) {
+			return false, err
+		}
+
+		// only a response can be rate limited, transport errors have none
+		if err == nil && resp != nil {
+			switch kind, _, message := classifyRateLimit(resp); kind {
+			case primaryRateLimit, secondaryRateLimit:
+				return true, nil
+			case forbidden:
+				fe := &ForbiddenError{
+					Message: message,
+					Scopes:  resp.Header.Get("X-OAuth-Scopes"),
+					Needs:   resp.Header.Get("X-Accepted-OAuth-Scopes"),
+				}
+				if resp.Request != nil {
+					fe.URL = resp.Request.URL.String()
+				}
+				return false, fe
+			}
 		}
 
 		return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
 	}
 
-	if t := t.Token; t != nil {
+	// enterprise servers often sit behind an internal CA and/or proxy
+	if transport, ok := retryClient.HTTPClient.Transport.(*http.Transport); ok {
+		if err := t.Host.configureTransport(transport); err != nil {
+			clog.Log.Errorf("unable to configure transport for %s: %s", t.Host.Name(), err)
+		}
+	}
+
+	// every exchange is dumped at trace level, and recorded to or replayed from a cassette
+	if t.Cassette != nil {
+		retryClient.HTTPClient.Transport = t.Cassette.Transport(retryClient.HTTPClient.Transport)
+	}
+	retryClient.HTTPClient.Transport = chttp.NewTransport("GitHub", retryClient.HTTPClient.Transport)
+
+	if t.Throttle != nil {
+		retryClient.HTTPClient.Transport = t.Throttle.Transport(retryClient.HTTPClient.Transport)
+	}
+	if t.Pool != nil {
+		retryClient.HTTPClient.Transport = t.Pool.Transport(retryClient.HTTPClient.Transport)
+	} else if t.Budget != nil {
+		retryClient.HTTPClient.Transport = t.Budget.Transport(retryClient.HTTPClient.Transport)
+	}
+	if t.Conditional != nil {
+		retryClient.HTTPClient.Transport = NewConditionalTransport(t.Conditional, retryClient.HTTPClient.Transport)
+	}
+
+	// oauth2 builds its transport on top of the client found in the context
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, retryClient.HTTPClient)
+
+	if t.Source != nil {
+		retryClient.HTTPClient = oauth2.NewClient(ctx, t.Source)
+	} else if t := t.Token; t != nil {
 		t := oauth2.StaticTokenSource(
 			&oauth2.Token{AccessToken: *t},
 		)
 		retryClient.HTTPClient = oauth2.NewClient(ctx, t)
 	}
 
-	return github.NewClient(retryClient.StandardClient()), ctx
+	return retryClient.StandardClient(), ctx
 }
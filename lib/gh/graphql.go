@@ -1,38 +1,91 @@
 package gh
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"os/exec"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/katbyte/gogo-repo-stats/lib/chttp"
+	"github.com/katbyte/gogo-repo-stats/lib/clog"
 )
 
-func (t Token) GraphQLQueryUnmarshal(query string, params [][]string, data interface{}) error {
-	out, err := t.GraphQLQuery(query, params)
+// GraphQLURL is the github.com GraphQL api, enterprise servers use Host.GraphQL
+const GraphQLURL = "https://api.github.com/graphql"
+
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Type    string        `json:"type"`
+		Message string        `json:"message"`
+		Path    []interface{} `json:"path"`
+	} `json:"errors"`
+}
+
+// GraphQLQueryUnmarshal runs query and unmarshals the response's data into data.
+func (t Token) GraphQLQueryUnmarshal(query string, variables map[string]interface{}, data interface{}) error {
+	out, err := t.GraphQLQuery(query, variables)
 	if err != nil {
 		return err
 	}
 	return json.Unmarshal([]byte(*out), data)
 }
 
-func (t Token) GraphQLQuery(query string, params [][]string) (*string, error) {
-	args := []string{"api", "graphql", "-f", query}
+// GraphQLQuery posts query to the github GraphQL API and returns the raw json of the data field.
+func (t Token) GraphQLQuery(query string, variables map[string]interface{}) (*string, error) {
+	replaying := t.Cassette != nil && t.Cassette.Mode == chttp.CassetteReplay
+	if t.Token == nil && t.Source == nil && t.Pool == nil && !replaying {
+		return nil, fmt.Errorf("the graphql api requires a token")
+	}
 
-	for _, p := range params {
-		args = append(args, p[0])
-		args = append(args, p[1])
+	body, err := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	if err != nil {
+		return nil, fmt.Errorf("encoding graphql query: %w", err)
 	}
 
-	ghc := exec.Command("gh", args...)
-	if t.Token != nil {
-		ghc.Env = []string{"GITHUB_TOKEN=" + *t.Token}
+	client, ctx := t.NewHTTPClient()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.Host.GraphQL(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("building graphql request: %w", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
 
-	out, err := ghc.CombinedOutput()
-	s := string(out)
+	clog.Log.Debugf("GraphQL query with %v", variables)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("graphql request: %w", err)
+	}
+	defer resp.Body.Close()
 
+	raw, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return &s, fmt.Errorf("graph ql query error: %s\n\n %s\n\n%s", err, query, out)
+		return nil, fmt.Errorf("reading graphql response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("graph ql query error: %s\n\n %s\n\n%s", resp.Status, query, raw)
+	}
+
+	var gr graphQLResponse
+	if err := json.Unmarshal(raw, &gr); err != nil {
+		return nil, fmt.Errorf("decoding graphql response: %w", err)
+	}
+
+	if len(gr.Errors) > 0 {
+		var msgs []string
+		for _, e := range gr.Errors {
+			msgs = append(msgs, fmt.Sprintf("%s %v", e.Message, e.Path))
+		}
+		return nil, fmt.Errorf("graph ql query error: %s", strings.Join(msgs, ", "))
 	}
 
+	s := string(gr.Data)
 	return &s, nil
 }
@@ -0,0 +1,1038 @@
+package gh
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/katbyte/gogo-repo-stats/lib/clog"
+	"github.com/katbyte/gogo-repo-stats/lib/pointer"
+)
+
+// the graphql backend pulls a page of prs or issues along with their labels, milestone, reviews and timeline in a
+// single query. everything is converted back into go-github types so it can be stored exactly like the REST data
+
+type PullRequestWithTimeline struct {
+	PullRequest    *github.PullRequest
+	Events         []TimelineEvent
+	Reviews        []github.PullRequestReview
+	Files          []github.CommitFile
+	Comments       []github.IssueComment
+	ReviewComments []github.PullRequestComment
+
+	// nil when the pr has more commits than fit in the query, they have to be listed on their own
+	Commits *[]PullRequestCommit
+}
+
+// PullRequestCommit is a commit on a PR along with the combined state of its check runs & statuses (SUCCESS, FAILURE,
+// PENDING etc), empty when nothing has run on it.
+type PullRequestCommit struct {
+	SHA    string
+	Checks string
+}
+
+type IssueWithTimeline struct {
+	Issue    *github.Issue
+	Events   []TimelineEvent
+	Comments []github.IssueComment
+}
+
+const graphQLTimelineIssueItemTypes = `LABELED_EVENT, UNLABELED_EVENT, MILESTONED_EVENT, DEMILESTONED_EVENT, CLOSED_EVENT, REOPENED_EVENT,
+	ASSIGNED_EVENT, UNASSIGNED_EVENT, RENAMED_TITLE_EVENT, CROSS_REFERENCED_EVENT, LOCKED_EVENT, UNLOCKED_EVENT, ISSUE_COMMENT`
+
+const graphQLTimelinePRItemTypes = graphQLTimelineIssueItemTypes + `, MERGED_EVENT, PULL_REQUEST_REVIEW, REVIEW_REQUESTED_EVENT,
+	REVIEW_REQUEST_REMOVED_EVENT, READY_FOR_REVIEW_EVENT, CONVERT_TO_DRAFT_EVENT, HEAD_REF_FORCE_PUSHED_EVENT`
+
+const graphQLTimelineIssueFields = `
+	__typename
+	... on Node { id }
+	... on LabeledEvent { createdAt actor { login } label { name } }
+	... on UnlabeledEvent { createdAt actor { login } label { name } }
+	... on MilestonedEvent { createdAt actor { login } milestoneTitle }
+	... on DemilestonedEvent { createdAt actor { login } milestoneTitle }
+	... on ClosedEvent { createdAt actor { login } }
+	... on ReopenedEvent { createdAt actor { login } }
+	... on AssignedEvent { createdAt actor { login } assignee { ... on Actor { login } } }
+	... on UnassignedEvent { createdAt actor { login } assignee { ... on Actor { login } } }
+	... on RenamedTitleEvent { createdAt actor { login } previousTitle currentTitle }
+	... on CrossReferencedEvent { createdAt actor { login } source {
+		__typename
+		... on Issue { number repository { nameWithOwner } }
+		... on PullRequest { number repository { nameWithOwner } }
+	} }
+	... on LockedEvent { createdAt actor { login } lockReason }
+	... on UnlockedEvent { createdAt actor { login } }
+	... on IssueComment { createdAt author { login } body }
+`
+
+const graphQLTimelinePRFields = graphQLTimelineIssueFields + `
+	... on MergedEvent { createdAt actor { login } commit { oid } }
+	... on PullRequestReview { submittedAt author { login } state body }
+	... on ReviewRequestedEvent { createdAt actor { login } requestedReviewer { __typename ... on Actor { login } ... on Team { slug } } }
+	... on ReviewRequestRemovedEvent { createdAt actor { login } requestedReviewer { __typename ... on Actor { login } ... on Team { slug } } }
+	... on ReadyForReviewEvent { createdAt actor { login } }
+	... on ConvertToDraftEvent { createdAt actor { login } }
+	... on HeadRefForcePushedEvent { createdAt actor { login } beforeCommit { oid } afterCommit { oid } }
+`
+
+const graphQLCommentFields = `
+	databaseId authorAssociation createdAt updatedAt body url
+	author { login }
+`
+
+const graphQLReviewCommentFields = graphQLCommentFields + `
+	path line originalLine
+	replyTo { databaseId }
+`
+
+// review comments are nested in the reviews, keep the page small so a page of prs stays under the node limit
+const graphQLReviewFields = `
+	id databaseId state submittedAt body url authorAssociation
+	author { login }
+	commit { oid }
+	comments(first: 30) {
+		pageInfo { hasNextPage endCursor }
+		nodes {` + graphQLReviewCommentFields + `}
+	}
+`
+
+const graphQLFileFields = `
+	path additions deletions changeType
+`
+
+const graphQLPRFields = `
+	number title state createdAt updatedAt closedAt merged isDraft headRefOid
+	mergeCommit { oid }
+	additions deletions changedFiles
+	commits(last: 100) {
+		totalCount
+		nodes { commit { oid statusCheckRollup { state } } }
+	}
+	author { login }
+	mergedBy { login }
+	milestone { title }
+	labels(first: 100) { nodes { name } }
+	timelineItems(first: 100, itemTypes: [` + graphQLTimelinePRItemTypes + `]) {
+		pageInfo { hasNextPage endCursor }
+		nodes {` + graphQLTimelinePRFields + `}
+	}
+	reviews(first: 100) {
+		pageInfo { hasNextPage endCursor }
+		nodes {` + graphQLReviewFields + `}
+	}
+	files(first: 100) {
+		pageInfo { hasNextPage endCursor }
+		nodes {` + graphQLFileFields + `}
+	}
+	comments(first: 100) {
+		pageInfo { hasNextPage endCursor }
+		nodes {` + graphQLCommentFields + `}
+	}
+`
+
+const graphQLIssueFields = `
+	number title state createdAt updatedAt closedAt
+	author { login }
+	milestone { title }
+	labels(first: 100) { nodes { name } }
+	timelineItems(first: 100, itemTypes: [` + graphQLTimelineIssueItemTypes + `]) {
+		pageInfo { hasNextPage endCursor }
+		nodes {` + graphQLTimelineIssueFields + `}
+	}
+	comments(first: 100) {
+		pageInfo { hasNextPage endCursor }
+		nodes {` + graphQLCommentFields + `}
+	}
+`
+
+const graphQLQueryPRs = `
+query($owner: String!, $name: String!, $cursor: String) {
+	repository(owner: $owner, name: $name) {
+		pullRequests(first: 50, after: $cursor, orderBy: {field: UPDATED_AT, direction: DESC}) {
+			pageInfo { hasNextPage endCursor }
+			nodes {` + graphQLPRFields + `}
+		}
+	}
+}`
+
+const graphQLQueryIssues = `
+query($owner: String!, $name: String!, $cursor: String, $since: DateTime) {
+	repository(owner: $owner, name: $name) {
+		issues(first: 50, after: $cursor, orderBy: {field: UPDATED_AT, direction: DESC}, filterBy: {since: $since}) {
+			pageInfo { hasNextPage endCursor }
+			nodes {` + graphQLIssueFields + `}
+		}
+	}
+}`
+
+const graphQLQueryPRTimeline = `
+query($owner: String!, $name: String!, $number: Int!, $cursor: String) {
+	repository(owner: $owner, name: $name) {
+		item: pullRequest(number: $number) {
+			timelineItems(first: 100, after: $cursor, itemTypes: [` + graphQLTimelinePRItemTypes + `]) {
+				pageInfo { hasNextPage endCursor }
+				nodes {` + graphQLTimelinePRFields + `}
+			}
+		}
+	}
+}`
+
+const graphQLQueryIssueTimeline = `
+query($owner: String!, $name: String!, $number: Int!, $cursor: String) {
+	repository(owner: $owner, name: $name) {
+		item: issue(number: $number) {
+			timelineItems(first: 100, after: $cursor, itemTypes: [` + graphQLTimelineIssueItemTypes + `]) {
+				pageInfo { hasNextPage endCursor }
+				nodes {` + graphQLTimelineIssueFields + `}
+			}
+		}
+	}
+}`
+
+const graphQLQueryPRReviews = `
+query($owner: String!, $name: String!, $number: Int!, $cursor: String) {
+	repository(owner: $owner, name: $name) {
+		item: pullRequest(number: $number) {
+			reviews(first: 100, after: $cursor) {
+				pageInfo { hasNextPage endCursor }
+				nodes {` + graphQLReviewFields + `}
+			}
+		}
+	}
+}`
+
+const graphQLQueryPRFiles = `
+query($owner: String!, $name: String!, $number: Int!, $cursor: String) {
+	repository(owner: $owner, name: $name) {
+		item: pullRequest(number: $number) {
+			files(first: 100, after: $cursor) {
+				pageInfo { hasNextPage endCursor }
+				nodes {` + graphQLFileFields + `}
+			}
+		}
+	}
+}`
+
+const graphQLQueryPRComments = `
+query($owner: String!, $name: String!, $number: Int!, $cursor: String) {
+	repository(owner: $owner, name: $name) {
+		item: pullRequest(number: $number) {
+			comments(first: 100, after: $cursor) {
+				pageInfo { hasNextPage endCursor }
+				nodes {` + graphQLCommentFields + `}
+			}
+		}
+	}
+}`
+
+const graphQLQueryIssueComments = `
+query($owner: String!, $name: String!, $number: Int!, $cursor: String) {
+	repository(owner: $owner, name: $name) {
+		item: issue(number: $number) {
+			comments(first: 100, after: $cursor) {
+				pageInfo { hasNextPage endCursor }
+				nodes {` + graphQLCommentFields + `}
+			}
+		}
+	}
+}`
+
+const graphQLQueryReviewComments = `
+query($id: ID!, $cursor: String) {
+	node(id: $id) {
+		... on PullRequestReview {
+			comments(first: 100, after: $cursor) {
+				pageInfo { hasNextPage endCursor }
+				nodes {` + graphQLReviewCommentFields + `}
+			}
+		}
+	}
+}`
+
+type graphQLPageInfo struct {
+	HasNextPage bool    `json:"hasNextPage"`
+	EndCursor   *string `json:"endCursor"`
+}
+
+type graphQLLogin struct {
+	Login string `json:"login"`
+}
+
+type graphQLTimelineItem struct {
+	Typename    string        `json:"__typename"`
+	ID          *string       `json:"id"`
+	CreatedAt   *time.Time    `json:"createdAt"`
+	SubmittedAt *time.Time    `json:"submittedAt"`
+	Actor       *graphQLLogin `json:"actor"`
+	Author      *graphQLLogin `json:"author"`
+	Label       *struct {
+		Name string `json:"name"`
+	} `json:"label"`
+	MilestoneTitle *string        `json:"milestoneTitle"`
+	State          *string        `json:"state"`
+	Body           *string        `json:"body"`
+	BeforeCommit   *graphQLCommit `json:"beforeCommit"`
+	AfterCommit    *graphQLCommit `json:"afterCommit"`
+	Commit         *graphQLCommit `json:"commit"`
+	Assignee       *graphQLLogin  `json:"assignee"`
+	PreviousTitle  *string        `json:"previousTitle"`
+	CurrentTitle   *string        `json:"currentTitle"`
+	LockReason     *string        `json:"lockReason"`
+	Source         *struct {
+		Typename   string `json:"__typename"`
+		Number     int    `json:"number"`
+		Repository struct {
+			NameWithOwner string `json:"nameWithOwner"`
+		} `json:"repository"`
+	} `json:"source"`
+	RequestedReviewer *struct {
+		Typename string `json:"__typename"`
+		Login    string `json:"login"`
+		Slug     string `json:"slug"`
+	} `json:"requestedReviewer"`
+}
+
+type graphQLCommit struct {
+	OID string `json:"oid"`
+}
+
+type graphQLTimeline struct {
+	PageInfo graphQLPageInfo       `json:"pageInfo"`
+	Nodes    []graphQLTimelineItem `json:"nodes"`
+}
+
+type graphQLComment struct {
+	DatabaseID        int64         `json:"databaseId"`
+	AuthorAssociation string        `json:"authorAssociation"`
+	CreatedAt         time.Time     `json:"createdAt"`
+	UpdatedAt         *time.Time    `json:"updatedAt"`
+	Body              string        `json:"body"`
+	URL               string        `json:"url"`
+	Author            *graphQLLogin `json:"author"`
+
+	// review comments only
+	Path         *string `json:"path"`
+	Line         *int    `json:"line"`
+	OriginalLine *int    `json:"originalLine"`
+	ReplyTo      *struct {
+		DatabaseID int64 `json:"databaseId"`
+	} `json:"replyTo"`
+}
+
+type graphQLComments struct {
+	PageInfo graphQLPageInfo  `json:"pageInfo"`
+	Nodes    []graphQLComment `json:"nodes"`
+}
+
+type graphQLReview struct {
+	ID                string          `json:"id"`
+	DatabaseID        int64           `json:"databaseId"`
+	State             string          `json:"state"`
+	SubmittedAt       *time.Time      `json:"submittedAt"`
+	Body              string          `json:"body"`
+	URL               string          `json:"url"`
+	AuthorAssociation string          `json:"authorAssociation"`
+	Author            *graphQLLogin   `json:"author"`
+	Commit            *graphQLCommit  `json:"commit"`
+	Comments          graphQLComments `json:"comments"`
+}
+
+type graphQLReviews struct {
+	PageInfo graphQLPageInfo `json:"pageInfo"`
+	Nodes    []graphQLReview `json:"nodes"`
+}
+
+type graphQLFiles struct {
+	PageInfo graphQLPageInfo `json:"pageInfo"`
+	Nodes    []struct {
+		Path       string `json:"path"`
+		Additions  int    `json:"additions"`
+		Deletions  int    `json:"deletions"`
+		ChangeType string `json:"changeType"`
+	} `json:"nodes"`
+}
+
+type graphQLItem struct {
+	Number    int           `json:"number"`
+	Title     string        `json:"title"`
+	State     string        `json:"state"`
+	CreatedAt time.Time     `json:"createdAt"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+	ClosedAt  *time.Time    `json:"closedAt"`
+	Author    *graphQLLogin `json:"author"`
+	Milestone *struct {
+		Title string `json:"title"`
+	} `json:"milestone"`
+	Labels struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	TimelineItems graphQLTimeline `json:"timelineItems"`
+	Comments      graphQLComments `json:"comments"`
+
+	// prs only
+	Merged       bool           `json:"merged"`
+	IsDraft      bool           `json:"isDraft"`
+	MergedBy     *graphQLLogin  `json:"mergedBy"`
+	HeadRefOID   *string        `json:"headRefOid"`
+	MergeCommit  *graphQLCommit `json:"mergeCommit"`
+	Additions    *int           `json:"additions"`
+	Deletions    *int           `json:"deletions"`
+	ChangedFiles *int           `json:"changedFiles"`
+	Commits      *struct {
+		TotalCount int `json:"totalCount"`
+		Nodes      []struct {
+			Commit struct {
+				OID               string `json:"oid"`
+				StatusCheckRollup *struct {
+					State string `json:"state"`
+				} `json:"statusCheckRollup"`
+			} `json:"commit"`
+		} `json:"nodes"`
+	} `json:"commits"`
+	Reviews graphQLReviews `json:"reviews"`
+	Files   graphQLFiles   `json:"files"`
+}
+
+type graphQLItemPage struct {
+	PageInfo graphQLPageInfo `json:"pageInfo"`
+	Nodes    []graphQLItem   `json:"nodes"`
+}
+
+// graphQLTimelineEvents maps the graphql timeline type names onto the REST event names the cache & stats expect.
+var graphQLTimelineEvents = map[string]string{
+	"LabeledEvent":              "labeled",
+	"UnlabeledEvent":            "unlabeled",
+	"MilestonedEvent":           "milestoned",
+	"DemilestonedEvent":         "demilestoned",
+	"ClosedEvent":               "closed",
+	"ReopenedEvent":             "reopened",
+	"AssignedEvent":             "assigned",
+	"UnassignedEvent":           "unassigned",
+	"RenamedTitleEvent":         "renamed",
+	"CrossReferencedEvent":      "cross-referenced",
+	"IssueComment":              "commented",
+	"MergedEvent":               "merged",
+	"PullRequestReview":         "reviewed",
+	"ReviewRequestedEvent":      "review_requested",
+	"ReadyForReviewEvent":       "ready_for_review",
+	"ConvertToDraftEvent":       "convert_to_draft",
+	"HeadRefForcePushedEvent":   "head_ref_force_pushed",
+	"ReviewRequestRemovedEvent": "review_request_removed",
+	"LockedEvent":               "locked",
+	"UnlockedEvent":             "unlocked",
+}
+
+// graphQLLockReasons maps the graphql lock reasons onto REST's
+var graphQLLockReasons = map[string]string{
+	"OFF_TOPIC":  "off-topic",
+	"TOO_HEATED": "too heated",
+	"RESOLVED":   "resolved",
+	"SPAM":       "spam",
+}
+
+// ListPullRequestsWithTimelineGraphQL pages through PRs most recently updated first, with their complete timelines,
+// starting after the after cursor and stopping once it passes since. cb is given the cursor at the end of each page.
+func (r Repo) ListPullRequestsWithTimelineGraphQL(since time.Time, after *string, cb func([]PullRequestWithTimeline, *string) error) error {
+	cursor := after
+
+	for page := 1; ; page++ {
+		clog.Log.Debugf("GraphQL listing PRs for %s/%s (Page %d)...", r.Owner, r.Name, page)
+
+		var data struct {
+			Repository struct {
+				PullRequests graphQLItemPage `json:"pullRequests"`
+			} `json:"repository"`
+		}
+		err := r.GraphQLQueryUnmarshal(graphQLQueryPRs, map[string]interface{}{
+			"owner":  r.Owner,
+			"name":   r.Name,
+			"cursor": cursor,
+		}, &data)
+		if err != nil {
+			return fmt.Errorf("unable to list PRs for %s/%s (Page %d): %w", r.Owner, r.Name, page, err)
+		}
+
+		done := false
+		var prs []PullRequestWithTimeline
+		for _, n := range data.Repository.PullRequests.Nodes {
+			if !since.IsZero() && !n.UpdatedAt.After(since) {
+				done = true
+				break
+			}
+
+			events, err := r.graphQLAllTimelineItems(graphQLQueryPRTimeline, n)
+			if err != nil {
+				return err
+			}
+
+			reviews, reviewComments, err := r.graphQLAllReviews(n)
+			if err != nil {
+				return err
+			}
+
+			comments, err := r.graphQLAllComments(graphQLQueryPRComments, n)
+			if err != nil {
+				return err
+			}
+
+			files, err := r.graphQLAllFiles(n)
+			if err != nil {
+				return err
+			}
+
+			prs = append(prs, PullRequestWithTimeline{
+				PullRequest:    n.toPullRequest(),
+				Events:         events,
+				Reviews:        reviews,
+				Files:          files,
+				Comments:       comments,
+				ReviewComments: reviewComments,
+				Commits:        n.toPullRequestCommits(),
+			})
+		}
+
+		if err = cb(prs, data.Repository.PullRequests.PageInfo.EndCursor); err != nil {
+			return fmt.Errorf("callback failed for %s/%s (Page %d): %w", r.Owner, r.Name, page, err)
+		}
+
+		pi := data.Repository.PullRequests.PageInfo
+		if done || !pi.HasNextPage {
+			break
+		}
+		cursor = pi.EndCursor
+	}
+
+	return nil
+}
+
+// ListIssuesWithTimelineGraphQL pages through issues (never PRs) updated after since, most recently updated first,
+// starting after the after cursor. cb is given the cursor at the end of each page.
+func (r Repo) ListIssuesWithTimelineGraphQL(since time.Time, after *string, cb func([]IssueWithTimeline, *string) error) error {
+	cursor := after
+
+	var sinceVar *string
+	if !since.IsZero() {
+		sinceVar = pointer.To(since.UTC().Format(time.RFC3339))
+	}
+
+	for page := 1; ; page++ {
+		clog.Log.Debugf("GraphQL listing issues for %s/%s (Page %d)...", r.Owner, r.Name, page)
+
+		var data struct {
+			Repository struct {
+				Issues graphQLItemPage `json:"issues"`
+			} `json:"repository"`
+		}
+		err := r.GraphQLQueryUnmarshal(graphQLQueryIssues, map[string]interface{}{
+			"owner":  r.Owner,
+			"name":   r.Name,
+			"cursor": cursor,
+			"since":  sinceVar,
+		}, &data)
+		if err != nil {
+			return fmt.Errorf("unable to list issues for %s/%s (Page %d): %w", r.Owner, r.Name, page, err)
+		}
+
+		// since is inclusive, drop the items we already synced at exactly the watermark
+		done := false
+		var issues []IssueWithTimeline
+		for _, n := range data.Repository.Issues.Nodes {
+			if !since.IsZero() && !n.UpdatedAt.After(since) {
+				done = true
+				break
+			}
+
+			events, err := r.graphQLAllTimelineItems(graphQLQueryIssueTimeline, n)
+			if err != nil {
+				return err
+			}
+
+			comments, err := r.graphQLAllComments(graphQLQueryIssueComments, n)
+			if err != nil {
+				return err
+			}
+
+			issues = append(issues, IssueWithTimeline{
+				Issue:    n.toIssue(),
+				Events:   events,
+				Comments: comments,
+			})
+		}
+
+		if err = cb(issues, data.Repository.Issues.PageInfo.EndCursor); err != nil {
+			return fmt.Errorf("callback failed for %s/%s (Page %d): %w", r.Owner, r.Name, page, err)
+		}
+
+		pi := data.Repository.Issues.PageInfo
+		if done || !pi.HasNextPage {
+			break
+		}
+		cursor = pi.EndCursor
+	}
+
+	return nil
+}
+
+// graphQLAllTimelineItems converts the first page of an item's timeline and fetches any remaining pages.
+func (r Repo) graphQLAllTimelineItems(query string, item graphQLItem) ([]TimelineEvent, error) {
+	events := item.TimelineItems.toTimeline()
+
+	pi := item.TimelineItems.PageInfo
+	for pi.HasNextPage {
+		clog.Log.Debugf("GraphQL listing more timeline for %s/%s/%d...", r.Owner, r.Name, item.Number)
+
+		var data struct {
+			Repository struct {
+				Item struct {
+					TimelineItems graphQLTimeline `json:"timelineItems"`
+				} `json:"item"`
+			} `json:"repository"`
+		}
+		err := r.GraphQLQueryUnmarshal(query, map[string]interface{}{
+			"owner":  r.Owner,
+			"name":   r.Name,
+			"number": item.Number,
+			"cursor": pi.EndCursor,
+		}, &data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list timeline for %s/%s/%d: %w", r.Owner, r.Name, item.Number, err)
+		}
+
+		events = append(events, data.Repository.Item.TimelineItems.toTimeline()...)
+		pi = data.Repository.Item.TimelineItems.PageInfo
+	}
+
+	return events, nil
+}
+
+// graphQLAllReviews converts the first page of a PR's reviews and fetches any remaining pages, along with all the
+// comments left as part of them.
+func (r Repo) graphQLAllReviews(item graphQLItem) ([]github.PullRequestReview, []github.PullRequestComment, error) {
+	reviews := item.Reviews.toReviews()
+	comments, err := r.graphQLAllReviewComments(item.Number, item.Reviews.Nodes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pi := item.Reviews.PageInfo
+	for pi.HasNextPage {
+		clog.Log.Debugf("GraphQL listing more reviews for %s/%s/%d...", r.Owner, r.Name, item.Number)
+
+		var data struct {
+			Repository struct {
+				Item struct {
+					Reviews graphQLReviews `json:"reviews"`
+				} `json:"item"`
+			} `json:"repository"`
+		}
+		err := r.GraphQLQueryUnmarshal(graphQLQueryPRReviews, map[string]interface{}{
+			"owner":  r.Owner,
+			"name":   r.Name,
+			"number": item.Number,
+			"cursor": pi.EndCursor,
+		}, &data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to list reviews for %s/%s/%d: %w", r.Owner, r.Name, item.Number, err)
+		}
+
+		more, err := r.graphQLAllReviewComments(item.Number, data.Repository.Item.Reviews.Nodes)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		reviews = append(reviews, data.Repository.Item.Reviews.toReviews()...)
+		comments = append(comments, more...)
+		pi = data.Repository.Item.Reviews.PageInfo
+	}
+
+	return reviews, comments, nil
+}
+
+// graphQLAllReviewComments converts the first page of comments of each review and fetches any remaining pages.
+func (r Repo) graphQLAllReviewComments(number int, reviews []graphQLReview) ([]github.PullRequestComment, error) {
+	var comments []github.PullRequestComment
+
+	for _, rv := range reviews {
+		comments = append(comments, rv.Comments.toReviewComments()...)
+
+		pi := rv.Comments.PageInfo
+		for pi.HasNextPage {
+			clog.Log.Debugf("GraphQL listing more review comments for %s/%s/%d...", r.Owner, r.Name, number)
+
+			var data struct {
+				Node struct {
+					Comments graphQLComments `json:"comments"`
+				} `json:"node"`
+			}
+			err := r.GraphQLQueryUnmarshal(graphQLQueryReviewComments, map[string]interface{}{
+				"id":     rv.ID,
+				"cursor": pi.EndCursor,
+			}, &data)
+			if err != nil {
+				return nil, fmt.Errorf("unable to list review comments for %s/%s/%d: %w", r.Owner, r.Name, number, err)
+			}
+
+			comments = append(comments, data.Node.Comments.toReviewComments()...)
+			pi = data.Node.Comments.PageInfo
+		}
+	}
+
+	return comments, nil
+}
+
+// graphQLAllComments converts the first page of an item's comments and fetches any remaining pages.
+func (r Repo) graphQLAllComments(query string, item graphQLItem) ([]github.IssueComment, error) {
+	comments := item.Comments.toIssueComments()
+
+	pi := item.Comments.PageInfo
+	for pi.HasNextPage {
+		clog.Log.Debugf("GraphQL listing more comments for %s/%s/%d...", r.Owner, r.Name, item.Number)
+
+		var data struct {
+			Repository struct {
+				Item struct {
+					Comments graphQLComments `json:"comments"`
+				} `json:"item"`
+			} `json:"repository"`
+		}
+		err := r.GraphQLQueryUnmarshal(query, map[string]interface{}{
+			"owner":  r.Owner,
+			"name":   r.Name,
+			"number": item.Number,
+			"cursor": pi.EndCursor,
+		}, &data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list comments for %s/%s/%d: %w", r.Owner, r.Name, item.Number, err)
+		}
+
+		comments = append(comments, data.Repository.Item.Comments.toIssueComments()...)
+		pi = data.Repository.Item.Comments.PageInfo
+	}
+
+	return comments, nil
+}
+
+func (cs graphQLComments) toIssueComments() []github.IssueComment {
+	var comments []github.IssueComment
+
+	for _, n := range cs.Nodes {
+		cm := github.IssueComment{
+			ID:                pointer.To(n.DatabaseID),
+			Body:              pointer.To(n.Body),
+			CreatedAt:         pointer.To(n.CreatedAt),
+			UpdatedAt:         n.UpdatedAt,
+			AuthorAssociation: pointer.To(n.AuthorAssociation),
+			HTMLURL:           pointer.To(n.URL),
+		}
+		if n.Author != nil {
+			cm.User = &github.User{Login: pointer.To(n.Author.Login)}
+		}
+
+		comments = append(comments, cm)
+	}
+
+	return comments
+}
+
+func (cs graphQLComments) toReviewComments() []github.PullRequestComment {
+	var comments []github.PullRequestComment
+
+	for _, n := range cs.Nodes {
+		cm := github.PullRequestComment{
+			ID:                pointer.To(n.DatabaseID),
+			Body:              pointer.To(n.Body),
+			CreatedAt:         pointer.To(n.CreatedAt),
+			UpdatedAt:         n.UpdatedAt,
+			AuthorAssociation: pointer.To(n.AuthorAssociation),
+			HTMLURL:           pointer.To(n.URL),
+			Path:              n.Path,
+			Line:              n.Line,
+			OriginalLine:      n.OriginalLine,
+		}
+		if n.Author != nil {
+			cm.User = &github.User{Login: pointer.To(n.Author.Login)}
+		}
+		if n.ReplyTo != nil {
+			cm.InReplyTo = pointer.To(n.ReplyTo.DatabaseID)
+		}
+
+		comments = append(comments, cm)
+	}
+
+	return comments
+}
+
+// graphQLAllFiles converts the first page of a PR's files and fetches any remaining pages.
+func (r Repo) graphQLAllFiles(item graphQLItem) ([]github.CommitFile, error) {
+	files := item.Files.toCommitFiles()
+
+	pi := item.Files.PageInfo
+	for pi.HasNextPage {
+		clog.Log.Debugf("GraphQL listing more files for %s/%s/%d...", r.Owner, r.Name, item.Number)
+
+		var data struct {
+			Repository struct {
+				Item struct {
+					Files graphQLFiles `json:"files"`
+				} `json:"item"`
+			} `json:"repository"`
+		}
+		err := r.GraphQLQueryUnmarshal(graphQLQueryPRFiles, map[string]interface{}{
+			"owner":  r.Owner,
+			"name":   r.Name,
+			"number": item.Number,
+			"cursor": pi.EndCursor,
+		}, &data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list files for %s/%s/%d: %w", r.Owner, r.Name, item.Number, err)
+		}
+
+		files = append(files, data.Repository.Item.Files.toCommitFiles()...)
+		pi = data.Repository.Item.Files.PageInfo
+	}
+
+	return files, nil
+}
+
+// graphQLFileStatuses maps the graphql change types onto the REST file statuses.
+var graphQLFileStatuses = map[string]string{
+	"ADDED":    "added",
+	"DELETED":  "removed",
+	"MODIFIED": "modified",
+	"RENAMED":  "renamed",
+	"COPIED":   "copied",
+	"CHANGED":  "changed",
+}
+
+func (fs graphQLFiles) toCommitFiles() []github.CommitFile {
+	var files []github.CommitFile
+
+	for _, n := range fs.Nodes {
+		status, ok := graphQLFileStatuses[n.ChangeType]
+		if !ok {
+			status = strings.ToLower(n.ChangeType)
+		}
+
+		files = append(files, github.CommitFile{
+			Filename:  pointer.To(n.Path),
+			Status:    pointer.To(status),
+			Additions: pointer.To(n.Additions),
+			Deletions: pointer.To(n.Deletions),
+			Changes:   pointer.To(n.Additions + n.Deletions),
+		})
+	}
+
+	return files
+}
+
+func (rs graphQLReviews) toReviews() []github.PullRequestReview {
+	var reviews []github.PullRequestReview
+
+	for i, n := range rs.Nodes {
+		// pending reviews are not submitted, REST hides them from everyone but their author
+		if n.SubmittedAt == nil {
+			clog.Log.Debugf("reviews[%d] has not been submitted, skipping", i)
+			continue
+		}
+
+		rv := github.PullRequestReview{
+			ID:                pointer.To(n.DatabaseID),
+			State:             pointer.To(n.State),
+			SubmittedAt:       n.SubmittedAt,
+			Body:              pointer.To(n.Body),
+			HTMLURL:           pointer.To(n.URL),
+			AuthorAssociation: pointer.To(n.AuthorAssociation),
+		}
+		if n.Author != nil {
+			rv.User = &github.User{Login: pointer.To(n.Author.Login)}
+		}
+		if n.Commit != nil {
+			rv.CommitID = pointer.To(n.Commit.OID)
+		}
+
+		reviews = append(reviews, rv)
+	}
+
+	return reviews
+}
+
+func (t graphQLTimeline) toTimeline() []TimelineEvent {
+	var events []TimelineEvent
+
+	for i, n := range t.Nodes {
+		event, ok := graphQLTimelineEvents[n.Typename]
+		if !ok {
+			clog.Log.Debugf("timeline[%d] has unknown type %s, skipping", i, n.Typename)
+			continue
+		}
+
+		if n.CreatedAt == nil && n.SubmittedAt == nil {
+			clog.Log.Debugf("timeline[%d] has no date, skipping", i)
+			continue
+		}
+
+		e := TimelineEvent{Timeline: github.Timeline{
+			Event:       pointer.To(event),
+			CreatedAt:   n.CreatedAt,
+			SubmittedAt: n.SubmittedAt,
+			Body:        n.Body,
+		}, NodeID: n.ID}
+
+		// REST returns comments & reviews with a user and everything else with an actor
+		if n.Author != nil {
+			e.User = &github.User{Login: pointer.To(n.Author.Login)}
+		} else if n.Actor != nil {
+			e.Actor = &github.User{Login: pointer.To(n.Actor.Login)}
+		}
+
+		if n.Label != nil {
+			e.Label = &github.Label{Name: pointer.To(n.Label.Name)}
+		}
+		if n.MilestoneTitle != nil {
+			e.Milestone = &github.Milestone{Title: n.MilestoneTitle}
+		}
+		if n.State != nil {
+			e.State = pointer.To(strings.ToLower(*n.State))
+		}
+
+		// force pushes carry the new head as the commit like REST, and the head it replaced as the sha
+		if n.AfterCommit != nil {
+			e.CommitID = pointer.To(n.AfterCommit.OID)
+		}
+		if n.BeforeCommit != nil {
+			e.SHA = pointer.To(n.BeforeCommit.OID)
+		}
+		if n.Commit != nil {
+			e.CommitID = pointer.To(n.Commit.OID)
+		}
+
+		if n.Assignee != nil {
+			e.Assignee = &github.User{Login: pointer.To(n.Assignee.Login)}
+		}
+		if n.PreviousTitle != nil || n.CurrentTitle != nil {
+			e.Rename = &github.Rename{From: n.PreviousTitle, To: n.CurrentTitle}
+		}
+		if n.LockReason != nil {
+			e.LockReason = pointer.To(graphQLLockReasons[*n.LockReason])
+		}
+
+		if r := n.RequestedReviewer; r != nil {
+			if r.Typename == "Team" {
+				e.RequestedTeam = &github.Team{Slug: pointer.To(r.Slug)}
+			} else {
+				e.Reviewer = &github.User{Login: pointer.To(r.Login)}
+			}
+		}
+
+		// REST tells prs from issues by their pull_request links
+		if s := n.Source; s != nil && (s.Typename == "Issue" || s.Typename == "PullRequest") {
+			issue := &github.Issue{
+				Number:     pointer.To(s.Number),
+				Repository: &github.Repository{FullName: pointer.To(s.Repository.NameWithOwner)},
+			}
+			if s.Typename == "PullRequest" {
+				issue.PullRequestLinks = &github.PullRequestLinks{}
+			}
+			e.Source = &github.Source{Issue: issue}
+		}
+
+		events = append(events, e)
+	}
+
+	return events
+}
+
+func (n graphQLItem) labels() []*github.Label {
+	var labels []*github.Label
+	for _, l := range n.Labels.Nodes {
+		labels = append(labels, &github.Label{Name: pointer.To(l.Name)})
+	}
+	return labels
+}
+
+// toPullRequestCommits returns the commits of a PR, or nil if it has more than the query fetched.
+func (n graphQLItem) toPullRequestCommits() *[]PullRequestCommit {
+	if n.Commits == nil || n.Commits.TotalCount > len(n.Commits.Nodes) {
+		return nil
+	}
+
+	commits := []PullRequestCommit{}
+	for _, cn := range n.Commits.Nodes {
+		cm := PullRequestCommit{SHA: cn.Commit.OID}
+		if cn.Commit.StatusCheckRollup != nil {
+			cm.Checks = cn.Commit.StatusCheckRollup.State
+		}
+		commits = append(commits, cm)
+	}
+
+	return &commits
+}
+
+func (n graphQLItem) toPullRequest() *github.PullRequest {
+	// REST only has open & closed, merged prs are closed
+	state := strings.ToLower(n.State)
+	if state == "merged" {
+		state = "closed"
+	}
+
+	pr := &github.PullRequest{
+		Number:    pointer.To(n.Number),
+		Title:     pointer.To(n.Title),
+		State:     pointer.To(state),
+		CreatedAt: pointer.To(n.CreatedAt),
+		UpdatedAt: pointer.To(n.UpdatedAt),
+		ClosedAt:  n.ClosedAt,
+		Merged:    pointer.To(n.Merged),
+		Draft:     pointer.To(n.IsDraft),
+		Labels:    n.labels(),
+
+		Additions:    n.Additions,
+		Deletions:    n.Deletions,
+		ChangedFiles: n.ChangedFiles,
+	}
+
+	if n.Commits != nil {
+		pr.Commits = pointer.To(n.Commits.TotalCount)
+	}
+	if n.HeadRefOID != nil {
+		pr.Head = &github.PullRequestBranch{SHA: n.HeadRefOID}
+	}
+	if n.MergeCommit != nil {
+		pr.MergeCommitSHA = pointer.To(n.MergeCommit.OID)
+	}
+	if n.Author != nil {
+		pr.User = &github.User{Login: pointer.To(n.Author.Login)}
+	}
+	if n.MergedBy != nil {
+		pr.MergedBy = &github.User{Login: pointer.To(n.MergedBy.Login)}
+	}
+	if n.Milestone != nil {
+		pr.Milestone = &github.Milestone{Title: pointer.To(n.Milestone.Title)}
+	}
+
+	return pr
+}
+
+func (n graphQLItem) toIssue() *github.Issue {
+	issue := &github.Issue{
+		Number:    pointer.To(n.Number),
+		Title:     pointer.To(n.Title),
+		State:     pointer.To(strings.ToLower(n.State)),
+		CreatedAt: pointer.To(n.CreatedAt),
+		UpdatedAt: pointer.To(n.UpdatedAt),
+		ClosedAt:  n.ClosedAt,
+		Labels:    n.labels(),
+	}
+
+	if n.Author != nil {
+		issue.User = &github.User{Login: pointer.To(n.Author.Login)}
+	}
+	if n.Milestone != nil {
+		issue.Milestone = &github.Milestone{Title: pointer.To(n.Milestone.Title)}
+	}
+
+	return issue
+}
@@ -0,0 +1,139 @@
+package gh
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	DefaultHost   = "github.com"
+	DefaultAPIURL = "https://api.github.com/"
+	DefaultWebURL = "https://github.com"
+)
+
+// Host is the github instance to talk to, the zero value is github.com. for an enterprise server only one of the
+// urls is needed, the other is worked out from it.
+type Host struct {
+	APIURL string // ie https://ghes.example.com/api/v3/
+	WebURL string // ie https://ghes.example.com
+
+	// CABundle is a pem file of extra certificate authorities to trust, for servers with an internal CA
+	CABundle string
+
+	// Proxy is the http proxy to go through, when empty the HTTP_PROXY/HTTPS_PROXY/NO_PROXY environment is used
+	Proxy string
+}
+
+// Enterprise is true when talking to anything other than github.com.
+func (h Host) Enterprise() bool {
+	return h.Name() != DefaultHost
+}
+
+// Name is the hostname of the instance, ie github.com or ghes.example.com.
+func (h Host) Name() string {
+	if u, err := url.Parse(h.Web()); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return DefaultHost
+}
+
+// Web is the base url for links to repos, prs & issues without a trailing slash.
+func (h Host) Web() string {
+	if h.WebURL != "" {
+		return strings.TrimSuffix(h.WebURL, "/")
+	}
+
+	// enterprise servers serve the api under /api/v3 of the web host
+	if h.APIURL != "" {
+		if u, err := url.Parse(h.APIURL); err == nil && u.Host != "" && u.Host != "api.github.com" {
+			return u.Scheme + "://" + u.Host
+		}
+	}
+
+	return DefaultWebURL
+}
+
+// API is the base url of the REST api with a trailing slash as go-github expects.
+func (h Host) API() string {
+	if h.APIURL != "" {
+		return strings.TrimSuffix(h.APIURL, "/") + "/"
+	}
+
+	if h.Enterprise() {
+		return h.Web() + "/api/v3/"
+	}
+
+	return DefaultAPIURL
+}
+
+// GraphQL is the url of the GraphQL api, which enterprise servers serve next to rather than under the REST api.
+func (h Host) GraphQL() string {
+	if !h.Enterprise() {
+		return GraphQLURL
+	}
+
+	api := strings.TrimSuffix(h.API(), "/")
+	return strings.TrimSuffix(api, "/v3") + "/graphql"
+}
+
+// RepoKey is how a repo is keyed in the cache, github.com repos are just owner/name so existing caches keep
+// working while enterprise repos are prefixed with their host so both can live in one cache.
+func (h Host) RepoKey(ownerName string) string {
+	if !h.Enterprise() {
+		return ownerName
+	}
+	return h.Name() + "/" + ownerName
+}
+
+// configureTransport applies the CA bundle & proxy to the transport every client is built on.
+func (h Host) configureTransport(t *http.Transport) error {
+	if h.Proxy != "" {
+		u, err := url.Parse(h.Proxy)
+		if err != nil {
+			return fmt.Errorf("parsing proxy url %q: %w", h.Proxy, err)
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+
+	if h.CABundle != "" {
+		pem, err := os.ReadFile(h.CABundle)
+		if err != nil {
+			return fmt.Errorf("reading ca bundle %s: %w", h.CABundle, err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in ca bundle %s", h.CABundle)
+		}
+
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		t.TLSClientConfig.RootCAs = pool
+	}
+
+	return nil
+}
+
+// Validate checks the urls parse and the CA bundle & proxy can be used, so a bad option fails up front rather than
+// on the first request.
+func (h Host) Validate() error {
+	for _, u := range []string{h.APIURL, h.WebURL} {
+		if u == "" {
+			continue
+		}
+		if p, err := url.Parse(u); err != nil || p.Scheme == "" || p.Host == "" {
+			return fmt.Errorf("invalid url %q, expected scheme://host[/path]", u)
+		}
+	}
+
+	return h.configureTransport(&http.Transport{})
+}
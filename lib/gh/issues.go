@@ -4,13 +4,14 @@ import (
 	"fmt"
 	"sort"
 	"strconv"
+	"time"
 
 	"github.com/google/go-github/v45/github"
 	"github.com/katbyte/gogo-repo-stats/lib/clog"
 )
 
 func (r Repo) IssueURL(pr int) string {
-	return "https://github.com/" + r.Owner + "/" + r.Name + "/issues/" + strconv.Itoa(pr)
+	return r.Host.Web() + "/" + r.Owner + "/" + r.Name + "/issues/" + strconv.Itoa(pr)
 }
 
 func (r Repo) ListAllIssues(state string, cb func([]*github.Issue, *github.Response) error) error {
@@ -46,6 +47,57 @@ func (r Repo) ListAllIssues(state string, cb func([]*github.Issue, *github.Respo
 	return nil
 }
 
+// ListIssuesUpdatedSince pages through issues (and PRs, github returns both) most recently updated first, from startPage,
+// that have been updated after since.
+func (r Repo) ListIssuesUpdatedSince(since time.Time, startPage int, cb func([]*github.Issue, *github.Response) error) error {
+	client, ctx := r.NewClient()
+
+	opts := &github.IssueListByRepoOptions{
+		State:     "all",
+		Sort:      "updated",
+		Direction: "desc",
+		Since:     since,
+		ListOptions: github.ListOptions{
+			Page:    startPage,
+			PerPage: 100,
+		},
+	}
+
+	if opts.Page < 1 {
+		opts.Page = 1
+	}
+
+	for {
+		clog.Log.Debugf("Listing issues updated since %s for %s/%s (Page %d)...", since.Format(time.RFC3339), r.Owner, r.Name, opts.ListOptions.Page)
+		issues, resp, err := client.Issues.ListByRepo(ctx, r.Owner, r.Name, opts)
+		if err != nil {
+			return fmt.Errorf("unable to list issues for %s/%s (Page %d): %w", r.Owner, r.Name, opts.ListOptions.Page, err)
+		}
+
+		// since is inclusive, drop the items we already synced at exactly the watermark
+		done := false
+		updated := make([]*github.Issue, 0, len(issues))
+		for _, i := range issues {
+			if i != nil && !since.IsZero() && !i.GetUpdatedAt().After(since) {
+				done = true
+				break
+			}
+			updated = append(updated, i)
+		}
+
+		if err = cb(updated, resp); err != nil {
+			return fmt.Errorf("callback failed for %s/%s (Page %d): %w", r.Owner, r.Name, opts.ListOptions.Page, err)
+		}
+
+		if done || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return nil
+}
+
 func (r Repo) GetAllIssues(state string) (*[]github.Issue, error) {
 	var allIssues []github.Issue
 
@@ -0,0 +1,126 @@
+package gh
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/katbyte/gogo-repo-stats/lib/clog"
+)
+
+// Owner is an org or user whose repositories can be discovered rather than listed by hand.
+type Owner struct {
+	Name string
+	Token
+}
+
+func NewOwner(name, token string) Owner {
+	o := Owner{
+		Name: name,
+		Token: Token{
+			Token: nil,
+		},
+	}
+
+	if token != "" {
+		o.Token.Token = &token
+	}
+
+	return o
+}
+
+// ListAllRepos pages through every repository of the owner, as an org first and if that is not found as a user.
+func (o Owner) ListAllRepos(cb func([]*github.Repository, *github.Response) error) error {
+	client, ctx := o.NewClient()
+
+	orgOpts := &github.RepositoryListByOrgOptions{
+		Type: "all",
+		ListOptions: github.ListOptions{
+			Page:    1,
+			PerPage: 100,
+		},
+	}
+
+	for {
+		clog.Log.Debugf("Listing all repos for org %s (Page %d)...", o.Name, orgOpts.Page)
+		repos, resp, err := client.Repositories.ListByOrg(ctx, o.Name, orgOpts)
+		if err != nil {
+			var ghErr *github.ErrorResponse
+			if orgOpts.Page == 1 && errors.As(err, &ghErr) && ghErr.Response != nil && ghErr.Response.StatusCode == http.StatusNotFound {
+				return o.listAllUserRepos(cb)
+			}
+
+			return fmt.Errorf("unable to list repos for %s (Page %d): %w", o.Name, orgOpts.Page, err)
+		}
+
+		if err = cb(repos, resp); err != nil {
+			return fmt.Errorf("callback failed for %s (Page %d): %w", o.Name, orgOpts.Page, err)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		orgOpts.Page = resp.NextPage
+	}
+
+	return nil
+}
+
+func (o Owner) listAllUserRepos(cb func([]*github.Repository, *github.Response) error) error {
+	client, ctx := o.NewClient()
+
+	opts := &github.RepositoryListOptions{
+		Type: "owner",
+		ListOptions: github.ListOptions{
+			Page:    1,
+			PerPage: 100,
+		},
+	}
+
+	for {
+		clog.Log.Debugf("Listing all repos for user %s (Page %d)...", o.Name, opts.Page)
+		repos, resp, err := client.Repositories.List(ctx, o.Name, opts)
+		if err != nil {
+			return fmt.Errorf("unable to list repos for %s (Page %d): %w", o.Name, opts.Page, err)
+		}
+
+		if err = cb(repos, resp); err != nil {
+			return fmt.Errorf("callback failed for %s (Page %d): %w", o.Name, opts.Page, err)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return nil
+}
+
+func (o Owner) GetAllRepos() (*[]github.Repository, error) {
+	var allRepos []github.Repository
+
+	err := o.ListAllRepos(func(repos []*github.Repository, resp *github.Response) error {
+		for i, r := range repos {
+			if r == nil {
+				clog.Log.Debugf("repos[%d] was nil, skipping", i)
+				continue
+			}
+
+			allRepos = append(allRepos, *r)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all repos for %s: %w", o.Name, err)
+	}
+
+	sort.Slice(allRepos, func(i, j int) bool {
+		return allRepos[i].GetName() < allRepos[j].GetName()
+	})
+
+	return &allRepos, nil
+}
@@ -0,0 +1,59 @@
+package gh
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/katbyte/gogo-repo-stats/lib/clog"
+)
+
+// ListAllPullRequestFiles pages through the files changed by a PR, github stops listing after 3000 files.
+func (r Repo) ListAllPullRequestFiles(number int, cb func([]*github.CommitFile, *github.Response) error) error {
+	client, ctx := r.NewClient()
+
+	opts := &github.ListOptions{
+		Page:    1,
+		PerPage: 100,
+	}
+
+	for {
+		clog.Log.Debugf("Listing all files for %s/%s/%d (Page %d)...", r.Owner, r.Name, number, opts.Page)
+		files, resp, err := client.PullRequests.ListFiles(ctx, r.Owner, r.Name, number, opts)
+		if err != nil {
+			return fmt.Errorf("unable to list files for %s/%s/%d (Page %d): %w", r.Owner, r.Name, number, opts.Page, err)
+		}
+
+		if err = cb(files, resp); err != nil {
+			return fmt.Errorf("callback failed for %s/%s/%d (Page %d): %w", r.Owner, r.Name, number, opts.Page, err)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return nil
+}
+
+func (r Repo) GetAllPullRequestFiles(number int) (*[]github.CommitFile, error) {
+	var allFiles []github.CommitFile
+
+	err := r.ListAllPullRequestFiles(number, func(files []*github.CommitFile, resp *github.Response) error {
+		for i, f := range files {
+			if f == nil {
+				clog.Log.Debugf("files[%d] was nil, skipping", i)
+				continue
+			}
+
+			allFiles = append(allFiles, *f)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all files for %s/%s/%d: %w", r.Owner, r.Name, number, err)
+	}
+
+	return &allFiles, nil
+}
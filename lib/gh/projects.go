@@ -0,0 +1,160 @@
+package gh
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/katbyte/gogo-repo-stats/lib/clog"
+)
+
+// a projects (v2) board is only available through graphql. each item is a linked issue or pr (or a draft) with a
+// value for each of the board's fields, only single select (Status), iteration & number fields are kept
+
+type ProjectItem struct {
+	ID       string
+	Type     string // ISSUE, PULL_REQUEST, DRAFT_ISSUE or REDACTED
+	Repo     string // owner/name, empty for drafts
+	Number   int
+	Title    string
+	Archived bool
+	Updated  time.Time
+
+	// Fields is the value of each set field by name
+	Fields map[string]string
+}
+
+const graphQLProjectItemsQuery = `
+query($owner: String!, $number: Int!, $after: String) {
+	repositoryOwner(login: $owner) {
+		... on ProjectV2Owner {
+			projectV2(number: $number) {
+				title
+				items(first: 100, after: $after) {
+					pageInfo { hasNextPage endCursor }
+					nodes {
+						id
+						type
+						isArchived
+						updatedAt
+						content {
+							... on Issue { number title repository { nameWithOwner } }
+							... on PullRequest { number title repository { nameWithOwner } }
+							... on DraftIssue { title }
+						}
+						fieldValues(first: 50) {
+							nodes {
+								__typename
+								... on ProjectV2ItemFieldSingleSelectValue { name field { ... on ProjectV2FieldCommon { name } } }
+								... on ProjectV2ItemFieldIterationValue { title field { ... on ProjectV2FieldCommon { name } } }
+								... on ProjectV2ItemFieldNumberValue { number field { ... on ProjectV2FieldCommon { name } } }
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}`
+
+type graphQLProjectItems struct {
+	RepositoryOwner *struct {
+		ProjectV2 *struct {
+			Title string `json:"title"`
+			Items struct {
+				PageInfo graphQLPageInfo `json:"pageInfo"`
+				Nodes    []struct {
+					ID         string    `json:"id"`
+					Type       string    `json:"type"`
+					IsArchived bool      `json:"isArchived"`
+					UpdatedAt  time.Time `json:"updatedAt"`
+					Content    *struct {
+						Number     int    `json:"number"`
+						Title      string `json:"title"`
+						Repository *struct {
+							NameWithOwner string `json:"nameWithOwner"`
+						} `json:"repository"`
+					} `json:"content"`
+					FieldValues struct {
+						Nodes []struct {
+							Typename string   `json:"__typename"`
+							Name     string   `json:"name"`
+							Title    string   `json:"title"`
+							Number   *float64 `json:"number"`
+							Field    struct {
+								Name string `json:"name"`
+							} `json:"field"`
+						} `json:"nodes"`
+					} `json:"fieldValues"`
+				} `json:"nodes"`
+			} `json:"items"`
+		} `json:"projectV2"`
+	} `json:"repositoryOwner"`
+}
+
+// ListItems pages through every item on the project, the owner can be an org or a user.
+func (p Project) ListItems(cb func([]ProjectItem) error) error {
+	var after *string
+
+	for page := 1; ; page++ {
+		clog.Log.Debugf("Listing items of project %s/%d (Page %d)...", p.Owner, p.Number, page)
+
+		var data graphQLProjectItems
+		vars := map[string]interface{}{
+			"owner":  p.Owner,
+			"number": p.Number,
+			"after":  after,
+		}
+		if err := p.GraphQLQueryUnmarshal(graphQLProjectItemsQuery, vars, &data); err != nil {
+			return fmt.Errorf("unable to list items of project %s/%d (Page %d): %w", p.Owner, p.Number, page, err)
+		}
+
+		if data.RepositoryOwner == nil || data.RepositoryOwner.ProjectV2 == nil {
+			return fmt.Errorf("project %s/%d not found", p.Owner, p.Number)
+		}
+		items := data.RepositoryOwner.ProjectV2.Items
+
+		out := make([]ProjectItem, 0, len(items.Nodes))
+		for _, n := range items.Nodes {
+			item := ProjectItem{
+				ID:       n.ID,
+				Type:     n.Type,
+				Archived: n.IsArchived,
+				Updated:  n.UpdatedAt,
+				Fields:   map[string]string{},
+			}
+
+			if n.Content != nil {
+				item.Number = n.Content.Number
+				item.Title = n.Content.Title
+				if n.Content.Repository != nil {
+					item.Repo = n.Content.Repository.NameWithOwner
+				}
+			}
+
+			for _, v := range n.FieldValues.Nodes {
+				switch v.Typename {
+				case "ProjectV2ItemFieldSingleSelectValue":
+					item.Fields[v.Field.Name] = v.Name
+				case "ProjectV2ItemFieldIterationValue":
+					item.Fields[v.Field.Name] = v.Title
+				case "ProjectV2ItemFieldNumberValue":
+					if v.Number != nil {
+						item.Fields[v.Field.Name] = strconv.FormatFloat(*v.Number, 'f', -1, 64)
+					}
+				}
+			}
+
+			out = append(out, item)
+		}
+
+		if err := cb(out); err != nil {
+			return fmt.Errorf("callback failed for project %s/%d (Page %d): %w", p.Owner, p.Number, page, err)
+		}
+
+		if !items.PageInfo.HasNextPage {
+			return nil
+		}
+		after = items.PageInfo.EndCursor
+	}
+}
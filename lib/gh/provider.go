@@ -0,0 +1,204 @@
+package gh
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/katbyte/gogo-repo-stats/lib/provider"
+)
+
+// a github repo is a provider too, fetch has its own richer pipeline for github (a worker pool, graphql, checks,
+// reviews & releases) but the same listing is available through the interface every provider shares
+
+var _ provider.Provider = Repo{}
+
+func (r Repo) ListPRs(since time.Time, cb func([]provider.PR) error) error {
+	return r.ListPullRequestsUpdatedSince(since, 1, func(prs []*github.PullRequest, _ *github.Response) error {
+		page := make([]provider.PR, 0, len(prs))
+		for _, pr := range prs {
+			page = append(page, NormalizePR(pr))
+		}
+		return cb(page)
+	})
+}
+
+func (r Repo) ListIssues(since time.Time, cb func([]provider.Issue) error) error {
+	return r.ListIssuesUpdatedSince(since, 1, func(issues []*github.Issue, _ *github.Response) error {
+		page := make([]provider.Issue, 0, len(issues))
+		for _, i := range issues {
+			if i.IsPullRequest() {
+				continue
+			}
+			page = append(page, NormalizeIssue(i))
+		}
+		return cb(page)
+	})
+}
+
+func (r Repo) PREvents(number int) ([]provider.Event, error) {
+	return r.IssueEvents(number)
+}
+
+func (r Repo) IssueEvents(number int) ([]provider.Event, error) {
+	timeline, err := r.GetAllIssueEvents(number)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]provider.Event, 0, len(*timeline))
+	for _, t := range *timeline {
+		t := t
+		e, err := NormalizeEvent(&t)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s#%d: %w", r.Owner, r.Name, number, err)
+		}
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+func NormalizePR(pr *github.PullRequest) provider.PR {
+	// only fetching a single pr says if it was merged, listing them just gives when
+	p := provider.PR{
+		Number:       pr.GetNumber(),
+		Title:        pr.GetTitle(),
+		User:         pr.GetUser().GetLogin(),
+		State:        pr.GetState(),
+		Milestone:    pr.GetMilestone().GetTitle(),
+		Labels:       labelNames(pr.Labels),
+		Draft:        pr.GetDraft(),
+		Merged:       pr.GetMerged() || pr.MergedAt != nil,
+		Merger:       pr.GetMergedBy().GetLogin(),
+		Created:      pr.GetCreatedAt(),
+		Updated:      pr.GetUpdatedAt(),
+		Closed:       pr.GetClosedAt(),
+		Additions:    pr.Additions,
+		Deletions:    pr.Deletions,
+		ChangedFiles: pr.ChangedFiles,
+		Commits:      pr.Commits,
+	}
+
+	// github also sets merge_commit_sha on open prs for the test merge so it is only kept once merged
+	if p.Merged {
+		p.MergeSHA = pr.GetMergeCommitSHA()
+	}
+
+	return p
+}
+
+func labelNames(labels []*github.Label) []string {
+	names := make([]string, 0, len(labels))
+	for _, label := range labels {
+		names = append(names, label.GetName())
+	}
+	return names
+}
+
+func NormalizeIssue(issue *github.Issue) provider.Issue {
+	return provider.Issue{
+		Number:    issue.GetNumber(),
+		Title:     issue.GetTitle(),
+		User:      issue.GetUser().GetLogin(),
+		State:     issue.GetState(),
+		Milestone: issue.GetMilestone().GetTitle(),
+		Labels:    labelNames(issue.Labels),
+		Created:   issue.GetCreatedAt(),
+		Updated:   issue.GetUpdatedAt(),
+		Closed:    issue.GetClosedAt(),
+	}
+}
+
+func NormalizeEvent(event *TimelineEvent) (provider.Event, error) {
+	// get user - it is either User/Actor
+	if event.Actor != nil && event.User != nil {
+		if event.GetActor() == event.GetUser() {
+			return provider.Event{}, fmt.Errorf("both actor and user exist and differ %s: %s != %s", event.GetURL(), event.GetActor(), event.GetUser())
+		}
+	}
+
+	u := ""
+	if event.Actor != nil {
+		u = event.Actor.GetLogin()
+	}
+	if event.User != nil {
+		u = event.User.GetLogin()
+	}
+
+	// get correct date - createdAt opr SubmittedAt
+	if event.CreatedAt != nil && event.SubmittedAt != nil {
+		if event.GetActor() == event.GetUser() {
+			return provider.Event{}, fmt.Errorf("both actor and user exist and differ %s:  %s  == %s", event.GetURL(), event.GetActor(), event.GetUser())
+		}
+	}
+
+	var t time.Time
+	if event.CreatedAt != nil {
+		t = event.GetCreatedAt()
+	}
+	if event.SubmittedAt != nil {
+		t = event.GetSubmittedAt()
+	}
+
+	e := provider.Event{
+		ID:    event.GetNodeID(),
+		Date:  t,
+		Event: event.GetEvent(),
+		User:  u,
+
+		State:     event.GetState(),
+		Label:     event.GetLabel().GetName(),
+		Milestone: event.GetMilestone().GetTitle(),
+		Body:      event.GetBody(),
+
+		Assignee:   event.GetAssignee().GetLogin(),
+		Reviewer:   event.GetReviewer().GetLogin(),
+		Team:       event.GetRequestedTeam().GetSlug(),
+		RenameFrom: event.GetRename().GetFrom(),
+		RenameTo:   event.GetRename().GetTo(),
+		CommitID:   event.GetCommitID(),
+		LockReason: event.GetLockReason(),
+
+		URL: event.GetURL(),
+	}
+
+	switch e.Event {
+	case "committed":
+		// commits are the event, their sha is all there is
+		e.CommitID = event.GetSHA()
+	case "head_ref_force_pushed":
+		// only graphql says what the head was before
+		e.BeforeCommit = event.GetSHA()
+	case "cross-referenced":
+		if issue := event.GetSource().GetIssue(); issue != nil {
+			e.SourceRepo = issue.GetRepository().GetFullName()
+			if e.SourceRepo == "" {
+				e.SourceRepo = apiURLRepo(issue.GetRepositoryURL())
+			}
+			e.SourceNumber = issue.GetNumber()
+
+			e.SourceKind = "issue"
+			if issue.IsPullRequest() {
+				e.SourceKind = "pr"
+			}
+		}
+	}
+
+	return e, nil
+}
+
+// apiURLRepo returns the owner/name of a repo's api url like https://api.github.com/repos/owner/name
+func apiURLRepo(u string) string {
+	_, ownerName, ok := strings.Cut(u, "/repos/")
+	if !ok {
+		return ""
+	}
+
+	parts := strings.Split(ownerName, "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0] + "/" + parts[1]
+}
@@ -0,0 +1,91 @@
+package gh
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/katbyte/gogo-repo-stats/lib/chttp"
+	"github.com/katbyte/gogo-repo-stats/lib/provider"
+)
+
+// replayRepo is a repo whose client is served from the cassette in testdata, no token or network needed
+func replayRepo(t *testing.T) Repo {
+	t.Helper()
+
+	cassette, err := chttp.NewCassette("testdata/cassette", chttp.CassetteReplay)
+	if err != nil {
+		t.Fatalf("opening cassette: %v", err)
+	}
+
+	r := NewRepoOwnerName("katbyte", "example", "")
+	r.Cassette = cassette
+
+	return r
+}
+
+func TestPREventsReplayed(t *testing.T) {
+	events, err := replayRepo(t).PREvents(1)
+	if err != nil {
+		t.Fatalf("getting events: %v", err)
+	}
+
+	// newest first, as they are listed
+	expected := []provider.Event{
+		{
+			ID:    "CE_kwDOAAAAAc4AAAAD",
+			Date:  time.Date(2024, 3, 4, 16, 45, 0, 0, time.UTC),
+			Event: "closed",
+			User:  "alice",
+			URL:   "https://api.github.com/repos/katbyte/example/issues/events/3003",
+		},
+		{
+			Date:         time.Date(2024, 3, 3, 8, 0, 0, 0, time.UTC),
+			Event:        "cross-referenced",
+			User:         "carol",
+			SourceRepo:   "katbyte/other",
+			SourceNumber: 7,
+			SourceKind:   "pr",
+		},
+		{
+			ID:    "PRR_kwDOAAAAAc4AAAAC",
+			Date:  time.Date(2024, 3, 2, 9, 30, 0, 0, time.UTC),
+			Event: "reviewed",
+			User:  "bob",
+			State: "changes_requested",
+			Body:  "needs a test",
+		},
+		{
+			ID:    "LE_kwDOAAAAAc4AAAAB",
+			Date:  time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+			Event: "labeled",
+			User:  "alice",
+			Label: "bug",
+			URL:   "https://api.github.com/repos/katbyte/example/issues/events/1001",
+		},
+	}
+
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %d: %+v", len(expected), len(events), events)
+	}
+	for i, e := range events {
+		if !e.Date.Equal(expected[i].Date) {
+			t.Errorf("event %d: expected date %s, got %s", i, expected[i].Date, e.Date)
+		}
+		e.Date = expected[i].Date
+
+		if e != expected[i] {
+			t.Errorf("event %d:\nexpected %+v\ngot      %+v", i, expected[i], e)
+		}
+	}
+}
+
+func TestReplayWithoutRecording(t *testing.T) {
+	_, err := replayRepo(t).PREvents(2)
+
+	// not being recorded is final, it should not be retried until the retries run out
+	var noRecording *chttp.NoRecordingError
+	if !errors.As(err, &noRecording) {
+		t.Fatalf("expected a NoRecordingError, got %v", err)
+	}
+}
@@ -4,13 +4,14 @@ import (
 	"fmt"
 	"sort"
 	"strconv"
+	"time"
 
 	"github.com/google/go-github/v45/github"
 	"github.com/katbyte/gogo-repo-stats/lib/clog"
 )
 
 func (r Repo) PrURL(pr int) string {
-	return "https://github.com/" + r.Owner + "/" + r.Name + "/pull/" + strconv.Itoa(pr)
+	return r.Host.Web() + "/" + r.Owner + "/" + r.Name + "/pull/" + strconv.Itoa(pr)
 }
 
 func (r Repo) ListAllPullRequests(state string, cb func([]*github.PullRequest, *github.Response) error) error {
@@ -44,6 +45,55 @@ func (r Repo) ListAllPullRequests(state string, cb func([]*github.PullRequest, *
 	return nil
 }
 
+// ListPullRequestsUpdatedSince pages through PRs most recently updated first, from startPage, and stops once it passes
+// since. the PR list API has no since parameter so we have to check each PR's updated_at ourselves.
+func (r Repo) ListPullRequestsUpdatedSince(since time.Time, startPage int, cb func([]*github.PullRequest, *github.Response) error) error {
+	client, ctx := r.NewClient()
+
+	opts := &github.PullRequestListOptions{
+		State:     "all",
+		Sort:      "updated",
+		Direction: "desc",
+		ListOptions: github.ListOptions{
+			Page:    startPage,
+			PerPage: 100,
+		},
+	}
+
+	if opts.Page < 1 {
+		opts.Page = 1
+	}
+
+	for {
+		clog.Log.Debugf("Listing PRs updated since %s for %s/%s (Page %d)...", since.Format(time.RFC3339), r.Owner, r.Name, opts.ListOptions.Page)
+		prs, resp, err := client.PullRequests.List(ctx, r.Owner, r.Name, opts)
+		if err != nil {
+			return fmt.Errorf("unable to list PRs for %s/%s (Page %d): %w", r.Owner, r.Name, opts.ListOptions.Page, err)
+		}
+
+		done := false
+		updated := make([]*github.PullRequest, 0, len(prs))
+		for _, p := range prs {
+			if p != nil && !since.IsZero() && !p.GetUpdatedAt().After(since) {
+				done = true
+				break
+			}
+			updated = append(updated, p)
+		}
+
+		if err = cb(updated, resp); err != nil {
+			return fmt.Errorf("callback failed for %s/%s (Page %d): %w", r.Owner, r.Name, opts.ListOptions.Page, err)
+		}
+
+		if done || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return nil
+}
+
 func (r Repo) GetAllPullRequests(state string) (*[]github.PullRequest, error) {
 	var allPRs []github.PullRequest
 
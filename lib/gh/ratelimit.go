@@ -0,0 +1,137 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/katbyte/gogo-repo-stats/lib/clog"
+)
+
+// RateLimitBudget tracks the core rate limit remaining across every client that shares it, so concurrent
+// workers pause together before the budget runs out rather than all hammering github into 403s.
+type RateLimitBudget struct {
+	// Reserve is how many requests to leave unused before pausing until the reset
+	Reserve int
+
+	mu        sync.Mutex
+	remaining int // -1 until we have seen a response
+	limit     int
+	reset     time.Time
+	used      int
+}
+
+func NewRateLimitBudget(reserve int) *RateLimitBudget {
+	return &RateLimitBudget{
+		Reserve:   reserve,
+		remaining: -1,
+	}
+}
+
+// Wait blocks until there is budget for another request, taking one from the remaining count.
+func (b *RateLimitBudget) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		if b.remaining < 0 || b.remaining > b.Reserve || !time.Now().Before(b.reset) {
+			if b.remaining > 0 {
+				b.remaining--
+			}
+			b.used++
+			b.mu.Unlock()
+			return nil
+		}
+
+		wait := time.Until(b.reset) + time.Second
+		b.mu.Unlock()
+
+		clog.Log.Warnf("rate limit budget at %d, waiting %s for reset", b.Remaining(), wait.String())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+
+		// the reset has passed so we don't know what is left until the next response tells us
+		b.mu.Lock()
+		if !time.Now().Before(b.reset) {
+			b.remaining = -1
+		}
+		b.mu.Unlock()
+	}
+}
+
+// Update records the rate limit headers from a response.
+func (b *RateLimitBudget) Update(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+
+	// search & graphql have their own buckets
+	if r := resp.Header.Get("X-RateLimit-Resource"); r != "" && r != "core" {
+		return
+	}
+
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	resetUnix, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+	reset := time.Unix(resetUnix, 0)
+	limit, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// responses from concurrent requests arrive out of order, within a window only ever count down
+	if reset.After(b.reset) || b.remaining < 0 || remaining < b.remaining {
+		b.remaining = remaining
+	}
+	if reset.After(b.reset) {
+		b.reset = reset
+	}
+	if limit > 0 {
+		b.limit = limit
+	}
+}
+
+func (b *RateLimitBudget) Remaining() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.remaining
+}
+
+func (b *RateLimitBudget) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return fmt.Sprintf("%d requests made, %d/%d remaining (resets %s)", b.used, b.remaining, b.limit, b.reset.Format("15:04:05"))
+}
+
+// Transport wraps base so every request made through it waits for and updates the budget.
+func (b *RateLimitBudget) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &budgetTransport{budget: b, base: base}
+}
+
+type budgetTransport struct {
+	budget *RateLimitBudget
+	base   http.RoundTripper
+}
+
+func (t *budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.budget.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	t.budget.Update(resp)
+
+	return resp, err
+}
@@ -0,0 +1,176 @@
+package gh
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/katbyte/gogo-repo-stats/lib/clog"
+)
+
+func (r Repo) ListAllReleases(cb func([]*github.RepositoryRelease, *github.Response) error) error {
+	client, ctx := r.NewClient()
+
+	opts := &github.ListOptions{
+		Page:    1,
+		PerPage: 100,
+	}
+
+	for {
+		clog.Log.Debugf("Listing all releases for %s/%s (Page %d)...", r.Owner, r.Name, opts.Page)
+		releases, resp, err := client.Repositories.ListReleases(ctx, r.Owner, r.Name, opts)
+		if err != nil {
+			return fmt.Errorf("unable to list releases for %s/%s (Page %d): %w", r.Owner, r.Name, opts.Page, err)
+		}
+
+		if err = cb(releases, resp); err != nil {
+			return fmt.Errorf("callback failed for %s/%s (Page %d): %w", r.Owner, r.Name, opts.Page, err)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return nil
+}
+
+// GetAllReleases returns every published release of a repo, oldest first. drafts are skipped as their tag does not
+// exist until they are published.
+func (r Repo) GetAllReleases() (*[]github.RepositoryRelease, error) {
+	var allReleases []github.RepositoryRelease
+
+	err := r.ListAllReleases(func(releases []*github.RepositoryRelease, resp *github.Response) error {
+		for i, rl := range releases {
+			if rl == nil {
+				clog.Log.Debugf("releases[%d] was nil, skipping", i)
+				continue
+			}
+
+			if rl.GetDraft() || rl.PublishedAt == nil {
+				clog.Log.Debugf("releases[%d] is a draft, skipping", i)
+				continue
+			}
+
+			allReleases = append(allReleases, *rl)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all releases for %s/%s: %w", r.Owner, r.Name, err)
+	}
+
+	sort.Slice(allReleases, func(a, b int) bool {
+		return allReleases[a].GetPublishedAt().Before(allReleases[b].GetPublishedAt().Time)
+	})
+
+	return &allReleases, nil
+}
+
+func (r Repo) ListAllTags(cb func([]*github.RepositoryTag, *github.Response) error) error {
+	client, ctx := r.NewClient()
+
+	opts := &github.ListOptions{
+		Page:    1,
+		PerPage: 100,
+	}
+
+	for {
+		clog.Log.Debugf("Listing all tags for %s/%s (Page %d)...", r.Owner, r.Name, opts.Page)
+		tags, resp, err := client.Repositories.ListTags(ctx, r.Owner, r.Name, opts)
+		if err != nil {
+			return fmt.Errorf("unable to list tags for %s/%s (Page %d): %w", r.Owner, r.Name, opts.Page, err)
+		}
+
+		if err = cb(tags, resp); err != nil {
+			return fmt.Errorf("callback failed for %s/%s (Page %d): %w", r.Owner, r.Name, opts.Page, err)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return nil
+}
+
+// GetAllTagReleases returns a repo's tags as releases, oldest first, for repos that push tags without publishing
+// releases. a tag has no date of its own so it is taken as released when its commit was committed, which costs a
+// request per tag (revalidated for free on later fetches). tags have no id either, so they are numbered from -1 down
+// to keep them apart from real releases.
+func (r Repo) GetAllTagReleases() (*[]github.RepositoryRelease, error) {
+	var tags []github.RepositoryTag
+
+	err := r.ListAllTags(func(page []*github.RepositoryTag, resp *github.Response) error {
+		for i, t := range page {
+			if t == nil || t.GetCommit().GetSHA() == "" {
+				clog.Log.Debugf("tags[%d] has no commit, skipping", i)
+				continue
+			}
+			tags = append(tags, *t)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all tags for %s/%s: %w", r.Owner, r.Name, err)
+	}
+
+	client, ctx := r.NewClient()
+
+	releases := make([]github.RepositoryRelease, 0, len(tags))
+	for _, t := range tags {
+		clog.Log.Debugf("Getting commit %s of tag %s for %s/%s...", t.GetCommit().GetSHA(), t.GetName(), r.Owner, r.Name)
+		commit, _, err := client.Git.GetCommit(ctx, r.Owner, r.Name, t.GetCommit().GetSHA())
+		if err != nil {
+			return nil, fmt.Errorf("unable to get commit of tag %s for %s/%s: %w", t.GetName(), r.Owner, r.Name, err)
+		}
+
+		date := commit.GetCommitter().GetDate()
+		releases = append(releases, github.RepositoryRelease{
+			TagName:     t.Name,
+			Name:        t.Name,
+			CreatedAt:   &github.Timestamp{Time: date},
+			PublishedAt: &github.Timestamp{Time: date},
+		})
+	}
+
+	sort.SliceStable(releases, func(a, b int) bool {
+		return releases[a].GetPublishedAt().Before(releases[b].GetPublishedAt().Time)
+	})
+	for i := range releases {
+		id := int64(-(i + 1))
+		releases[i].ID = &id
+	}
+
+	return &releases, nil
+}
+
+// TagContainsCommit uses the compare api to check if a commit is part of a tag's history, the tag is then either
+// ahead of or identical to the commit. a commit github can't compare, ie merged into a branch since deleted, is in
+// no tag.
+func (r Repo) TagContainsCommit(tag, sha string) (bool, error) {
+	client, ctx := r.NewClient()
+
+	clog.Log.Debugf("Comparing %s...%s for %s/%s...", sha, tag, r.Owner, r.Name)
+	cmp, _, err := client.Repositories.CompareCommits(ctx, r.Owner, r.Name, sha, tag, &github.ListOptions{PerPage: 1})
+	if err != nil {
+		var ghErr *github.ErrorResponse
+		if errors.As(err, &ghErr) && ghErr.Response != nil {
+			if code := ghErr.Response.StatusCode; code == http.StatusNotFound || code == http.StatusUnprocessableEntity {
+				clog.Log.Warnf("unable to compare %s...%s for %s/%s (%d), taking it as not in the tag", sha, tag, r.Owner, r.Name, code)
+				return false, nil
+			}
+		}
+
+		return false, fmt.Errorf("unable to compare %s...%s for %s/%s: %w", sha, tag, r.Owner, r.Name, err)
+	}
+
+	status := cmp.GetStatus()
+	return status == "ahead" || status == "identical", nil
+}
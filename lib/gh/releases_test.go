@@ -0,0 +1,111 @@
+package gh
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRepo is a repo whose api is served by handler, as an enterprise server so the client can be pointed at it
+func testRepo(t *testing.T, handler http.HandlerFunc) Repo {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	r := NewRepoOwnerName("katbyte", "example", "")
+	r.Host = Host{APIURL: srv.URL + "/api/v3/", WebURL: srv.URL}
+
+	return r
+}
+
+func TestTagContainsCommit(t *testing.T) {
+	r := testRepo(t, func(w http.ResponseWriter, req *http.Request) {
+		// /api/v3/repos/katbyte/example/compare/<sha>...<tag>
+		sha, _, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/api/v3/repos/katbyte/example/compare/"), "...")
+
+		switch sha {
+		case "ahead", "identical", "behind", "diverged":
+			fmt.Fprintf(w, `{"status": %q}`, sha)
+		case "deleted":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		case "unrelated":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `{"message": "No common ancestor between v1.0.0 and unrelated."}`)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message": "Bad credentials"}`)
+		}
+	})
+
+	cases := []struct {
+		sha      string
+		contains bool
+		err      bool
+	}{
+		{"ahead", true, false},
+		{"identical", true, false},
+		{"behind", false, false},
+		{"diverged", false, false},
+		{"deleted", false, false},
+		{"unrelated", false, false},
+		{"unauthorized", false, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.sha, func(t *testing.T) {
+			contains, err := r.TagContainsCommit("v1.0.0", tc.sha)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+			if contains != tc.contains {
+				t.Errorf("expected contains %t, got %t", tc.contains, contains)
+			}
+		})
+	}
+}
+
+func TestGetAllTagReleases(t *testing.T) {
+	r := testRepo(t, func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/api/v3/repos/katbyte/example/tags":
+			fmt.Fprint(w, `[
+				{"name": "v1.1.0", "commit": {"sha": "bbb"}},
+				{"name": "v1.0.0", "commit": {"sha": "aaa"}}
+			]`)
+		case "/api/v3/repos/katbyte/example/git/commits/aaa":
+			fmt.Fprint(w, `{"sha": "aaa", "committer": {"date": "2024-01-10T12:00:00Z"}}`)
+		case "/api/v3/repos/katbyte/example/git/commits/bbb":
+			fmt.Fprint(w, `{"sha": "bbb", "committer": {"date": "2024-02-20T08:30:00Z"}}`)
+		default:
+			http.NotFound(w, req)
+		}
+	})
+
+	releases, err := r.GetAllTagReleases()
+	if err != nil {
+		t.Fatalf("getting tag releases: %v", err)
+	}
+
+	expected := []struct {
+		id        int64
+		tag       string
+		published time.Time
+	}{
+		{-1, "v1.0.0", time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)},
+		{-2, "v1.1.0", time.Date(2024, 2, 20, 8, 30, 0, 0, time.UTC)},
+	}
+
+	if len(*releases) != len(expected) {
+		t.Fatalf("expected %d releases, got %d", len(expected), len(*releases))
+	}
+	for i, rl := range *releases {
+		if rl.GetID() != expected[i].id || rl.GetTagName() != expected[i].tag || !rl.GetPublishedAt().Time.Equal(expected[i].published) {
+			t.Errorf("release %d: expected %d %s @ %s, got %d %s @ %s", i, expected[i].id, expected[i].tag, expected[i].published, rl.GetID(), rl.GetTagName(), rl.GetPublishedAt())
+		}
+	}
+}
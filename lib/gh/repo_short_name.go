@@ -3,7 +3,8 @@ package gh
 import "strings"
 
 func RepoShortName(repo string) string {
-	repoShort := strings.Split(repo, "/")[1] // remove owner by default
+	parts := strings.Split(repo, "/")
+	repoShort := parts[len(parts)-1] // remove host & owner by default
 	switch repo {
 	case "hashicorp/terraform-provider-azurerm":
 		repoShort = "azurerm"
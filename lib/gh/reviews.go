@@ -0,0 +1,70 @@
+package gh
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/katbyte/gogo-repo-stats/lib/clog"
+)
+
+func (r Repo) ListAllPullRequestReviews(number int, cb func([]*github.PullRequestReview, *github.Response) error) error {
+	client, ctx := r.NewClient()
+
+	opts := &github.ListOptions{
+		Page:    1,
+		PerPage: 100,
+	}
+
+	for {
+		clog.Log.Debugf("Listing all reviews for %s/%s/%d (Page %d)...", r.Owner, r.Name, number, opts.Page)
+		reviews, resp, err := client.PullRequests.ListReviews(ctx, r.Owner, r.Name, number, opts)
+		if err != nil {
+			return fmt.Errorf("unable to list reviews for %s/%s/%d (Page %d): %w", r.Owner, r.Name, number, opts.Page, err)
+		}
+
+		if err = cb(reviews, resp); err != nil {
+			return fmt.Errorf("callback failed for %s/%s/%d (Page %d): %w", r.Owner, r.Name, number, opts.Page, err)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return nil
+}
+
+// GetAllPullRequestReviews returns every submitted review of a PR, oldest first. pending reviews have no
+// submitted date and are only visible to their author so they are skipped.
+func (r Repo) GetAllPullRequestReviews(number int) (*[]github.PullRequestReview, error) {
+	var allReviews []github.PullRequestReview
+
+	err := r.ListAllPullRequestReviews(number, func(reviews []*github.PullRequestReview, resp *github.Response) error {
+		for i, rv := range reviews {
+			if rv == nil {
+				clog.Log.Debugf("reviews[%d] was nil, skipping", i)
+				continue
+			}
+
+			if rv.SubmittedAt == nil {
+				clog.Log.Debugf("reviews[%d] has not been submitted, skipping", i)
+				continue
+			}
+
+			allReviews = append(allReviews, *rv)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all reviews for %s/%s/%d: %w", r.Owner, r.Name, number, err)
+	}
+
+	sort.Slice(allReviews, func(a, b int) bool {
+		return allReviews[a].GetSubmittedAt().Before(allReviews[b].GetSubmittedAt())
+	})
+
+	return &allReviews, nil
+}
@@ -0,0 +1,241 @@
+package gh
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/katbyte/gogo-repo-stats/lib/clog"
+)
+
+// github answers with a 403 (or 429) for three different things:
+//   - primary rate limit: the hourly budget is spent, x-ratelimit-remaining is 0 and we wait for x-ratelimit-reset
+//   - secondary rate limit: too many requests too quickly, there is a retry-after or the message says so and
+//     everything should back off and slow down
+//   - a genuine permission error, which no amount of retrying will fix
+
+type rateLimitKind int
+
+const (
+	notRateLimited rateLimitKind = iota
+	primaryRateLimit
+	secondaryRateLimit
+	forbidden
+)
+
+func (k rateLimitKind) String() string {
+	switch k {
+	case primaryRateLimit:
+		return "primary rate limit"
+	case secondaryRateLimit:
+		return "secondary rate limit"
+	case forbidden:
+		return "forbidden"
+	}
+	return "ok"
+}
+
+// github asks for at least a minute between secondary limit hits when it doesn't say how long
+const secondaryRateLimitWait = time.Minute
+
+// ForbiddenError is a 403 that is not a rate limit, ie the token lacks a scope or access to the repo.
+type ForbiddenError struct {
+	URL     string
+	Message string
+	Scopes  string // scopes the token has
+	Needs   string // scopes the endpoint accepts
+}
+
+func (e *ForbiddenError) Error() string {
+	msg := fmt.Sprintf("permission denied for %s: %s", e.URL, e.Message)
+	if e.Needs != "" {
+		msg += fmt.Sprintf(" (token has scopes %q, endpoint accepts %q)", e.Scopes, e.Needs)
+	}
+	return msg
+}
+
+// classifyRateLimit works out why a request was refused and how long to wait before trying again, the body is
+// read and replaced so the response can still be used afterwards.
+func classifyRateLimit(resp *http.Response) (rateLimitKind, time.Duration, string) {
+	if resp == nil || (resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests) {
+		return notRateLimited, 0, ""
+	}
+
+	var message string
+	if resp.Body != nil {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		var ghErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &ghErr) == nil {
+			message = ghErr.Message
+		}
+	}
+
+	if s := resp.Header.Get("Retry-After"); s != "" {
+		if secs, err := strconv.Atoi(s); err == nil {
+			return secondaryRateLimit, time.Duration(secs) * time.Second, message
+		}
+		if t, err := http.ParseTime(s); err == nil {
+			return secondaryRateLimit, time.Until(t), message
+		}
+	}
+
+	lower := strings.ToLower(message)
+	if strings.Contains(lower, "secondary rate limit") || strings.Contains(lower, "abuse") {
+		return secondaryRateLimit, secondaryRateLimitWait, message
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		wait := time.Minute
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			wait = time.Until(time.Unix(reset, 0)) + time.Second
+		}
+		return primaryRateLimit, wait, message
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return secondaryRateLimit, secondaryRateLimitWait, message
+	}
+
+	return forbidden, 0, message
+}
+
+// Throttle is the rate limit middleware shared by every client: it pauses everything after a secondary limit hit
+// and spaces requests out further each time one is hit, easing back off as requests succeed.
+type Throttle struct {
+	// MaxInterval caps how far apart requests are spaced
+	MaxInterval time.Duration
+
+	mu       sync.Mutex
+	interval time.Duration // minimum time between requests
+	next     time.Time     // when the next request may go out
+	paused   time.Time     // no requests until then after a secondary limit hit
+	okStreak int
+
+	requests  int
+	primary   int
+	secondary int
+	forbidden int
+	waited    time.Duration
+}
+
+func NewThrottle() *Throttle {
+	return &Throttle{MaxInterval: 10 * time.Second}
+}
+
+// Wait blocks until the pacing & any pause allow another request.
+func (t *Throttle) Wait(ctx context.Context) error {
+	t.mu.Lock()
+	now := time.Now()
+	start := t.next
+	if t.paused.After(start) {
+		start = t.paused
+	}
+	if start.Before(now) {
+		start = now
+	}
+	t.next = start.Add(t.interval)
+	t.requests++
+
+	wait := start.Sub(now)
+	t.waited += wait
+	t.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+// Observe records how a request went and adjusts the pacing.
+func (t *Throttle) Observe(kind rateLimitKind, wait time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch kind {
+	case notRateLimited:
+		// every 50 requests without a secondary limit halve the spacing again
+		t.okStreak++
+		if t.interval > 0 && t.okStreak >= 50 {
+			t.interval /= 2
+			if t.interval < 100*time.Millisecond {
+				t.interval = 0
+			}
+			t.okStreak = 0
+		}
+	case primaryRateLimit:
+		t.primary++
+	case secondaryRateLimit:
+		t.secondary++
+		t.okStreak = 0
+
+		if until := time.Now().Add(wait); until.After(t.paused) {
+			t.paused = until
+		}
+
+		if t.interval == 0 {
+			t.interval = 500 * time.Millisecond
+		} else {
+			t.interval *= 2
+		}
+		if t.interval > t.MaxInterval {
+			t.interval = t.MaxInterval
+		}
+		clog.Log.Warnf("secondary rate limit hit, pausing for %s and spacing requests %s apart", wait.String(), t.interval.String())
+	case forbidden:
+		t.forbidden++
+	}
+}
+
+func (t *Throttle) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return fmt.Sprintf("%d requests, %d primary & %d secondary rate limit hits, %d forbidden, %s spent throttled, spacing %s",
+		t.requests, t.primary, t.secondary, t.forbidden, t.waited.Round(time.Second).String(), t.interval.String())
+}
+
+// Transport wraps base so every request made through it is paced and its response observed.
+func (t *Throttle) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &throttleTransport{throttle: t, base: base}
+}
+
+type throttleTransport struct {
+	throttle *Throttle
+	base     http.RoundTripper
+}
+
+func (t *throttleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.throttle.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	kind, wait, _ := classifyRateLimit(resp)
+	t.throttle.Observe(kind, wait)
+
+	return resp, nil
+}
@@ -0,0 +1,242 @@
+package gh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// respond gets a real response with the given status, headers & body from a test server
+func respond(t *testing.T, status int, headers map[string]string, body string) *http.Response {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		for k, v := range headers {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Get(srv.URL) // nolint:noctx
+	if err != nil {
+		t.Fatalf("getting response: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return resp
+}
+
+func TestClassifyRateLimit(t *testing.T) {
+	reset := time.Now().Add(10 * time.Minute)
+	retryAt := time.Now().Add(2 * time.Minute)
+
+	cases := []struct {
+		name    string
+		status  int
+		headers map[string]string
+		body    string
+		kind    rateLimitKind
+		wait    time.Duration // roughly, anything that depends on the clock is given a few seconds
+		message string
+	}{
+		{
+			name:   "ok",
+			status: http.StatusOK,
+			body:   `{}`,
+			kind:   notRateLimited,
+		},
+		{
+			name:   "not found is not a rate limit",
+			status: http.StatusNotFound,
+			body:   `{"message": "Not Found"}`,
+			kind:   notRateLimited,
+		},
+		{
+			name:    "primary",
+			status:  http.StatusForbidden,
+			headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(reset.Unix(), 10)},
+			body:    `{"message": "API rate limit exceeded for user ID 1."}`,
+			kind:    primaryRateLimit,
+			wait:    time.Until(reset) + time.Second,
+			message: "API rate limit exceeded for user ID 1.",
+		},
+		{
+			name:    "primary without a reset",
+			status:  http.StatusForbidden,
+			headers: map[string]string{"X-RateLimit-Remaining": "0"},
+			body:    `{"message": "API rate limit exceeded for user ID 1."}`,
+			kind:    primaryRateLimit,
+			wait:    time.Minute,
+			message: "API rate limit exceeded for user ID 1.",
+		},
+		{
+			name:    "secondary with retry-after seconds",
+			status:  http.StatusForbidden,
+			headers: map[string]string{"Retry-After": "30", "X-RateLimit-Remaining": "4000"},
+			body:    `{"message": "You have exceeded a secondary rate limit."}`,
+			kind:    secondaryRateLimit,
+			wait:    30 * time.Second,
+			message: "You have exceeded a secondary rate limit.",
+		},
+		{
+			name:    "secondary with retry-after date",
+			status:  http.StatusForbidden,
+			headers: map[string]string{"Retry-After": retryAt.UTC().Format(http.TimeFormat)},
+			body:    `{"message": "You have exceeded a secondary rate limit."}`,
+			kind:    secondaryRateLimit,
+			wait:    time.Until(retryAt),
+			message: "You have exceeded a secondary rate limit.",
+		},
+		{
+			name:    "retry-after wins over a spent primary",
+			status:  http.StatusForbidden,
+			headers: map[string]string{"Retry-After": "5", "X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(reset.Unix(), 10)},
+			body:    `{"message": "You have exceeded a secondary rate limit."}`,
+			kind:    secondaryRateLimit,
+			wait:    5 * time.Second,
+			message: "You have exceeded a secondary rate limit.",
+		},
+		{
+			name:    "secondary from the message",
+			status:  http.StatusForbidden,
+			headers: map[string]string{"X-RateLimit-Remaining": "4000"},
+			body:    `{"message": "You have exceeded a secondary rate limit. Please wait a few minutes before you try again."}`,
+			kind:    secondaryRateLimit,
+			wait:    secondaryRateLimitWait,
+			message: "You have exceeded a secondary rate limit. Please wait a few minutes before you try again.",
+		},
+		{
+			name:    "abuse detection",
+			status:  http.StatusForbidden,
+			body:    `{"message": "You have triggered an abuse detection mechanism."}`,
+			kind:    secondaryRateLimit,
+			wait:    secondaryRateLimitWait,
+			message: "You have triggered an abuse detection mechanism.",
+		},
+		{
+			name:   "too many requests",
+			status: http.StatusTooManyRequests,
+			kind:   secondaryRateLimit,
+			wait:   secondaryRateLimitWait,
+		},
+		{
+			name:    "permission",
+			status:  http.StatusForbidden,
+			headers: map[string]string{"X-RateLimit-Remaining": "4999", "X-OAuth-Scopes": "public_repo", "X-Accepted-OAuth-Scopes": "repo"},
+			body:    `{"message": "Resource not accessible by integration"}`,
+			kind:    forbidden,
+			message: "Resource not accessible by integration",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := respond(t, tc.status, tc.headers, tc.body)
+
+			kind, wait, message := classifyRateLimit(resp)
+			if kind != tc.kind {
+				t.Errorf("expected %s, got %s", tc.kind, kind)
+			}
+			if d := wait - tc.wait; d < -2*time.Second || d > 2*time.Second {
+				t.Errorf("expected to wait %s, got %s", tc.wait, wait)
+			}
+			if message != tc.message {
+				t.Errorf("expected message %q, got %q", tc.message, message)
+			}
+
+			// the body is still there for whoever handles the response next
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("reading body: %v", err)
+			}
+			if string(body) != tc.body {
+				t.Errorf("expected body %q to be left, got %q", tc.body, string(body))
+			}
+		})
+	}
+}
+
+func TestThrottleBackoff(t *testing.T) {
+	th := NewThrottle()
+	th.MaxInterval = 2 * time.Second
+
+	// each secondary hit doubles the spacing up to the max, primary & permission errors leave it alone
+	steps := []struct {
+		kind     rateLimitKind
+		interval time.Duration
+	}{
+		{primaryRateLimit, 0},
+		{forbidden, 0},
+		{secondaryRateLimit, 500 * time.Millisecond},
+		{secondaryRateLimit, time.Second},
+		{secondaryRateLimit, 2 * time.Second},
+		{secondaryRateLimit, 2 * time.Second},
+		{primaryRateLimit, 2 * time.Second},
+	}
+	for i, s := range steps {
+		th.Observe(s.kind, 0)
+		if th.interval != s.interval {
+			t.Fatalf("step %d (%s): expected spacing %s, got %s", i, s.kind, s.interval, th.interval)
+		}
+	}
+
+	// and every 50 requests that get through halve it again, until it is small enough to drop
+	for _, expected := range []time.Duration{time.Second, 500 * time.Millisecond, 250 * time.Millisecond, 125 * time.Millisecond, 0} {
+		for i := 0; i < 50; i++ {
+			th.Observe(notRateLimited, 0)
+		}
+		if th.interval != expected {
+			t.Fatalf("expected spacing %s after 50 ok requests, got %s", expected, th.interval)
+		}
+	}
+
+	if th.primary != 2 || th.secondary != 4 || th.forbidden != 1 {
+		t.Errorf("expected 2 primary, 4 secondary & 1 forbidden, got %s", th)
+	}
+}
+
+func TestThrottleTransportPausesOnRetryAfter(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message": "You have exceeded a secondary rate limit."}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	th := NewThrottle()
+	client := &http.Client{Transport: th.Transport(nil)}
+
+	resp, err := client.Get(srv.URL) // nolint:noctx
+	if err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected the 403 to be passed on, got %d", resp.StatusCode)
+	}
+
+	if until := time.Until(th.paused); until < 55*time.Second || until > 60*time.Second {
+		t.Errorf("expected everything to be paused for about a minute, got %s", until)
+	}
+
+	// nothing goes out while paused
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if _, err = client.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the paused request to time out, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request to reach github, got %d", requests)
+	}
+}
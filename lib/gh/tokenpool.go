@@ -0,0 +1,145 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/katbyte/gogo-repo-stats/lib/clog"
+)
+
+// TokenPool spreads requests over several tokens, each with its own rate limit budget. every request goes out with
+// the token that has the most left and only once all of them are spent does it wait, for the earliest reset.
+type TokenPool struct {
+	Tokens []*PooledToken
+}
+
+type PooledToken struct {
+	Name   string // masked so it can be logged
+	token  string
+	Budget *RateLimitBudget
+}
+
+func NewTokenPool(tokens []string, reserve int) *TokenPool {
+	p := &TokenPool{}
+	for i, t := range tokens {
+		name := fmt.Sprintf("token %d", i+1)
+		if len(t) > 8 {
+			name += " (..." + t[len(t)-4:] + ")"
+		}
+
+		p.Tokens = append(p.Tokens, &PooledToken{
+			Name:   name,
+			token:  t,
+			Budget: NewRateLimitBudget(reserve),
+		})
+	}
+
+	return p
+}
+
+// left is what a budget has left to spend, unknown or reset budgets are assumed to be full
+func (b *RateLimitBudget) left() (int, time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.remaining < 0 || !time.Now().Before(b.reset) {
+		return math.MaxInt32, b.reset
+	}
+	return b.remaining - b.Reserve, b.reset
+}
+
+func (b *RateLimitBudget) take() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.remaining > 0 {
+		b.remaining--
+	}
+	b.used++
+}
+
+// pick returns the token with the most budget left, or nil and when the first one resets if they are all spent.
+func (p *TokenPool) pick() (*PooledToken, time.Time) {
+	var best *PooledToken
+	var reset time.Time
+	bestLeft := 0
+
+	for _, t := range p.Tokens {
+		left, r := t.Budget.left()
+		if left > bestLeft {
+			best, bestLeft = t, left
+		}
+		if reset.IsZero() || r.Before(reset) {
+			reset = r
+		}
+	}
+
+	return best, reset
+}
+
+// Available is true while at least one token has budget left.
+func (p *TokenPool) Available() bool {
+	t, _ := p.pick()
+	return t != nil
+}
+
+// Acquire blocks until a token has budget and takes a request from it.
+func (p *TokenPool) Acquire(ctx context.Context) (*PooledToken, error) {
+	for {
+		t, reset := p.pick()
+		if t != nil {
+			t.Budget.take()
+			return t, nil
+		}
+
+		wait := time.Until(reset) + time.Second
+		clog.Log.Warnf("all %d tokens are out of budget, waiting %s for the first reset", len(p.Tokens), wait.String())
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// String reports what each token has used and has left, one per line.
+func (p *TokenPool) String() string {
+	lines := make([]string, 0, len(p.Tokens))
+	for _, t := range p.Tokens {
+		lines = append(lines, t.Name+": "+t.Budget.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Transport wraps base so every request made through it is authenticated with the token with the most budget left.
+func (p *TokenPool) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &tokenPoolTransport{pool: p, base: base}
+}
+
+type tokenPoolTransport struct {
+	pool *TokenPool
+	base http.RoundTripper
+}
+
+func (t *tokenPoolTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.pool.Acquire(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	// round trippers must not modify the request they are given
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token.token)
+
+	resp, err := t.base.RoundTrip(req)
+	token.Budget.Update(resp)
+
+	return resp, err
+}
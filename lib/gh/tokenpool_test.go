@@ -0,0 +1,169 @@
+package gh
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTokenPoolPick(t *testing.T) {
+	soon := time.Now().Add(10 * time.Minute)
+	later := time.Now().Add(30 * time.Minute)
+	past := time.Now().Add(-time.Minute)
+
+	// budget is a token's remaining & reset, remaining -1 is one no response has been seen for yet
+	type budget struct {
+		remaining int
+		reset     time.Time
+	}
+
+	cases := []struct {
+		name    string
+		reserve int
+		budgets []budget
+		picked  int // index of the token expected, -1 for none
+		reset   time.Time
+	}{
+		{
+			name:    "unknown budgets tie at max, the first wins",
+			budgets: []budget{{-1, time.Time{}}, {-1, time.Time{}}},
+			picked:  0,
+		},
+		{
+			name:    "reset budgets tie with unknown ones, the first wins",
+			budgets: []budget{{0, past}, {-1, time.Time{}}},
+			picked:  0,
+		},
+		{
+			name:    "unknown over known",
+			budgets: []budget{{4000, soon}, {-1, time.Time{}}},
+			picked:  1,
+		},
+		{
+			name:    "most left",
+			budgets: []budget{{100, soon}, {3000, later}, {2000, soon}},
+			picked:  1,
+			reset:   soon,
+		},
+		{
+			name:    "equal budgets, the first wins",
+			budgets: []budget{{500, later}, {500, soon}},
+			picked:  0,
+			reset:   soon,
+		},
+		{
+			name:    "the reserve is left alone",
+			reserve: 100,
+			budgets: []budget{{100, soon}, {101, later}},
+			picked:  1,
+			reset:   soon,
+		},
+		{
+			name:    "all spent, wait for the first reset",
+			reserve: 100,
+			budgets: []budget{{50, later}, {100, soon}, {0, later}},
+			picked:  -1,
+			reset:   soon,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens := make([]string, len(tc.budgets))
+			for i := range tokens {
+				tokens[i] = fmt.Sprintf("ghp_token%d", i)
+			}
+
+			p := NewTokenPool(tokens, tc.reserve)
+			for i, b := range tc.budgets {
+				p.Tokens[i].Budget.remaining = b.remaining
+				p.Tokens[i].Budget.reset = b.reset
+			}
+
+			picked, reset := p.pick()
+
+			var expected *PooledToken
+			if tc.picked >= 0 {
+				expected = p.Tokens[tc.picked]
+			}
+			if picked != expected {
+				t.Errorf("expected %v, got %v", expected, picked)
+			}
+			if !reset.Equal(tc.reset) {
+				t.Errorf("expected reset %s, got %s", tc.reset, reset)
+			}
+			if p.Available() != (expected != nil) {
+				t.Errorf("expected available to be %t", expected != nil)
+			}
+		})
+	}
+}
+
+func TestTokenPoolBudgetLeft(t *testing.T) {
+	b := NewRateLimitBudget(10)
+	if left, _ := b.left(); left != math.MaxInt32 {
+		t.Errorf("expected an unknown budget to be full, got %d", left)
+	}
+
+	b.remaining, b.reset = 50, time.Now().Add(time.Minute)
+	if left, _ := b.left(); left != 40 {
+		t.Errorf("expected 40 left over the reserve, got %d", left)
+	}
+
+	b.reset = time.Now().Add(-time.Second)
+	if left, _ := b.left(); left != math.MaxInt32 {
+		t.Errorf("expected a reset budget to be full, got %d", left)
+	}
+}
+
+func TestTokenPoolTransport(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+
+	// the first token is nearly spent, the second has plenty
+	var mu sync.Mutex
+	var used []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		mu.Lock()
+		used = append(used, token)
+		mu.Unlock()
+
+		remaining := "4000"
+		if token == "ghp_token0" {
+			remaining = "10"
+		}
+		w.Header().Set("X-RateLimit-Remaining", remaining)
+		w.Header().Set("X-RateLimit-Reset", reset)
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		fmt.Fprint(w, `{}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	p := NewTokenPool([]string{"ghp_token0", "ghp_token1"}, 0)
+	client := &http.Client{Transport: p.Transport(nil)}
+
+	for i := 0; i < 4; i++ {
+		resp, err := client.Get(srv.URL) // nolint:noctx
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		resp.Body.Close()
+	}
+
+	// both start unknown so the first goes first, once they are known the one with the most left gets the rest
+	expected := []string{"ghp_token0", "ghp_token1", "ghp_token1", "ghp_token1"}
+	if strings.Join(used, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected tokens %v to be used, got %v", expected, used)
+	}
+
+	if got := p.Tokens[0].Budget.Remaining(); got != 10 {
+		t.Errorf("expected the first token to have 10 left, got %d", got)
+	}
+}
@@ -0,0 +1,349 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/katbyte/gogo-repo-stats/lib/chttp"
+	"github.com/katbyte/gogo-repo-stats/lib/clog"
+	"github.com/katbyte/gogo-repo-stats/lib/provider"
+)
+
+// merge requests, issues and their label/milestone/state events from the gitlab v4 api, normalised to github's shapes
+
+var _ provider.Provider = Project{}
+
+type Project struct {
+	// URL is the gitlab instance, ie https://gitlab.com
+	URL string
+
+	// Path is the project's full path, ie group/subgroup/project
+	Path string
+
+	// Token is a personal, group or project access token with read_api, public projects don't need one
+	Token string
+}
+
+func NewProject(baseURL, path, token string) Project {
+	if baseURL == "" {
+		baseURL = "https://gitlab.com"
+	}
+
+	return Project{
+		URL:   strings.TrimSuffix(baseURL, "/"),
+		Path:  path,
+		Token: token,
+	}
+}
+
+type user struct {
+	Username string `json:"username"`
+}
+
+type milestone struct {
+	Title string `json:"title"`
+}
+
+type mergeRequest struct {
+	IID            int        `json:"iid"`
+	Title          string     `json:"title"`
+	Author         user       `json:"author"`
+	State          string     `json:"state"`
+	Milestone      *milestone `json:"milestone"`
+	Labels         []string   `json:"labels"`
+	Draft          bool       `json:"draft"`
+	WorkInProgress bool       `json:"work_in_progress"`
+	MergedBy       *user      `json:"merged_by"`
+	MergeCommitSHA string     `json:"merge_commit_sha"`
+	SquashSHA      string     `json:"squash_commit_sha"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	MergedAt       *time.Time `json:"merged_at"`
+	ClosedAt       *time.Time `json:"closed_at"`
+}
+
+type issue struct {
+	IID       int        `json:"iid"`
+	Title     string     `json:"title"`
+	Author    user       `json:"author"`
+	State     string     `json:"state"`
+	Milestone *milestone `json:"milestone"`
+	Labels    []string   `json:"labels"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
+}
+
+// resourceEvent covers the label, milestone & state event apis which only differ in what changed
+type resourceEvent struct {
+	ID        int64     `json:"id"`
+	User      user      `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+	Action    string    `json:"action"` // add or remove
+	Label     *struct {
+		Name string `json:"name"`
+	} `json:"label"`
+	Milestone *milestone `json:"milestone"`
+	State     string     `json:"state"` // closed, reopened, merged...
+}
+
+func (p Project) api(path string) string {
+	return p.URL + "/api/v4/projects/" + url.PathEscape(p.Path) + path
+}
+
+// web returns the url of a merge request or issue, kind is merge_requests or issues
+func (p Project) web(kind string, iid int) string {
+	return p.URL + "/" + p.Path + "/-/" + kind + "/" + strconv.Itoa(iid)
+}
+
+func (p Project) NewHTTPClient() *http.Client {
+	retryClient := retryablehttp.NewClient()
+	retryClient.RetryMax = 7
+	retryClient.Logger = clog.Log
+
+	// gitlab says how long to wait when rate limited, retryablehttp honours Retry-After on 429s
+	retryClient.HTTPClient.Transport = chttp.NewTransport("GitLab", retryClient.HTTPClient.Transport)
+
+	return retryClient.StandardClient()
+}
+
+// list pages through a list api calling cb with each page's raw items until there are no more pages
+func (p Project) list(path string, query url.Values, cb func(json.RawMessage) error) error {
+	client := p.NewHTTPClient()
+
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("per_page", "100")
+
+	for page := "1"; page != ""; {
+		query.Set("page", page)
+		u := p.api(path) + "?" + query.Encode()
+		clog.Log.Debugf("Listing %s for %s (Page %s)...", path, p.Path, page)
+
+		req, err := http.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			return err
+		}
+		if p.Token != "" {
+			req.Header.Set("PRIVATE-TOKEN", p.Token)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("unable to list %s for %s (Page %s): %w", path, p.Path, page, err)
+		}
+
+		var raw json.RawMessage
+		err = json.NewDecoder(resp.Body).Decode(&raw)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unable to list %s for %s (Page %s): %s %s", path, p.Path, page, resp.Status, string(raw))
+		}
+		if err != nil {
+			return fmt.Errorf("decoding %s for %s (Page %s): %w", path, p.Path, page, err)
+		}
+
+		if err = cb(raw); err != nil {
+			return fmt.Errorf("callback failed for %s (Page %s): %w", p.Path, page, err)
+		}
+
+		page = resp.Header.Get("X-Next-Page")
+	}
+
+	return nil
+}
+
+// synced is if an item was last updated no later than the watermark, so is already in the cache
+func synced(updated, since time.Time) bool {
+	return !since.IsZero() && !updated.After(since)
+}
+
+// updatedSince lists merge requests or issues most recently updated first
+func updatedSince(since time.Time) url.Values {
+	q := url.Values{
+		"scope":    {"all"},
+		"order_by": {"updated_at"},
+		"sort":     {"desc"},
+	}
+	if !since.IsZero() {
+		// updated_after is inclusive & gitlab times have milliseconds, so send the watermark as it is and drop the items
+		// we already synced at exactly it ourselves (see synced)
+		q.Set("updated_after", since.UTC().Format(time.RFC3339Nano))
+	}
+
+	return q
+}
+
+func (p Project) ListPRs(since time.Time, cb func([]provider.PR) error) error {
+	return p.list("/merge_requests", updatedSince(since), func(raw json.RawMessage) error {
+		var mrs []mergeRequest
+		if err := json.Unmarshal(raw, &mrs); err != nil {
+			return fmt.Errorf("decoding merge requests: %w", err)
+		}
+
+		page := make([]provider.PR, 0, len(mrs))
+		for _, mr := range mrs {
+			if synced(mr.UpdatedAt, since) {
+				continue
+			}
+			page = append(page, mr.normalize())
+		}
+		return cb(page)
+	})
+}
+
+func (mr mergeRequest) normalize() provider.PR {
+	pr := provider.PR{
+		Number:  mr.IID,
+		Title:   mr.Title,
+		User:    mr.Author.Username,
+		State:   normalizeState(mr.State),
+		Labels:  mr.Labels,
+		Draft:   mr.Draft || mr.WorkInProgress, // older gitlab only has work_in_progress
+		Created: mr.CreatedAt,
+		Updated: mr.UpdatedAt,
+	}
+
+	if mr.Milestone != nil {
+		pr.Milestone = mr.Milestone.Title
+	}
+	if mr.ClosedAt != nil {
+		pr.Closed = *mr.ClosedAt
+	}
+
+	if mr.State == "merged" {
+		pr.Merged = true
+		if mr.MergedBy != nil {
+			pr.Merger = mr.MergedBy.Username
+		}
+		if mr.MergedAt != nil {
+			pr.Closed = *mr.MergedAt
+		}
+
+		// a squash merge lands the squashed commit, otherwise the merge commit
+		pr.MergeSHA = mr.MergeCommitSHA
+		if mr.SquashSHA != "" {
+			pr.MergeSHA = mr.SquashSHA
+		}
+	}
+
+	return pr
+}
+
+func (p Project) ListIssues(since time.Time, cb func([]provider.Issue) error) error {
+	return p.list("/issues", updatedSince(since), func(raw json.RawMessage) error {
+		var issues []issue
+		if err := json.Unmarshal(raw, &issues); err != nil {
+			return fmt.Errorf("decoding issues: %w", err)
+		}
+
+		page := make([]provider.Issue, 0, len(issues))
+		for _, i := range issues {
+			if synced(i.UpdatedAt, since) {
+				continue
+			}
+
+			is := provider.Issue{
+				Number:  i.IID,
+				Title:   i.Title,
+				User:    i.Author.Username,
+				State:   normalizeState(i.State),
+				Labels:  i.Labels,
+				Created: i.CreatedAt,
+				Updated: i.UpdatedAt,
+			}
+			if i.Milestone != nil {
+				is.Milestone = i.Milestone.Title
+			}
+			if i.ClosedAt != nil {
+				is.Closed = *i.ClosedAt
+			}
+			page = append(page, is)
+		}
+		return cb(page)
+	})
+}
+
+// normalizeState maps gitlab's opened, closed, merged & locked to github's open & closed
+func normalizeState(state string) string {
+	if state == "opened" {
+		return "open"
+	}
+	return "closed"
+}
+
+func (p Project) PREvents(number int) ([]provider.Event, error) {
+	return p.events("merge_requests", number)
+}
+
+func (p Project) IssueEvents(number int) ([]provider.Event, error) {
+	return p.events("issues", number)
+}
+
+// events gets the label, milestone & state events of a merge request or issue
+func (p Project) events(kind string, iid int) ([]provider.Event, error) {
+	var events []provider.Event
+	base := "/" + kind + "/" + strconv.Itoa(iid)
+	link := p.web(kind, iid)
+
+	for _, resource := range []string{"label", "milestone", "state"} {
+		err := p.list(base+"/resource_"+resource+"_events", nil, func(raw json.RawMessage) error {
+			var page []resourceEvent
+			if err := json.Unmarshal(raw, &page); err != nil {
+				return fmt.Errorf("decoding %s events: %w", resource, err)
+			}
+
+			for _, re := range page {
+				// ids are per kind of resource event
+				e := provider.Event{
+					ID:   resource + ":" + strconv.FormatInt(re.ID, 10),
+					Date: re.CreatedAt,
+					User: re.User.Username,
+					URL:  link,
+				}
+
+				switch resource {
+				case "label":
+					e.Event = "labeled"
+					if re.Action == "remove" {
+						e.Event = "unlabeled"
+					}
+					if re.Label != nil {
+						e.Label = re.Label.Name
+					}
+				case "milestone":
+					e.Event = "milestoned"
+					if re.Action == "remove" {
+						e.Event = "demilestoned"
+					}
+					if re.Milestone != nil {
+						e.Milestone = re.Milestone.Title
+					}
+				case "state":
+					e.Event = re.State
+				}
+
+				events = append(events, e)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s events for %s!%d: %w", resource, p.Path, iid, err)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Date.Before(events[j].Date)
+	})
+
+	return events, nil
+}
@@ -0,0 +1,103 @@
+package provider
+
+import (
+	"strings"
+	"time"
+)
+
+// a provider is somewhere repos live that prs, issues & their events can be fetched from. everything is normalised
+// to the shape github gives them, as that is what the cache, report & graphs were built on, so they never need to
+// know where an item came from
+
+const (
+	GitHub = "github"
+	GitLab = "gitlab"
+)
+
+// Provider lists a single repo's prs & issues and gets their events.
+type Provider interface {
+	// ListPRs calls cb with each page of prs updated after since, everything when since is zero
+	ListPRs(since time.Time, cb func([]PR) error) error
+
+	// ListIssues calls cb with each page of issues (but not prs) updated after since, everything when since is zero
+	ListIssues(since time.Time, cb func([]Issue) error) error
+
+	// PREvents & IssueEvents return all events of a pr or issue in no particular order
+	PREvents(number int) ([]Event, error)
+	IssueEvents(number int) ([]Event, error)
+}
+
+type PR struct {
+	Number    int
+	Title     string
+	User      string
+	State     string // open or closed, merged prs are closed
+	Milestone string
+	Labels    []string
+	Draft     bool
+	Merged    bool
+	Merger    string
+	MergeSHA  string // only once merged
+	Created   time.Time
+	Updated   time.Time
+	Closed    time.Time
+
+	// size, nil when not known
+	Additions    *int
+	Deletions    *int
+	ChangedFiles *int
+	Commits      *int
+}
+
+type Issue struct {
+	Number    int
+	Title     string
+	User      string
+	State     string // open or closed
+	Milestone string
+	Labels    []string
+	Created   time.Time
+	Updated   time.Time
+	Closed    time.Time
+}
+
+// Event is something that happened to a pr or issue, named as on github's timeline: closed, reopened, merged,
+// labeled, unlabeled, milestoned, demilestoned, reviewed, commented...
+type Event struct {
+	// ID is the provider's id for the event, when it has none the cache makes one up from the event itself
+	ID string
+
+	Date  time.Time
+	Event string
+	User  string
+
+	State     string
+	Label     string
+	Milestone string
+	Body      string
+
+	// the details only some kinds of event have, empty for the rest
+	Assignee     string // assigned & unassigned
+	Reviewer     string // review_requested & review_request_removed, a user
+	Team         string // review_requested & review_request_removed, a team's slug
+	RenameFrom   string // renamed
+	RenameTo     string // renamed
+	SourceRepo   string // cross-referenced, the owner/name of the issue or pr it was referenced from
+	SourceNumber int    // cross-referenced
+	SourceKind   string // cross-referenced, issue or pr
+	CommitID     string // merged, referenced, closed, committed & head_ref_force_pushed (the new head)
+	BeforeCommit string // head_ref_force_pushed, the head it replaced
+	LockReason   string // locked
+
+	URL string
+}
+
+// Split returns the provider of a repo key and the repo's path on it, repos not on github are prefixed with their
+// provider ie gitlab:group/subgroup/project
+func Split(repo string) (string, string) {
+	if strings.HasPrefix(repo, GitLab+":") {
+		return GitLab, strings.TrimPrefix(repo, GitLab+":")
+	}
+
+	return GitHub, repo
+}